@@ -0,0 +1,76 @@
+// cmd/admin.go
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"plateforme-mys3/internal/storage"
+)
+
+// runCommand exécute une commande d'administration (binaire lancé avec des arguments) et retourne le code de
+// sortie du processus. Ces commandes agissent directement sur le stockage et ne sont pas exposées en HTTP :
+// les lancer suppose un accès à la machine du serveur.
+func runCommand(ctx context.Context, args []string, s storage.Backend, out io.Writer) int {
+	switch args[0] {
+	case "purge-bucket":
+		return purgeBucketCommand(ctx, args[1:], s, out)
+	case "rotate-master-key":
+		return rotateMasterKeyCommand(ctx, s, out)
+	default:
+		fmt.Fprintf(out, "Commande inconnue : %s\n", args[0])
+		fmt.Fprintln(out, "Commandes disponibles :")
+		fmt.Fprintln(out, "  purge-bucket -confirm <bucket> [-batch n] <bucket>   supprime un bucket et tout son contenu")
+		fmt.Fprintln(out, "  rotate-master-key                                    réenveloppe les clés SSE-S3 avec SSE_MASTER_KEY")
+		return 2
+	}
+}
+
+// purgeBucketCommand supprime un bucket et tout son contenu par lots, en affichant l'avancement.
+// Le nom du bucket doit être répété dans -confirm : une purge est irréversible.
+func purgeBucketCommand(ctx context.Context, args []string, s storage.Backend, out io.Writer) int {
+	flags := flag.NewFlagSet("purge-bucket", flag.ContinueOnError)
+	flags.SetOutput(out)
+	batchSize := flags.Int("batch", storage.DefaultPurgeBatchSize, "nombre de versions supprimées par lot")
+	confirm := flags.String("confirm", "", "nom du bucket, répété pour confirmer la purge")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(out, "Usage : purge-bucket -confirm <bucket> [-batch n] <bucket>")
+		return 2
+	}
+	bucketName := flags.Arg(0)
+	if *confirm != bucketName {
+		fmt.Fprintf(out, "Purge refusée : -confirm doit valoir %q\n", bucketName)
+		return 2
+	}
+
+	fmt.Fprintf(out, "Purge du bucket %s (lots de %d versions)\n", bucketName, *batchSize)
+	err := storage.PurgeBucket(ctx, s, bucketName, *batchSize, func(p storage.PurgeProgress) {
+		fmt.Fprintf(out, "Lot %d : %d version(s) supprimée(s), %d upload(s) annulé(s)\n", p.Batches, p.DeletedVersions, p.AbortedUploads)
+	})
+	if err != nil {
+		fmt.Fprintf(out, "Erreur lors de la purge du bucket %s : %v\n", bucketName, err)
+		return 1
+	}
+	fmt.Fprintf(out, "Bucket %s purgé et supprimé\n", bucketName)
+	return 0
+}
+
+// rotateMasterKeyCommand réenveloppe avec la clé maître courante (SSE_MASTER_KEY) les clés de données des objets
+// SSE-S3 encore enveloppées par l'ancienne (SSE_PREVIOUS_MASTER_KEY). Les contenus ne sont pas rechiffrés.
+func rotateMasterKeyCommand(ctx context.Context, s storage.Backend, out io.Writer) int {
+	fmt.Fprintln(out, "Rotation de la clé maître SSE-S3")
+	p, err := storage.RotateDataKeys(ctx, s, func(p storage.RotationProgress) {
+		fmt.Fprintf(out, "Bucket %s traité : %d version(s) examinée(s), %d clé(s) réenveloppée(s) au total\n", p.Bucket, p.Versions, p.Rewrapped)
+	})
+	if err != nil {
+		fmt.Fprintf(out, "Erreur lors de la rotation (SSE_PREVIOUS_MASTER_KEY doit contenir l'ancienne clé) : %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(out, "Rotation terminée : %d clé(s) réenveloppée(s) ; SSE_PREVIOUS_MASTER_KEY peut être retirée\n", p.Rewrapped)
+	return 0
+}
@@ -1,169 +1,161 @@
-//
+// cmd/main.go
 
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"plateforme-mys3/config"
+	"plateforme-mys3/internal/auth"
+	"plateforme-mys3/internal/handlers"
+	"plateforme-mys3/internal/middleware"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+
+	"github.com/gorilla/mux"
 )
 
-type Config struct {
-	AccessKeyID     string
-	SecretAccessKey string
-	Region          string
-}
-
 // Fonction principale
 func main() {
-	dataDir := "./data/"
-	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
-		err := os.Mkdir(dataDir, 0755)
+	cfg := config.LoadConfig()
+
+	if _, err := os.Stat(cfg.StoragePath); os.IsNotExist(err) {
+		err := os.MkdirAll(cfg.StoragePath, 0755)
 		if err != nil {
-			log.Fatalf("Erreur lors de la création du répertoire %s : %v", dataDir, err)
+			log.Fatalf("Erreur lors de la création du répertoire %s : %v", cfg.StoragePath, err)
 		}
-		log.Printf("Répertoire %s créé avec succès.", dataDir)
+		log.Printf("Répertoire %s créé avec succès.", cfg.StoragePath)
 	}
 
-	cfg := Config{
-		AccessKeyID:     "admin1234",
-		SecretAccessKey: "adminsecretkey12345678",
-		Region:          "eu-west-1",
+	s := storage.NewStorage(cfg.StoragePath)
+	s.MasterKey = cfg.SSEMasterKey
+	s.PreviousMasterKey = cfg.SSEPreviousMasterKey
+	moved, err := s.MigrateLegacyKeys()
+	if err != nil {
+		log.Fatalf("Erreur lors de la migration des clés vers le nouveau schéma de chemins : %v", err)
 	}
-
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// Normaliser le chemin
-		path := strings.TrimSuffix(r.URL.Path, "/")
-		bucketName := strings.Split(strings.TrimPrefix(path, "/"), "/")[0]
-		objectName := strings.TrimPrefix(path, bucketName+"/")
-
-		log.Printf("Requête reçue: %s %s", r.Method, path)
-
-		// Authentification simplifiée
-		if !authenticateRequest(r, cfg) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		switch r.Method {
-		case http.MethodPut:
-			if objectName == "" {
-				createBucket(w, r, bucketName, cfg)
-			}
-		case http.MethodGet:
-			if objectName == "" {
-				listBuckets(w, r, cfg)
-			} else {
-				downloadObject(w, r, bucketName, objectName, cfg)
-			}
-		case http.MethodHead:
-			handleHeadRequest(w, r, bucketName, objectName, cfg)
-		case http.MethodPost:
-			// Ajoutez le traitement des requêtes POST si nécessaire
-			w.WriteHeader(http.StatusOK)
-		case http.MethodOptions:
-			// Répondre correctement aux requêtes OPTIONS
-			w.WriteHeader(http.StatusOK)
-		default:
-			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
-		}
-	})
-
-	log.Println("Serveur démarré sur le port 9000")
-	log.Fatal(http.ListenAndServe(":9000", nil))
-}
-
-// Fonction pour authentifier les requêtes
-func authenticateRequest(r *http.Request, cfg Config) bool {
-	return true // Pour simplifier, nous acceptons toutes les requêtes
-}
-
-func createBucket(w http.ResponseWriter, r *http.Request, bucketName string, cfg Config) {
-	path := "./data/" + bucketName
-	log.Printf("Tentative de création du bucket : %s", bucketName)
-
-	// Vérifiez si le répertoire existe déjà
-	if _, err := os.Stat(path); !os.IsNotExist(err) {
-		log.Printf("Le bucket %s existe déjà", bucketName)
-		http.Error(w, "Bucket Already Exists", http.StatusConflict)
-		return
+	if moved > 0 {
+		log.Printf("%d objet(s) migré(s) vers le nouveau schéma de chemins", moved)
 	}
-
-	// Créer le répertoire
-	err := os.Mkdir(path, 0755)
+	assigned, err := s.AssignLegacyOwners(cfg.LegacyBucketOwner)
 	if err != nil {
-		log.Printf("Erreur lors de la création du bucket %s : %v", bucketName, err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
+		log.Fatalf("Erreur lors de l'attribution d'un propriétaire aux buckets hérités : %v", err)
 	}
-
-	// Vérifiez si le répertoire a bien été créé
-	if _, err := os.Stat(path); err == nil {
-		log.Printf("Bucket %s créé avec succès à l'emplacement : %s", bucketName, path)
-	} else {
-		log.Printf("Échec de la création du bucket %s", bucketName)
+	if assigned > 0 {
+		log.Printf("%d bucket(s) sans propriétaire attribué(s) à %s", assigned, cfg.LegacyBucketOwner)
+	}
+	recovered, err := s.RecoverWrites()
+	if err != nil {
+		log.Fatalf("Erreur lors de la reprise des écritures interrompues : %v", err)
+	}
+	if recovered > 0 {
+		log.Printf("%d écriture(s) interrompue(s) terminée(s)", recovered)
+	}
+	removed, err := s.RemoveStaleTempFiles()
+	if err != nil {
+		log.Fatalf("Erreur lors du nettoyage des fichiers temporaires : %v", err)
+	}
+	if removed > 0 {
+		log.Printf("%d fichier(s) temporaire(s) d'écritures interrompues supprimé(s)", removed)
 	}
 
-	w.WriteHeader(http.StatusOK)
-}
+	// Commandes d'administration (ex. : mys3 purge-bucket -confirm b b). SIGINT arrête une purge
+	// entre deux suppressions ; elle peut être relancée.
+	if len(os.Args) > 1 {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		code := runCommand(ctx, os.Args[1:], s, os.Stdout)
+		cancel()
+		os.Exit(code)
+	}
 
-func listBuckets(w http.ResponseWriter, r *http.Request, cfg Config) {
-	dataDir := "./data/"
-	entries, err := os.ReadDir(dataDir)
+	store, err := auth.NewCredentialStore(cfg)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		log.Printf("Erreur lors de la lecture du répertoire data : %v", err)
-		return
+		log.Fatalf("Erreur lors du chargement des credentials : %v", err)
 	}
 
-	w.Header().Set("Content-Type", "application/xml")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("<ListAllMyBucketsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\">"))
-	w.Write([]byte("<Buckets>"))
-	for _, entry := range entries {
-		if entry.IsDir() {
-			w.Write([]byte("<Bucket><Name>" + entry.Name() + "</Name></Bucket>"))
+	if cfg.ETagVerifyInterval > 0 {
+		log.Printf("Vérificateur d'ETags actif (période : %s)", cfg.ETagVerifyInterval)
+		go s.RunETagVerifier(context.Background(), cfg.ETagVerifyInterval)
+	}
+	if cfg.LifecycleInterval > 0 {
+		if cfg.LifecycleDryRun {
+			log.Printf("Cycle de vie des buckets en simulation (période : %s) : les suppressions sont seulement journalisées", cfg.LifecycleInterval)
+		} else {
+			log.Printf("Cycle de vie des buckets actif (période : %s)", cfg.LifecycleInterval)
 		}
+		go storage.RunLifecycle(context.Background(), s, cfg.LifecycleInterval, cfg.LifecycleDryRun)
 	}
-	w.Write([]byte("</Buckets></ListAllMyBucketsResult>"))
+
+	log.Printf("Serveur démarré sur %s (stockage : %s)", cfg.ListenAddr, cfg.StoragePath)
+	log.Fatal(http.ListenAndServe(cfg.ListenAddr, newServer(s, cfg, store)))
 }
 
-func downloadObject(w http.ResponseWriter, r *http.Request, bucketName, objectName string, cfg Config) {
-	objectPath := "./data/" + bucketName + "/" + objectName
+// newServer construit le handler HTTP complet : routeur S3 protégé par l'authentification SigV4 et les politiques de bucket
+func newServer(s storage.Backend, cfg config.Config, store auth.CredentialStore) http.Handler {
+	return middleware.AuthMiddleware(newRouter(s, cfg.Region), cfg, store, s)
+}
 
-	// Vérifier que l'objet existe
-	if _, err := os.Stat(objectPath); os.IsNotExist(err) {
-		http.Error(w, "Object Not Found", http.StatusNotFound)
-		log.Printf("Objet non trouvé : %s dans le bucket %s", objectName, bucketName)
-		return
+// newRouter déclare les routes S3 (style chemin : /{bucket}/{object}) sur les handlers internes ;
+// region est la seule contrainte de localisation acceptée à la création d'un bucket
+func newRouter(s storage.Backend, region string) *mux.Router {
+	r := mux.NewRouter()
+
+	// Service
+	r.HandleFunc("/", handlers.ListBucketsHandler(s)).Methods(http.MethodGet)
+
+	// Bucket
+	r.HandleFunc("/{bucket}", handlers.BucketPolicyHandler(s)).Methods(http.MethodGet, http.MethodPut, http.MethodDelete).Queries("policy", "")
+	r.HandleFunc("/{bucket}/", handlers.BucketPolicyHandler(s)).Methods(http.MethodGet, http.MethodPut, http.MethodDelete).Queries("policy", "")
+	r.HandleFunc("/{bucket}", handlers.BucketACLHandler(s)).Methods(http.MethodGet, http.MethodPut).Queries("acl", "")
+	r.HandleFunc("/{bucket}/", handlers.BucketACLHandler(s)).Methods(http.MethodGet, http.MethodPut).Queries("acl", "")
+	r.HandleFunc("/{bucket}", handlers.ListMultipartUploadsHandler(s)).Methods(http.MethodGet).Queries("uploads", "")
+	r.HandleFunc("/{bucket}/", handlers.ListMultipartUploadsHandler(s)).Methods(http.MethodGet).Queries("uploads", "")
+	r.HandleFunc("/{bucket}", handlers.BucketVersioningHandler(s)).Methods(http.MethodGet, http.MethodPut).Queries("versioning", "")
+	r.HandleFunc("/{bucket}/", handlers.BucketVersioningHandler(s)).Methods(http.MethodGet, http.MethodPut).Queries("versioning", "")
+	r.HandleFunc("/{bucket}", handlers.BucketEncryptionHandler(s)).Methods(http.MethodGet, http.MethodPut, http.MethodDelete).Queries("encryption", "")
+	r.HandleFunc("/{bucket}/", handlers.BucketEncryptionHandler(s)).Methods(http.MethodGet, http.MethodPut, http.MethodDelete).Queries("encryption", "")
+	r.HandleFunc("/{bucket}", handlers.BucketLifecycleHandler(s)).Methods(http.MethodGet, http.MethodPut, http.MethodDelete).Queries("lifecycle", "")
+	r.HandleFunc("/{bucket}/", handlers.BucketLifecycleHandler(s)).Methods(http.MethodGet, http.MethodPut, http.MethodDelete).Queries("lifecycle", "")
+	r.HandleFunc("/{bucket}", handlers.ListObjectVersionsHandler(s)).Methods(http.MethodGet).Queries("versions", "")
+	r.HandleFunc("/{bucket}/", handlers.ListObjectVersionsHandler(s)).Methods(http.MethodGet).Queries("versions", "")
+	r.HandleFunc("/{bucket}", handlers.DeleteObjectsHandler(s)).Methods(http.MethodPost).Queries("delete", "")
+	r.HandleFunc("/{bucket}/", handlers.DeleteObjectsHandler(s)).Methods(http.MethodPost).Queries("delete", "")
+	// Une sous-ressource demandée avec une autre méthode ne retombe pas sur les routes génériques du bucket
+	for _, subresource := range []string{"policy", "acl", "uploads", "versioning", "versions", "encryption", "lifecycle", "delete"} {
+		r.HandleFunc("/{bucket}", methodNotAllowed).Queries(subresource, "")
+		r.HandleFunc("/{bucket}/", methodNotAllowed).Queries(subresource, "")
+	}
+	r.HandleFunc("/{bucket}", handlers.ListObjectsHandler(s)).Methods(http.MethodGet)
+	r.HandleFunc("/{bucket}/", handlers.ListObjectsHandler(s)).Methods(http.MethodGet)
+	r.HandleFunc("/{bucket}", handlers.BucketHandler(s, region)).Methods(http.MethodPut, http.MethodDelete, http.MethodHead)
+	r.HandleFunc("/{bucket}/", handlers.BucketHandler(s, region)).Methods(http.MethodPut, http.MethodDelete, http.MethodHead)
+
+	// ACL d'objet
+	r.HandleFunc("/{bucket}/{object:.+}", handlers.ObjectACLHandler(s)).Methods(http.MethodGet, http.MethodPut).Queries("acl", "")
+
+	// Upload multipart
+	r.HandleFunc("/{bucket}/{object:.+}", handlers.CreateMultipartUploadHandler(s)).Methods(http.MethodPost).Queries("uploads", "")
+	r.HandleFunc("/{bucket}/{object:.+}", handlers.UploadPartHandler(s)).Methods(http.MethodPut).Queries("partNumber", "{partNumber}", "uploadId", "{uploadId}")
+	r.HandleFunc("/{bucket}/{object:.+}", handlers.CompleteMultipartUploadHandler(s)).Methods(http.MethodPost).Queries("uploadId", "{uploadId}")
+	r.HandleFunc("/{bucket}/{object:.+}", handlers.AbortMultipartUploadHandler(s)).Methods(http.MethodDelete).Queries("uploadId", "{uploadId}")
+	r.HandleFunc("/{bucket}/{object:.+}", handlers.ListPartsHandler(s)).Methods(http.MethodGet).Queries("uploadId", "{uploadId}")
+
+	// Une sous-ressource d'objet demandée avec une autre méthode ne retombe pas sur la route de l'objet
+	for _, subresource := range []string{"acl", "uploads", "uploadId"} {
+		r.HandleFunc("/{bucket}/{object:.+}", methodNotAllowed).Queries(subresource, "")
 	}
 
-	// Envoyer le fichier en réponse
-	log.Printf("Téléchargement de l'objet %s depuis le bucket %s", objectName, bucketName)
-	http.ServeFile(w, r, objectPath)
+	// Objet
+	r.HandleFunc("/{bucket}/{object:.+}", handlers.ObjectHandler(s)).Methods(http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete)
+
+	return r
 }
 
-func handleHeadRequest(w http.ResponseWriter, r *http.Request, bucketName, objectName string, cfg Config) {
-	if objectName == "" {
-		// Vérifier si le bucket existe
-		bucketPath := "./data/" + bucketName
-		if _, err := os.Stat(bucketPath); os.IsNotExist(err) {
-			http.Error(w, "Bucket Not Found", http.StatusNotFound)
-			log.Printf("Bucket non trouvé : %s", bucketName)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-		log.Printf("Bucket %s existe", bucketName)
-	} else {
-		// Vérifier si l'objet existe
-		objectPath := "./data/" + bucketName + "/" + objectName
-		if _, err := os.Stat(objectPath); os.IsNotExist(err) {
-			http.Error(w, "Object Not Found", http.StatusNotFound)
-			log.Printf("Objet non trouvé : %s dans le bucket %s", objectName, bucketName)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-		log.Printf("Objet %s existe dans le bucket %s", objectName, bucketName)
-	}
+// methodNotAllowed répond aux couples sous-ressource / méthode qu'aucun handler ne prend en charge
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Méthode non autorisée: %s %s", r.Method, r.URL.String())
+	s3err.Write(w, r, s3err.ErrMethodNotAllowed)
 }
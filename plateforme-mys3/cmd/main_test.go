@@ -1,11 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"plateforme-mys3/config"
+	"plateforme-mys3/internal/auth"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/storage"
 	"strings"
 	"testing"
+	"time"
 )
 
 // setup : Crée le répertoire ./data/ avant les tests
@@ -33,12 +47,27 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
-// Test de la fonction createBucket
+// newTestRouter construit le routeur sur le répertoire ./data/ des tests
+func newTestRouter() http.Handler {
+	return newTestHandler(storage.NewStorage("./data"))
+}
+
+// newTestHandler construit le routeur sans le middleware d'authentification, avec un Authorizer qui autorise
+// tout aux handlers qui vérifient eux-mêmes des droits (suppression multiple, source d'une copie)
+func newTestHandler(s storage.Backend) http.Handler {
+	router := newRouter(s, "us-east-1")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowAll := func(bucketName, objectName, action string) bool { return true }
+		router.ServeHTTP(w, r.WithContext(auth.WithAuthorizer(r.Context(), allowAll)))
+	})
+}
+
+// Test de la création d'un bucket
 func TestCreateBucket(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPut, "/testbucket", nil)
 	w := httptest.NewRecorder()
 
-	createBucket(w, req, "testbucket", Config{})
+	newTestRouter().ServeHTTP(w, req)
 
 	resp := w.Result()
 	if resp.StatusCode != http.StatusOK {
@@ -50,7 +79,7 @@ func TestCreateBucket(t *testing.T) {
 	}
 }
 
-// Test de la fonction listBuckets
+// Test de la liste des buckets
 func TestListBuckets(t *testing.T) {
 	os.Mkdir("./data/bucket1", 0755)
 	os.Mkdir("./data/bucket2", 0755)
@@ -58,7 +87,7 @@ func TestListBuckets(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	w := httptest.NewRecorder()
 
-	listBuckets(w, req, Config{})
+	newTestRouter().ServeHTTP(w, req)
 
 	resp := w.Result()
 	if resp.StatusCode != http.StatusOK {
@@ -70,3 +99,1255 @@ func TestListBuckets(t *testing.T) {
 		t.Errorf("Expected buckets to be listed in response")
 	}
 }
+
+// Test d'un aller-retour PUT / HEAD / GET / DELETE sur un objet
+func TestObjectRoundTrip(t *testing.T) {
+	router := newTestRouter()
+	os.Mkdir("./data/objbucket", 0755)
+
+	req := httptest.NewRequest(http.MethodPut, "/objbucket/dir/hello.txt", strings.NewReader("hello world"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Content-Encoding", "aws-chunked,gzip")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("x-amz-meta-author", "alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT: expected status %d but got %d", http.StatusOK, w.Code)
+	}
+	etag := w.Header().Get("ETag")
+
+	req = httptest.NewRequest(http.MethodHead, "/objbucket/dir/hello.txt", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Length") != "11" {
+		t.Errorf("HEAD: unexpected status %d / Content-Length %q", w.Code, w.Header().Get("Content-Length"))
+	}
+	expectedHeaders := map[string]string{
+		"Content-Type":      "text/plain",
+		"Content-Encoding":  "gzip",
+		"Cache-Control":     "no-cache",
+		"x-amz-meta-author": "alice",
+		"ETag":              etag,
+	}
+	for name, value := range expectedHeaders {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("HEAD: expected %s %q but got %q", name, value, got)
+		}
+	}
+	if _, err := http.ParseTime(w.Header().Get("Last-Modified")); err != nil {
+		t.Errorf("HEAD: invalid Last-Modified %q", w.Header().Get("Last-Modified"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/objbucket/dir/hello.txt", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "hello world" {
+		t.Errorf("GET: unexpected status %d / body %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/objbucket/dir/hello.txt", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("DELETE: expected status %d but got %d", http.StatusNoContent, w.Code)
+	}
+}
+
+// Test de ListObjectsV2 : clés imbriquées, préfixes communs et jetons de continuation
+func TestListObjectsV2(t *testing.T) {
+	router := newTestRouter()
+	os.Mkdir("./data/listbucket", 0755)
+	for _, key := range []string{"docs/a.txt", "docs/b.txt", "img/logo.png", "readme.md"} {
+		req := httptest.NewRequest(http.MethodPut, "/listbucket/"+key, strings.NewReader(key))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("PUT %s: unexpected status %d", key, w.Code)
+		}
+	}
+
+	var keys []string
+	target := "/listbucket?list-type=2&max-keys=2"
+	for pages := 0; pages < 5; pages++ {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var result dto.ListBucketResultV2
+		if err := xml.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("GET %s: invalid XML: %v", target, err)
+		}
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		target = "/listbucket?list-type=2&max-keys=2&continuation-token=" + result.NextContinuationToken
+	}
+	if got := strings.Join(keys, ","); got != "docs/a.txt,docs/b.txt,img/logo.png,readme.md" {
+		t.Errorf("Unexpected paginated keys %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/listbucket?list-type=2&delimiter=/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var result dto.ListBucketResultV2
+	if err := xml.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid XML: %v", err)
+	}
+	if result.KeyCount != 3 || len(result.Contents) != 1 || len(result.CommonPrefixes) != 2 || result.CommonPrefixes[0].Prefix != "docs/" {
+		t.Errorf("Unexpected delimited listing %+v", result)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/listbucket?list-type=2&continuation-token=%25%25", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid token but got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// Test du rejet des requêtes non signées par le serveur complet
+func TestServerRequiresSignature(t *testing.T) {
+	cfg := config.Config{
+		AccessKeyID:     "test",
+		SecretAccessKey: "secret",
+		Region:          "us-east-1",
+	}
+	store, _ := auth.NewCredentialStore(cfg)
+	handler := newServer(storage.NewStorage("./data"), cfg, store)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d but got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+// Test de l'accès anonyme à un objet public-read et du refus sur un objet privé
+func TestAnonymousPublicRead(t *testing.T) {
+	router := newTestRouter()
+	cfg := config.Config{AccessKeyID: "test", SecretAccessKey: "secret", Region: "us-east-1"}
+	store, _ := auth.NewCredentialStore(cfg)
+	server := newServer(storage.NewStorage("./data"), cfg, store)
+
+	req := httptest.NewRequest(http.MethodPut, "/aclbucket", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT bucket: unexpected status %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/aclbucket/public.txt", strings.NewReader("public"))
+	req.Header.Set("x-amz-acl", "public-read")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT public object: unexpected status %d", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodPut, "/aclbucket/private.txt", strings.NewReader("private"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT private object: unexpected status %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/aclbucket/bad.txt", strings.NewReader("x"))
+	req.Header.Set("x-amz-acl", "everyone")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PUT with unknown canned ACL: expected status %d but got %d", http.StatusBadRequest, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/aclbucket/public.txt", nil)
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "public" {
+		t.Errorf("Anonymous GET public object: unexpected status %d / body %q", w.Code, w.Body.String())
+	}
+
+	for _, target := range []string{"/aclbucket/private.txt", "/aclbucket", "/aclbucket/public.txt?acl"} {
+		req = httptest.NewRequest(http.MethodGet, target, nil)
+		w = httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Anonymous GET %s: expected status %d but got %d", target, http.StatusForbidden, w.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/aclbucket/public.txt?acl", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var policy dto.AccessControlPolicy
+	if err := xml.Unmarshal(w.Body.Bytes(), &policy); err != nil {
+		t.Fatalf("GET ?acl: invalid XML: %v", err)
+	}
+	grants := policy.AccessControlList.Grants
+	if len(grants) != 2 || grants[1].Grantee.Type != "Group" || grants[1].Permission != "READ" {
+		t.Errorf("GET ?acl: unexpected grants %+v", grants)
+	}
+}
+
+// Test du rejet par le routeur des sous-ressources demandées avec une méthode qu'aucun handler ne prend en charge
+func TestUnhandledSubresourceMethods(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/subbucket", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/subbucket/key", strings.NewReader("original")))
+
+	for _, tt := range []struct {
+		method, target string
+		body           string
+	}{
+		{http.MethodDelete, "/subbucket/key?acl", ""},
+		{http.MethodGet, "/subbucket/key?uploads", ""},
+		{http.MethodPut, "/subbucket/key?uploads", "overwritten"},
+		{http.MethodPut, "/subbucket/key?uploadId=abc", "overwritten"},
+		{http.MethodDelete, "/subbucket?versions", ""},
+		{http.MethodDelete, "/subbucket?acl", ""},
+		{http.MethodDelete, "/subbucket/?uploads", ""},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+		if w.Code != http.StatusMethodNotAllowed || !strings.Contains(w.Body.String(), "MethodNotAllowed") {
+			t.Errorf("%s %s: expected MethodNotAllowed, got %d %s", tt.method, tt.target, w.Code, w.Body.String())
+		}
+	}
+
+	// L'objet et le bucket sont intacts
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/subbucket/key", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "original" {
+		t.Errorf("Expected the object to be untouched, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+// Test du cycle de vie complet d'un upload multipart
+func TestMultipartUpload(t *testing.T) {
+	router := newTestRouter()
+	os.Mkdir("./data/mpbucket", 0755)
+
+	do := func(method, target string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Initialisation
+	w := do(http.MethodPost, "/mpbucket/big.bin?uploads", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("CreateMultipartUpload: expected status %d but got %d", http.StatusOK, w.Code)
+	}
+	var initiated dto.InitiateMultipartUploadResult
+	if err := xml.Unmarshal(w.Body.Bytes(), &initiated); err != nil || initiated.UploadID == "" {
+		t.Fatalf("CreateMultipartUpload: invalid response %q (%v)", w.Body.String(), err)
+	}
+	uploadID := initiated.UploadID
+
+	// Envoi de deux parts : la première doit faire au moins 5 Mo
+	part1 := bytes.Repeat([]byte("a"), 5<<20)
+	part2 := []byte("fin du fichier")
+	var etags []string
+	for i, part := range [][]byte{part1, part2} {
+		w = do(http.MethodPut, fmt.Sprintf("/mpbucket/big.bin?partNumber=%d&uploadId=%s", i+1, uploadID), part)
+		if w.Code != http.StatusOK {
+			t.Fatalf("UploadPart %d: expected status %d but got %d", i+1, http.StatusOK, w.Code)
+		}
+		sum := md5.Sum(part)
+		if want := "\"" + hex.EncodeToString(sum[:]) + "\""; w.Header().Get("ETag") != want {
+			t.Errorf("UploadPart %d: expected ETag %s but got %s", i+1, want, w.Header().Get("ETag"))
+		}
+		etags = append(etags, w.Header().Get("ETag"))
+	}
+
+	// Liste des parts et des uploads en cours
+	w = do(http.MethodGet, "/mpbucket/big.bin?uploadId="+uploadID, nil)
+	var parts dto.ListPartsResult
+	if err := xml.Unmarshal(w.Body.Bytes(), &parts); err != nil || len(parts.Parts) != 2 {
+		t.Fatalf("ListParts: expected 2 parts, got %q (%v)", w.Body.String(), err)
+	}
+	w = do(http.MethodGet, "/mpbucket?uploads", nil)
+	if !strings.Contains(w.Body.String(), uploadID) {
+		t.Errorf("ListMultipartUploads: expected upload %s in %q", uploadID, w.Body.String())
+	}
+
+	// Corps de finalisation démesuré : refusé sans être lu en entier
+	w = do(http.MethodPost, "/mpbucket/big.bin?uploadId="+uploadID, bytes.Repeat([]byte(" "), 5<<20))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "MalformedXML") {
+		t.Errorf("CompleteMultipartUpload: expected MalformedXML for an oversized body, got %d %q", w.Code, w.Body.String())
+	}
+
+	// Finalisation
+	complete := fmt.Sprintf(`<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>%s</ETag></Part><Part><PartNumber>2</PartNumber><ETag>%s</ETag></Part></CompleteMultipartUpload>`, etags[0], etags[1])
+	w = do(http.MethodPost, "/mpbucket/big.bin?uploadId="+uploadID, []byte(complete))
+	if w.Code != http.StatusOK {
+		t.Fatalf("CompleteMultipartUpload: expected status %d but got %d", http.StatusOK, w.Code)
+	}
+	var result dto.CompleteMultipartUploadResult
+	if err := xml.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("CompleteMultipartUpload: invalid response %q (%v)", w.Body.String(), err)
+	}
+	sum1, sum2 := md5.Sum(part1), md5.Sum(part2)
+	etagOfEtags := md5.Sum(append(sum1[:], sum2[:]...))
+	if want := "\"" + hex.EncodeToString(etagOfEtags[:]) + "-2\""; result.ETag != want {
+		t.Errorf("CompleteMultipartUpload: expected ETag %s but got %s", want, result.ETag)
+	}
+
+	w = do(http.MethodGet, "/mpbucket/big.bin", nil)
+	if !bytes.Equal(w.Body.Bytes(), append(part1, part2...)) {
+		t.Errorf("GET: assembled object does not match the uploaded parts")
+	}
+
+	// L'upload n'existe plus une fois finalisé
+	w = do(http.MethodGet, "/mpbucket/big.bin?uploadId="+uploadID, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("ListParts after complete: expected status %d but got %d", http.StatusNotFound, w.Code)
+	}
+
+	// La Location d'une clé à caractères spéciaux est encodée segment par segment
+	w = do(http.MethodPost, "/mpbucket/dir/a%20b%23.txt?uploads", nil)
+	if err := xml.Unmarshal(w.Body.Bytes(), &initiated); err != nil || initiated.UploadID == "" {
+		t.Fatalf("CreateMultipartUpload: invalid response %q (%v)", w.Body.String(), err)
+	}
+	w = do(http.MethodPut, "/mpbucket/dir/a%20b%23.txt?partNumber=1&uploadId="+initiated.UploadID, part2)
+	complete = fmt.Sprintf(`<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>%s</ETag></Part></CompleteMultipartUpload>`, w.Header().Get("ETag"))
+	w = do(http.MethodPost, "/mpbucket/dir/a%20b%23.txt?uploadId="+initiated.UploadID, []byte(complete))
+	if err := xml.Unmarshal(w.Body.Bytes(), &result); err != nil || result.Location != "http://example.com/mpbucket/dir/a%20b%23.txt" {
+		t.Errorf("CompleteMultipartUpload: unexpected Location in %q (%v)", w.Body.String(), err)
+	}
+}
+
+// Test de l'annulation d'un upload multipart
+func TestAbortMultipartUpload(t *testing.T) {
+	router := newTestRouter()
+	os.Mkdir("./data/abortbucket", 0755)
+
+	req := httptest.NewRequest(http.MethodPost, "/abortbucket/obj?uploads", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var initiated dto.InitiateMultipartUploadResult
+	xml.Unmarshal(w.Body.Bytes(), &initiated)
+
+	req = httptest.NewRequest(http.MethodDelete, "/abortbucket/obj?uploadId="+initiated.UploadID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("AbortMultipartUpload: expected status %d but got %d", http.StatusNoContent, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/abortbucket/obj?partNumber=1&uploadId="+initiated.UploadID, strings.NewReader("data"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("UploadPart after abort: expected status %d but got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// Test du versionnage : identifiants de version, marqueur de suppression et listing des versions
+func TestObjectVersioning(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/versionedbucket", nil))
+
+	body := `<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>`
+	req := httptest.NewRequest(http.MethodPut, "/versionedbucket?versioning", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PutBucketVersioning: expected status %d but got %d", http.StatusOK, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/versionedbucket/obj.txt", strings.NewReader("v1"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	firstVersion := w.Header().Get("x-amz-version-id")
+	if firstVersion == "" {
+		t.Fatal("Expected an x-amz-version-id header on PUT")
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/versionedbucket/obj.txt", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || w.Header().Get("x-amz-delete-marker") != "true" {
+		t.Fatalf("DeleteObject: expected a delete marker, got %d %v", w.Code, w.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/versionedbucket/obj.txt", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get("x-amz-delete-marker") != "true" {
+		t.Errorf("GET after delete: expected 404 with delete marker, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/versionedbucket/obj.txt?versionId="+firstVersion, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "v1" {
+		t.Errorf("GET versionId: expected %q but got %d %q", "v1", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/versionedbucket?versions", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var listing dto.ListVersionsResult
+	if err := xml.Unmarshal(w.Body.Bytes(), &listing); err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.Versions) != 1 || len(listing.DeleteMarkers) != 1 || !listing.DeleteMarkers[0].IsLatest {
+		t.Errorf("Unexpected version listing %+v", listing)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/versionedbucket?versioning", strings.NewReader(`<VersioningConfiguration><Status>On</Status></VersioningConfiguration>`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Invalid versioning status: expected status %d but got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// Test des lectures partielles (Range), des en-têtes conditionnels et des paramètres response-*
+func TestRangeAndConditionalGet(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/rangebucket", nil))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/rangebucket/video.bin", strings.NewReader("0123456789")))
+	etag := w.Header().Get("ETag")
+
+	get := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	ranges := []struct {
+		header, wantBody, wantRange string
+		wantStatus                  int
+	}{
+		{"bytes=2-5", "2345", "bytes 2-5/10", http.StatusPartialContent},
+		{"bytes=7-", "789", "bytes 7-9/10", http.StatusPartialContent},
+		{"bytes=-3", "789", "bytes 7-9/10", http.StatusPartialContent},
+		{"bytes=5-100", "56789", "bytes 5-9/10", http.StatusPartialContent},
+		{"bytes=10-", "<Code>InvalidRange</Code>", "bytes */10", http.StatusRequestedRangeNotSatisfiable},
+		{"bytes=0-1,4-5", "0123456789", "", http.StatusOK},
+	}
+	for _, tt := range ranges {
+		w := get("/rangebucket/video.bin", map[string]string{"Range": tt.header})
+		body := w.Body.String()
+		if w.Code == http.StatusRequestedRangeNotSatisfiable && strings.Contains(body, tt.wantBody) {
+			body = tt.wantBody
+		}
+		if w.Code != tt.wantStatus || body != tt.wantBody || w.Header().Get("Content-Range") != tt.wantRange {
+			t.Errorf("Range %q: got %d %q (Content-Range %q)", tt.header, w.Code, w.Body.String(), w.Header().Get("Content-Range"))
+		}
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	conditions := []struct {
+		headers    map[string]string
+		wantStatus int
+	}{
+		{map[string]string{"If-Match": etag}, http.StatusOK},
+		{map[string]string{"If-Match": `"other"`}, http.StatusPreconditionFailed},
+		{map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{map[string]string{"If-Modified-Since": future}, http.StatusNotModified},
+		{map[string]string{"If-Modified-Since": past}, http.StatusOK},
+		{map[string]string{"If-Unmodified-Since": past}, http.StatusPreconditionFailed},
+		{map[string]string{"If-Match": etag, "If-Unmodified-Since": past}, http.StatusOK},
+		{map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": future}, http.StatusOK},
+	}
+	for _, tt := range conditions {
+		if w := get("/rangebucket/video.bin", tt.headers); w.Code != tt.wantStatus {
+			t.Errorf("Conditions %v: expected status %d but got %d", tt.headers, tt.wantStatus, w.Code)
+		}
+	}
+
+	w = get("/rangebucket/video.bin?response-content-type=video/mp4&response-content-disposition=attachment", nil)
+	if w.Header().Get("Content-Type") != "video/mp4" || w.Header().Get("Content-Disposition") != "attachment" {
+		t.Errorf("Expected response-* overrides to be applied, got %v", w.Header())
+	}
+}
+
+// Test des erreurs S3 renvoyées au format XML
+func TestErrorResponses(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/errorbucket", nil))
+
+	tests := []struct {
+		method, target string
+		wantStatus     int
+		wantCode       string
+	}{
+		{http.MethodGet, "/errorbucket/missing.txt", http.StatusNotFound, "NoSuchKey"},
+		{http.MethodGet, "/nosuchbucket/missing.txt", http.StatusNotFound, "NoSuchBucket"},
+		{http.MethodGet, "/nosuchbucket", http.StatusNotFound, "NoSuchBucket"},
+		{http.MethodGet, "/errorbucket?policy", http.StatusNotFound, "NoSuchBucketPolicy"},
+		{http.MethodGet, "/errorbucket?max-keys=abc", http.StatusBadRequest, "InvalidArgument"},
+		{http.MethodPut, "/errorbucket/obj?partNumber=1&uploadId=unknown", http.StatusNotFound, "NoSuchUpload"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader("")))
+		var apiErr dto.Error
+		if err := xml.Unmarshal(w.Body.Bytes(), &apiErr); err != nil {
+			t.Errorf("%s %s: invalid XML error body %q", tt.method, tt.target, w.Body.String())
+			continue
+		}
+		if w.Code != tt.wantStatus || apiErr.Code != tt.wantCode || apiErr.RequestId == "" {
+			t.Errorf("%s %s: expected %d %s but got %d %+v", tt.method, tt.target, tt.wantStatus, tt.wantCode, w.Code, apiErr)
+		}
+	}
+
+	// Une réponse HEAD n'a pas de corps
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/errorbucket/missing.txt", nil))
+	if w.Code != http.StatusNotFound || w.Body.Len() != 0 {
+		t.Errorf("HEAD: expected an empty 404 but got %d %q", w.Code, w.Body.String())
+	}
+}
+
+// Test des règles de création des buckets : nommage, bucket existant et contrainte de localisation
+func TestCreateBucketRules(t *testing.T) {
+	router := newTestRouter()
+
+	tests := []struct {
+		target, body string
+		wantStatus   int
+		wantCode     string
+	}{
+		{"/Invalid_Bucket", "", http.StatusBadRequest, "InvalidBucketName"},
+		{"/ab", "", http.StatusBadRequest, "InvalidBucketName"},
+		{"/192.168.1.1", "", http.StatusBadRequest, "InvalidBucketName"},
+		{"/xn--bucket", "", http.StatusBadRequest, "InvalidBucketName"},
+		{"/rulesbucket", "<CreateBucketConfiguration><LocationConstraint>eu-west-3</LocationConstraint></CreateBucketConfiguration>", http.StatusBadRequest, "IllegalLocationConstraintException"},
+		{"/rulesbucket", "<CreateBucketConfiguration>", http.StatusBadRequest, "MalformedXML"},
+		{"/rulesbucket", "<CreateBucketConfiguration><LocationConstraint>us-east-1</LocationConstraint></CreateBucketConfiguration>", http.StatusOK, ""},
+		{"/rulesbucket", "", http.StatusConflict, "BucketAlreadyOwnedByYou"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, tt.target, strings.NewReader(tt.body)))
+		if w.Code != tt.wantStatus {
+			t.Errorf("PUT %s: expected status %d but got %d (%s)", tt.target, tt.wantStatus, w.Code, w.Body.String())
+			continue
+		}
+		if tt.wantCode == "" {
+			continue
+		}
+		var apiErr dto.Error
+		if err := xml.Unmarshal(w.Body.Bytes(), &apiErr); err != nil || apiErr.Code != tt.wantCode {
+			t.Errorf("PUT %s: expected code %s but got %q", tt.target, tt.wantCode, w.Body.String())
+		}
+	}
+}
+
+// Test de la suppression d'un bucket non vide : refusée avec BucketNotEmpty, le contenu est conservé
+func TestDeleteNonEmptyBucket(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/fullbucket", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/fullbucket/keep.txt", strings.NewReader("keep")))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/fullbucket", nil))
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "<Code>BucketNotEmpty</Code>") {
+		t.Fatalf("Expected 409 BucketNotEmpty but got %d %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat("./data/fullbucket/keep.txt"); err != nil {
+		t.Errorf("Expected the object to be kept: %v", err)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/fullbucket/keep.txt", nil))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/fullbucket", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d but got %d", http.StatusNoContent, w.Code)
+	}
+}
+
+// Test de la commande d'administration purge-bucket
+func TestPurgeBucketCommand(t *testing.T) {
+	s := storage.NewMemoryBackend()
+	s.CreateBucket("purgebucket", "")
+	for _, key := range []string{"a", "b", "c"} {
+		s.PutObject("purgebucket", key, strings.NewReader(key), storage.ObjectMetadata{})
+	}
+
+	var out bytes.Buffer
+	if code := runCommand(context.Background(), []string{"purge-bucket", "purgebucket"}, s, &out); code != 2 || !s.BucketExists("purgebucket") {
+		t.Fatalf("Expected an unconfirmed purge to be refused, got code %d: %s", code, out.String())
+	}
+
+	out.Reset()
+	code := runCommand(context.Background(), []string{"purge-bucket", "-confirm", "purgebucket", "-batch", "2", "purgebucket"}, s, &out)
+	if code != 0 || s.BucketExists("purgebucket") {
+		t.Fatalf("Expected the bucket to be purged, got code %d: %s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Lot 2 : 3 version(s) supprimée(s)") {
+		t.Errorf("Expected progress to be reported, got %s", out.String())
+	}
+}
+
+// Test de la suppression multiple : résultat par clé, mode Quiet, Content-MD5 et versions
+func TestDeleteObjects(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/multidelbucket", nil))
+	for _, key := range []string{"a.txt", "dir/b.txt", "c.txt"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/multidelbucket/"+key, strings.NewReader(key)))
+	}
+
+	deleteObjects := func(handler http.Handler, body string, withMD5 bool) (*httptest.ResponseRecorder, dto.DeleteResult) {
+		req := httptest.NewRequest(http.MethodPost, "/multidelbucket?delete", strings.NewReader(body))
+		if withMD5 {
+			sum := md5.Sum([]byte(body))
+			req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var result dto.DeleteResult
+		xml.Unmarshal(w.Body.Bytes(), &result)
+		return w, result
+	}
+
+	body := `<Delete><Object><Key>a.txt</Key></Object><Object><Key>dir/b.txt</Key></Object><Object><Key>missing.txt</Key></Object></Delete>`
+	if w, _ := deleteObjects(router, body, false); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "<Code>InvalidRequest</Code>") {
+		t.Errorf("Expected a missing Content-MD5 to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+	req := httptest.NewRequest(http.MethodPost, "/multidelbucket?delete", strings.NewReader(body))
+	req.Header.Set("Content-MD5", "1B2M2Y8AsgTpgAmY7PhCfg==")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "<Code>BadDigest</Code>") {
+		t.Errorf("Expected a wrong Content-MD5 to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+
+	w, result := deleteObjects(router, body, true)
+	if w.Code != http.StatusOK || len(result.Deleted) != 3 || len(result.Errors) != 0 {
+		t.Fatalf("Expected 3 deleted keys, got %d %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat("./data/multidelbucket/a.txt"); !os.IsNotExist(err) {
+		t.Errorf("Expected a.txt to be deleted")
+	}
+
+	// Mode Quiet : seules les erreurs sont listées
+	_, result = deleteObjects(router, `<Delete><Quiet>true</Quiet><Object><Key>c.txt</Key></Object><Object><Key>x</Key><VersionId>bad</VersionId></Object></Delete>`, true)
+	if len(result.Deleted) != 0 || len(result.Errors) != 1 || result.Errors[0].Code != "NoSuchVersion" {
+		t.Errorf("Unexpected quiet result %+v", result)
+	}
+
+	// Bucket versionné : marqueur de suppression créé, puis supprimé par son versionId
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/multidelbucket?versioning",
+		strings.NewReader(`<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>`)))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/multidelbucket/v.txt", strings.NewReader("v")))
+	_, result = deleteObjects(router, `<Delete><Object><Key>v.txt</Key></Object></Delete>`, true)
+	if len(result.Deleted) != 1 || !result.Deleted[0].DeleteMarker || result.Deleted[0].DeleteMarkerVersionId == "" {
+		t.Fatalf("Expected a delete marker, got %+v", result)
+	}
+	markerID := result.Deleted[0].DeleteMarkerVersionId
+	_, result = deleteObjects(router, `<Delete><Object><Key>v.txt</Key><VersionId>`+markerID+`</VersionId></Object></Delete>`, true)
+	if len(result.Deleted) != 1 || result.Deleted[0].VersionId != markerID || !result.Deleted[0].DeleteMarker {
+		t.Errorf("Expected the delete marker to be removed, got %+v", result)
+	}
+
+	// Serveur complet : chaque clé est autorisée séparément par la politique du bucket
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/multidelbucket/tmp/t.txt", strings.NewReader("t")))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/multidelbucket/keep.txt", strings.NewReader("k")))
+	policyDoc := `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:DeleteObject", "Resource": "arn:aws:s3:::multidelbucket/tmp/*"}]}`
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/multidelbucket?policy", strings.NewReader(policyDoc)))
+	cfg := config.Config{AccessKeyID: "test", SecretAccessKey: "secret", Region: "us-east-1"}
+	store, _ := auth.NewCredentialStore(cfg)
+	server := newServer(storage.NewStorage("./data"), cfg, store)
+	_, result = deleteObjects(server, `<Delete><Object><Key>tmp/t.txt</Key></Object><Object><Key>keep.txt</Key></Object></Delete>`, true)
+	if len(result.Deleted) != 1 || result.Deleted[0].Key != "tmp/t.txt" || len(result.Errors) != 1 || result.Errors[0].Code != "AccessDenied" {
+		t.Errorf("Expected tmp/t.txt to be deleted and keep.txt to be denied, got %+v", result)
+	}
+}
+
+// Test de la copie côté serveur (CopyObject) : métadonnées COPY/REPLACE, versions et conditions
+func TestCopyObject(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/copysrcbucket", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/copydstbucket", nil))
+	put := httptest.NewRequest(http.MethodPut, "/copysrcbucket/dir/source%20file.txt", strings.NewReader("copy me"))
+	put.Header.Set("Content-Type", "text/plain")
+	put.Header.Set("x-amz-meta-origin", "upload")
+	router.ServeHTTP(httptest.NewRecorder(), put)
+
+	copyObject := func(target, source string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, target, nil)
+		req.Header.Set("x-amz-copy-source", source)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := copyObject("/copydstbucket/copy.txt", "/copysrcbucket/dir/source%20file.txt", nil)
+	var result dto.CopyObjectResult
+	if err := xml.Unmarshal(w.Body.Bytes(), &result); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected copy to succeed, got %d %s", w.Code, w.Body.String())
+	}
+	sum := md5.Sum([]byte("copy me"))
+	if result.ETag != "\""+hex.EncodeToString(sum[:])+"\"" || result.LastModified == "" {
+		t.Errorf("Unexpected CopyObjectResult %+v", result)
+	}
+	get := httptest.NewRecorder()
+	router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/copydstbucket/copy.txt", nil))
+	if get.Body.String() != "copy me" || get.Header().Get("Content-Type") != "text/plain" || get.Header().Get("x-amz-meta-origin") != "upload" {
+		t.Errorf("Expected content and metadata to be copied, got %q %v", get.Body.String(), get.Header())
+	}
+
+	// REPLACE : les métadonnées de la requête remplacent celles de la source
+	w = copyObject("/copydstbucket/copy.txt", "copysrcbucket/dir/source%20file.txt", map[string]string{
+		"x-amz-metadata-directive": "REPLACE",
+		"Content-Type":             "application/json",
+		"x-amz-meta-origin":        "copy",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected REPLACE copy to succeed, got %d %s", w.Code, w.Body.String())
+	}
+	head := httptest.NewRecorder()
+	router.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/copydstbucket/copy.txt", nil))
+	if head.Header().Get("Content-Type") != "application/json" || head.Header().Get("x-amz-meta-origin") != "copy" {
+		t.Errorf("Expected replaced metadata, got %v", head.Header())
+	}
+
+	// Étiquettes : copiées par défaut (COPY), remplacées par celles de x-amz-tagging avec REPLACE
+	put = httptest.NewRequest(http.MethodPut, "/copysrcbucket/tagged.txt", strings.NewReader("tagged"))
+	put.Header.Set("x-amz-tagging", "team=a%20b&tmp=yes")
+	router.ServeHTTP(httptest.NewRecorder(), put)
+	tagsOf := func(key string) map[string]string {
+		metadata, err := storage.NewStorage("./data").GetObjectMetadata("copydstbucket", key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return metadata.Tags
+	}
+	if w := copyObject("/copydstbucket/tags.txt", "copysrcbucket/tagged.txt", map[string]string{"x-amz-tagging": "ignored=1"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected COPY tagging to succeed, got %d %s", w.Code, w.Body.String())
+	}
+	if tags := tagsOf("tags.txt"); len(tags) != 2 || tags["team"] != "a b" || tags["tmp"] != "yes" {
+		t.Errorf("Expected the source tags to be copied, got %v", tags)
+	}
+	if w := copyObject("/copydstbucket/tags.txt", "copysrcbucket/tagged.txt", map[string]string{
+		"x-amz-tagging-directive": "REPLACE",
+		"x-amz-tagging":           "owner=copy",
+	}); w.Code != http.StatusOK {
+		t.Fatalf("Expected REPLACE tagging to succeed, got %d %s", w.Code, w.Body.String())
+	}
+	if tags := tagsOf("tags.txt"); len(tags) != 1 || tags["owner"] != "copy" {
+		t.Errorf("Expected the request tags to replace the source tags, got %v", tags)
+	}
+	if w := copyObject("/copydstbucket/tags.txt", "copysrcbucket/tagged.txt", map[string]string{"x-amz-tagging-directive": "REPLACE"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected REPLACE tagging without tags to succeed, got %d %s", w.Code, w.Body.String())
+	}
+	if tags := tagsOf("tags.txt"); len(tags) != 0 {
+		t.Errorf("Expected REPLACE without x-amz-tagging to drop the tags, got %v", tags)
+	}
+	invalidTags := map[string]map[string]string{
+		"unknown directive": {"x-amz-tagging-directive": "MERGE"},
+		"too many tags":     {"x-amz-tagging-directive": "REPLACE", "x-amz-tagging": "a=1&b=2&c=3&d=4&e=5&f=6&g=7&h=8&i=9&j=10&k=11"},
+		"empty key":         {"x-amz-tagging-directive": "REPLACE", "x-amz-tagging": "=value"},
+		"long value":        {"x-amz-tagging-directive": "REPLACE", "x-amz-tagging": "key=" + strings.Repeat("v", 257)},
+		"malformed":         {"x-amz-tagging-directive": "REPLACE", "x-amz-tagging": "key=%zz"},
+	}
+	for name, headers := range invalidTags {
+		if w := copyObject("/copydstbucket/tags.txt", "copysrcbucket/tagged.txt", headers); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "<Code>InvalidArgument</Code>") {
+			t.Errorf("%s: expected InvalidArgument, got %d %s", name, w.Code, w.Body.String())
+		}
+	}
+
+	// Copie d'un objet sur lui-même sans REPLACE, directive inconnue, source absente
+	if w := copyObject("/copydstbucket/copy.txt", "copydstbucket/copy.txt", nil); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "<Code>InvalidRequest</Code>") {
+		t.Errorf("Expected self copy to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+	if w := copyObject("/copydstbucket/copy.txt", "copysrcbucket/dir/source%20file.txt", map[string]string{"x-amz-metadata-directive": "MOVE"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown directive to be rejected, got %d", w.Code)
+	}
+	if w := copyObject("/copydstbucket/copy.txt", "copysrcbucket/missing.txt", nil); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "<Code>NoSuchKey</Code>") {
+		t.Errorf("Expected NoSuchKey for a missing source, got %d %s", w.Code, w.Body.String())
+	}
+	if w := copyObject("/copydstbucket/copy.txt", "nosuchsrcbucket/a.txt", nil); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "<Code>NoSuchBucket</Code>") {
+		t.Errorf("Expected NoSuchBucket for a missing source bucket, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Conditions x-amz-copy-source-if-* : tout échec donne 412
+	etag := "\"" + hex.EncodeToString(sum[:]) + "\""
+	conditions := []struct {
+		header, value string
+		status        int
+	}{
+		{"x-amz-copy-source-if-match", etag, http.StatusOK},
+		{"x-amz-copy-source-if-match", "\"other\"", http.StatusPreconditionFailed},
+		{"x-amz-copy-source-if-none-match", etag, http.StatusPreconditionFailed},
+		{"x-amz-copy-source-if-modified-since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), http.StatusPreconditionFailed},
+		{"x-amz-copy-source-if-unmodified-since", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), http.StatusPreconditionFailed},
+	}
+	for _, c := range conditions {
+		if w := copyObject("/copydstbucket/cond.txt", "copysrcbucket/dir/source%20file.txt", map[string]string{c.header: c.value}); w.Code != c.status {
+			t.Errorf("%s: %s: expected %d, got %d", c.header, c.value, c.status, w.Code)
+		}
+	}
+
+	// Bucket versionné : copie d'une version précise, qui peut restaurer une ancienne version sur la même clé
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/copysrcbucket?versioning",
+		strings.NewReader(`<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>`)))
+	first := httptest.NewRecorder()
+	router.ServeHTTP(first, httptest.NewRequest(http.MethodPut, "/copysrcbucket/v.txt", strings.NewReader("first")))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/copysrcbucket/v.txt", strings.NewReader("second")))
+	firstID := first.Header().Get("x-amz-version-id")
+	w = copyObject("/copysrcbucket/v.txt", "copysrcbucket/v.txt?versionId="+firstID, nil)
+	if w.Code != http.StatusOK || w.Header().Get("x-amz-copy-source-version-id") != firstID || w.Header().Get("x-amz-version-id") == "" {
+		t.Fatalf("Expected the first version to be restored, got %d %v %s", w.Code, w.Header(), w.Body.String())
+	}
+	get = httptest.NewRecorder()
+	router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/copysrcbucket/v.txt", nil))
+	if get.Body.String() != "first" {
+		t.Errorf("Expected restored content 'first', got %q", get.Body.String())
+	}
+	if w := copyObject("/copydstbucket/v.txt", "copysrcbucket/v.txt?versionId=bad", nil); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "<Code>NoSuchVersion</Code>") {
+		t.Errorf("Expected NoSuchVersion, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Serveur complet : la lecture de la source est autorisée séparément de l'écriture de la destination
+	policyDoc := `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::copydstbucket/*"}]}`
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/copydstbucket?policy", strings.NewReader(policyDoc)))
+	cfg := config.Config{AccessKeyID: "test", SecretAccessKey: "secret", Region: "us-east-1"}
+	store, _ := auth.NewCredentialStore(cfg)
+	server := newServer(storage.NewStorage("./data"), cfg, store)
+	req := httptest.NewRequest(http.MethodPut, "/copydstbucket/stolen.txt", nil)
+	req.Header.Set("x-amz-copy-source", "copysrcbucket/dir/source%20file.txt")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected an unreadable source to be denied, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+// Test de UploadPartCopy : parts copiées depuis des plages d'un objet existant
+func TestUploadPartCopy(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/partcopybucket", nil))
+	source := bytes.Repeat([]byte("0123456789"), storage.MinPartSize/10+1)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/partcopybucket/source.bin", bytes.NewReader(source)))
+
+	create := httptest.NewRecorder()
+	router.ServeHTTP(create, httptest.NewRequest(http.MethodPost, "/partcopybucket/target.bin?uploads", nil))
+	var initiated dto.InitiateMultipartUploadResult
+	xml.Unmarshal(create.Body.Bytes(), &initiated)
+
+	copyPart := func(partNumber int, sourceRange string) (*httptest.ResponseRecorder, dto.CopyPartResult) {
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/partcopybucket/target.bin?partNumber=%d&uploadId=%s", partNumber, initiated.UploadID), nil)
+		req.Header.Set("x-amz-copy-source", "/partcopybucket/source.bin")
+		if sourceRange != "" {
+			req.Header.Set("x-amz-copy-source-range", sourceRange)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var result dto.CopyPartResult
+		xml.Unmarshal(w.Body.Bytes(), &result)
+		return w, result
+	}
+
+	w1, part1 := copyPart(1, "")
+	w2, part2 := copyPart(2, "bytes=0-9")
+	if w1.Code != http.StatusOK || w2.Code != http.StatusOK || part1.ETag == "" || part2.ETag == "" {
+		t.Fatalf("Expected parts to be copied, got %d %s / %d %s", w1.Code, w1.Body.String(), w2.Code, w2.Body.String())
+	}
+	for _, invalid := range []string{"bytes=5-", "bytes=9-2", fmt.Sprintf("bytes=0-%d", len(source))} {
+		if w, _ := copyPart(3, invalid); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "<Code>InvalidArgument</Code>") {
+			t.Errorf("%s: expected InvalidArgument, got %d %s", invalid, w.Code, w.Body.String())
+		}
+	}
+
+	complete := fmt.Sprintf(`<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>%s</ETag></Part><Part><PartNumber>2</PartNumber><ETag>%s</ETag></Part></CompleteMultipartUpload>`, part1.ETag, part2.ETag)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/partcopybucket/target.bin?uploadId="+initiated.UploadID, strings.NewReader(complete)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected upload to complete, got %d %s", w.Code, w.Body.String())
+	}
+	get := httptest.NewRecorder()
+	router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/partcopybucket/target.bin", nil))
+	if !bytes.Equal(get.Body.Bytes(), append(append([]byte{}, source...), "0123456789"...)) {
+		t.Errorf("Unexpected assembled object of %d bytes", get.Body.Len())
+	}
+}
+
+// Test de la vérification des empreintes à l'écriture (Content-MD5, x-amz-checksum-*) et du renvoi
+// de la somme de contrôle enregistrée avec x-amz-checksum-mode
+func TestPutChecksums(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/checksumbucket", nil))
+
+	content := "checksummed content"
+	md5Sum := md5.Sum([]byte(content))
+	crc := crc32.ChecksumIEEE([]byte(content))
+	crcValue := base64.StdEncoding.EncodeToString([]byte{byte(crc >> 24), byte(crc >> 16), byte(crc >> 8), byte(crc)})
+	shaSum := sha256.Sum256([]byte(content))
+	shaValue := base64.StdEncoding.EncodeToString(shaSum[:])
+
+	put := func(key string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/checksumbucket/"+key, strings.NewReader(content))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		code    string
+	}{
+		{"valid Content-MD5", map[string]string{"Content-MD5": base64.StdEncoding.EncodeToString(md5Sum[:])}, http.StatusOK, ""},
+		{"wrong Content-MD5", map[string]string{"Content-MD5": "1B2M2Y8AsgTpgAmY7PhCfg=="}, http.StatusBadRequest, "BadDigest"},
+		{"malformed Content-MD5", map[string]string{"Content-MD5": "not-md5"}, http.StatusBadRequest, "InvalidDigest"},
+		{"valid CRC32", map[string]string{"x-amz-checksum-crc32": crcValue}, http.StatusOK, ""},
+		{"wrong SHA256", map[string]string{"x-amz-checksum-sha256": base64.StdEncoding.EncodeToString(make([]byte, 32))}, http.StatusBadRequest, "BadDigest"},
+		{"malformed CRC32", map[string]string{"x-amz-checksum-crc32": "AAAA"}, http.StatusBadRequest, "InvalidRequest"},
+		{"multiple checksums", map[string]string{"x-amz-checksum-crc32": crcValue, "x-amz-checksum-sha256": shaValue}, http.StatusBadRequest, "InvalidRequest"},
+		{"unknown SDK algorithm", map[string]string{"x-amz-sdk-checksum-algorithm": "MD4"}, http.StatusBadRequest, "InvalidRequest"},
+		{"conflicting SDK algorithm", map[string]string{"x-amz-sdk-checksum-algorithm": "SHA1", "x-amz-checksum-crc32": crcValue}, http.StatusBadRequest, "InvalidRequest"},
+	}
+	for _, c := range cases {
+		w := put("object.txt", c.headers)
+		if w.Code != c.status || (c.code != "" && !strings.Contains(w.Body.String(), "<Code>"+c.code+"</Code>")) {
+			t.Errorf("%s: expected %d %s, got %d %s", c.name, c.status, c.code, w.Code, w.Body.String())
+		}
+	}
+
+	// Une écriture refusée ne remplace pas l'objet existant
+	if w := put("kept.txt", nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected PUT to succeed, got %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodPut, "/checksumbucket/kept.txt", strings.NewReader("corrupted"))
+	req.Header.Set("x-amz-checksum-crc32", crcValue)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	get := httptest.NewRecorder()
+	router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/checksumbucket/kept.txt", nil))
+	if get.Body.String() != content {
+		t.Errorf("Expected the rejected write to leave the object untouched, got %q", get.Body.String())
+	}
+
+	// Somme calculée par le serveur (x-amz-sdk-checksum-algorithm seul), renvoyée seulement sur demande
+	w := put("computed.txt", map[string]string{"x-amz-sdk-checksum-algorithm": "sha256"})
+	if w.Code != http.StatusOK || w.Header().Get("x-amz-checksum-sha256") != shaValue {
+		t.Fatalf("Expected the SHA256 checksum in the PUT response, got %d %v", w.Code, w.Header())
+	}
+	head := httptest.NewRecorder()
+	router.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/checksumbucket/computed.txt", nil))
+	if head.Header().Get("x-amz-checksum-sha256") != "" {
+		t.Errorf("Expected no checksum without x-amz-checksum-mode")
+	}
+	req = httptest.NewRequest(http.MethodHead, "/checksumbucket/computed.txt", nil)
+	req.Header.Set("x-amz-checksum-mode", "ENABLED")
+	head = httptest.NewRecorder()
+	router.ServeHTTP(head, req)
+	if head.Header().Get("x-amz-checksum-sha256") != shaValue {
+		t.Errorf("Expected the stored checksum with x-amz-checksum-mode, got %v", head.Header())
+	}
+	req = httptest.NewRequest(http.MethodGet, "/checksumbucket/computed.txt", nil)
+	req.Header.Set("x-amz-checksum-mode", "ENABLED")
+	req.Header.Set("Range", "bytes=0-3")
+	get = httptest.NewRecorder()
+	router.ServeHTTP(get, req)
+	if get.Code != http.StatusPartialContent || get.Header().Get("x-amz-checksum-sha256") != "" {
+		t.Errorf("Expected no full-object checksum on a range read, got %d %v", get.Code, get.Header())
+	}
+
+	// Somme transmise en trailer d'un corps aws-chunked (déjà décodé par le middleware)
+	for _, trailerValue := range []string{crcValue, base64.StdEncoding.EncodeToString([]byte{0, 0, 0, 0})} {
+		req = httptest.NewRequest(http.MethodPut, "/checksumbucket/trailer.txt", strings.NewReader(content))
+		req.Header.Set("x-amz-trailer", "x-amz-checksum-crc32")
+		req.Trailer = http.Header{"X-Amz-Checksum-Crc32": {trailerValue}}
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if expected := trailerValue == crcValue; (w.Code == http.StatusOK) != expected {
+			t.Errorf("Trailer %s: unexpected status %d %s", trailerValue, w.Code, w.Body.String())
+		}
+	}
+}
+
+// Test du chiffrement côté serveur : SSE-S3, SSE-C (avec lecture partielle) et copie d'un objet SSE-C
+func TestServerSideEncryption(t *testing.T) {
+	st := storage.NewStorage("./data")
+	st.MasterKey = bytes.Repeat([]byte{1}, storage.SSEKeySize)
+	router := newTestHandler(st)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/ssebucket", nil))
+
+	customerKey := bytes.Repeat([]byte{2}, storage.SSEKeySize)
+	setCustomerKey := func(req *http.Request, prefix string) {
+		req.Header.Set(prefix+"algorithm", "AES256")
+		req.Header.Set(prefix+"key", base64.StdEncoding.EncodeToString(customerKey))
+		req.Header.Set(prefix+"key-MD5", storage.CustomerKeyMD5(customerKey))
+	}
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// SSE-S3 : chiffrement transparent, signalé dans les réponses
+	req := httptest.NewRequest(http.MethodPut, "/ssebucket/s3.txt", strings.NewReader("managed"))
+	req.Header.Set("x-amz-server-side-encryption", "AES256")
+	if w := do(req); w.Code != http.StatusOK || w.Header().Get("x-amz-server-side-encryption") != "AES256" {
+		t.Fatalf("Expected SSE-S3 PUT to succeed, got %d %v", w.Code, w.Header())
+	}
+	w := do(httptest.NewRequest(http.MethodGet, "/ssebucket/s3.txt", nil))
+	if w.Body.String() != "managed" || w.Header().Get("x-amz-server-side-encryption") != "AES256" {
+		t.Errorf("Expected the decrypted SSE-S3 object, got %q %v", w.Body.String(), w.Header())
+	}
+	req = httptest.NewRequest(http.MethodPut, "/ssebucket/kms.txt", strings.NewReader("x"))
+	req.Header.Set("x-amz-server-side-encryption", "aws:kms")
+	if w := do(req); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected unsupported encryption to be rejected, got %d", w.Code)
+	}
+
+	// SSE-C : la clé est exigée à chaque lecture
+	req = httptest.NewRequest(http.MethodPut, "/ssebucket/c.txt", strings.NewReader("customer secret"))
+	setCustomerKey(req, "x-amz-server-side-encryption-customer-")
+	if w := do(req); w.Code != http.StatusOK || w.Header().Get("x-amz-server-side-encryption-customer-key-MD5") != storage.CustomerKeyMD5(customerKey) {
+		t.Fatalf("Expected SSE-C PUT to succeed, got %d %v", w.Code, w.Header())
+	}
+	if w := do(httptest.NewRequest(http.MethodGet, "/ssebucket/c.txt", nil)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected GET without the customer key to fail, got %d", w.Code)
+	}
+	if w := do(httptest.NewRequest(http.MethodHead, "/ssebucket/c.txt", nil)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected HEAD without the customer key to fail, got %d", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/ssebucket/c.txt", nil)
+	setCustomerKey(req, "x-amz-server-side-encryption-customer-")
+	req.Header.Set("x-amz-server-side-encryption-customer-key-MD5", "AAAAAAAAAAAAAAAAAAAAAA==")
+	if w := do(req); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a wrong key MD5 to be rejected, got %d", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/ssebucket/c.txt", nil)
+	setCustomerKey(req, "x-amz-server-side-encryption-customer-")
+	req.Header.Set("Range", "bytes=9-14")
+	if w := do(req); w.Code != http.StatusPartialContent || w.Body.String() != "secret" {
+		t.Errorf("Expected range read of the SSE-C object, got %d %q", w.Code, w.Body.String())
+	}
+
+	// Copie d'un objet SSE-C vers un objet SSE-S3
+	req = httptest.NewRequest(http.MethodPut, "/ssebucket/copy.txt", nil)
+	req.Header.Set("x-amz-copy-source", "/ssebucket/c.txt")
+	req.Header.Set("x-amz-server-side-encryption", "AES256")
+	if w := do(req); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected copy without the source key to fail, got %d", w.Code)
+	}
+	setCustomerKey(req, "x-amz-copy-source-server-side-encryption-customer-")
+	if w := do(req); w.Code != http.StatusOK || w.Header().Get("x-amz-server-side-encryption") != "AES256" {
+		t.Fatalf("Expected copy to succeed, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(httptest.NewRequest(http.MethodGet, "/ssebucket/copy.txt", nil)); w.Body.String() != "customer secret" {
+		t.Errorf("Expected the copied content, got %q", w.Body.String())
+	}
+
+	// Upload multipart SSE-C : la clé accompagne chaque part, pas CompleteMultipartUpload
+	req = httptest.NewRequest(http.MethodPost, "/ssebucket/multi.txt?uploads", nil)
+	setCustomerKey(req, "x-amz-server-side-encryption-customer-")
+	w = do(req)
+	var initiated dto.InitiateMultipartUploadResult
+	if err := xml.Unmarshal(w.Body.Bytes(), &initiated); err != nil || initiated.UploadID == "" {
+		t.Fatalf("Expected SSE-C multipart upload to be initiated, got %d %s", w.Code, w.Body.String())
+	}
+	partURL := "/ssebucket/multi.txt?partNumber=1&uploadId=" + initiated.UploadID
+	if w := do(httptest.NewRequest(http.MethodPut, partURL, strings.NewReader("multipart secret"))); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a part without the customer key to fail, got %d", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodPut, partURL, strings.NewReader("multipart secret"))
+	setCustomerKey(req, "x-amz-server-side-encryption-customer-")
+	w = do(req)
+	if w.Code != http.StatusOK || w.Header().Get("x-amz-server-side-encryption-customer-key-MD5") != storage.CustomerKeyMD5(customerKey) {
+		t.Fatalf("Expected the SSE-C part to be stored, got %d %v", w.Code, w.Header())
+	}
+	complete := fmt.Sprintf(`<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>%s</ETag></Part></CompleteMultipartUpload>`, w.Header().Get("ETag"))
+	if w := do(httptest.NewRequest(http.MethodPost, "/ssebucket/multi.txt?uploadId="+initiated.UploadID, strings.NewReader(complete))); w.Code != http.StatusOK {
+		t.Fatalf("Expected complete to succeed without the customer key, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(httptest.NewRequest(http.MethodGet, "/ssebucket/multi.txt", nil)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected GET without the customer key to fail, got %d", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/ssebucket/multi.txt", nil)
+	setCustomerKey(req, "x-amz-server-side-encryption-customer-")
+	if w := do(req); w.Code != http.StatusOK || w.Body.String() != "multipart secret" {
+		t.Errorf("Expected the assembled SSE-C object, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+// Test du chiffrement par défaut d'un bucket (?encryption) appliqué aux objets écrits sans en-tête de chiffrement
+func TestBucketEncryption(t *testing.T) {
+	st := storage.NewStorage("./data")
+	st.MasterKey = bytes.Repeat([]byte{1}, storage.SSEKeySize)
+	router := newTestHandler(st)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/defaultsse", nil))
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do(http.MethodGet, "/defaultsse?encryption", ""); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "ServerSideEncryptionConfigurationNotFoundError") {
+		t.Errorf("Expected no encryption configuration, got %d %s", w.Code, w.Body.String())
+	}
+	kms := `<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>aws:kms</SSEAlgorithm></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>`
+	if w := do(http.MethodPut, "/defaultsse?encryption", kms); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected aws:kms to be rejected, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/defaultsse?encryption", "<ServerSideEncryptionConfiguration/>"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "MalformedXML") {
+		t.Errorf("Expected a configuration without rule to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+	config := `<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>AES256</SSEAlgorithm></ApplyServerSideEncryptionByDefault><BucketKeyEnabled>true</BucketKeyEnabled></Rule></ServerSideEncryptionConfiguration>`
+	if w := do(http.MethodPut, "/defaultsse?encryption", config); w.Code != http.StatusOK {
+		t.Fatalf("Expected the configuration to be stored, got %d %s", w.Code, w.Body.String())
+	}
+	var got dto.ServerSideEncryptionConfiguration
+	w := do(http.MethodGet, "/defaultsse?encryption", "")
+	if err := xml.Unmarshal(w.Body.Bytes(), &got); err != nil || len(got.Rules) != 1 || got.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm != "AES256" || !got.Rules[0].BucketKeyEnabled {
+		t.Errorf("Unexpected configuration %s (%v)", w.Body.String(), err)
+	}
+
+	// Un objet écrit sans en-tête est chiffré automatiquement
+	if w := do(http.MethodPut, "/defaultsse/auto.txt", "encrypted by default"); w.Code != http.StatusOK || w.Header().Get("x-amz-server-side-encryption") != "AES256" {
+		t.Fatalf("Expected the object to be encrypted by default, got %d %v", w.Code, w.Header())
+	}
+	if data, err := os.ReadFile(st.ObjectPath("defaultsse", "auto.txt")); err != nil || strings.Contains(string(data), "encrypted by default") {
+		t.Errorf("Expected the object to be encrypted on disk (%v)", err)
+	}
+	if w := do(http.MethodGet, "/defaultsse/auto.txt", ""); w.Body.String() != "encrypted by default" {
+		t.Errorf("Expected the decrypted object, got %q", w.Body.String())
+	}
+
+	if w := do(http.MethodDelete, "/defaultsse?encryption", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected the configuration to be deleted, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/defaultsse/plain.txt", "plain"); w.Header().Get("x-amz-server-side-encryption") != "" {
+		t.Errorf("Expected no encryption once the configuration is deleted, got %v", w.Header())
+	}
+}
+
+// Test de la commande d'administration rotate-master-key
+func TestRotateMasterKeyCommand(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, storage.SSEKeySize)
+	s := storage.NewMemoryBackend()
+	s.MasterKey = oldKey
+	s.CreateBucket("rotatebucket", "")
+	s.PutObject("rotatebucket", "a", strings.NewReader("a"), storage.ObjectMetadata{Encryption: &storage.EncryptionInfo{}})
+
+	// Nouvelle clé sans l'ancienne : la rotation échoue
+	s.MasterKey = bytes.Repeat([]byte{2}, storage.SSEKeySize)
+	var out bytes.Buffer
+	if code := runCommand(context.Background(), []string{"rotate-master-key"}, s, &out); code != 1 {
+		t.Fatalf("Expected the rotation to fail without the previous key, got code %d: %s", code, out.String())
+	}
+
+	s.PreviousMasterKey = oldKey
+	out.Reset()
+	if code := runCommand(context.Background(), []string{"rotate-master-key"}, s, &out); code != 0 || !strings.Contains(out.String(), "1 clé(s) réenveloppée(s)") {
+		t.Fatalf("Expected one key to be rewrapped, got code %d: %s", code, out.String())
+	}
+	s.PreviousMasterKey = nil
+	if file, _, err := s.GetObjectVersion("rotatebucket", "a", ""); err != nil {
+		t.Errorf("Expected the object to be readable with the new key: %v", err)
+	} else {
+		file.Close()
+	}
+}
+
+// Test de la configuration du cycle de vie d'un bucket (?lifecycle) et des étiquettes d'objet
+func TestBucketLifecycle(t *testing.T) {
+	router := newTestRouter()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/lifecyclebucket", nil))
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/lifecyclebucket?lifecycle", strings.NewReader(body))
+		sum := md5.Sum([]byte(body))
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := httptest.NewRecorder(); true {
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lifecyclebucket?lifecycle", nil))
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "NoSuchLifecycleConfiguration") {
+			t.Errorf("Expected NoSuchLifecycleConfiguration, got %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	config := `<LifecycleConfiguration>
+<Rule><ID>logs</ID><Filter><Prefix>logs/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>30</Days></Expiration><NoncurrentVersionExpiration><NoncurrentDays>7</NoncurrentDays></NoncurrentVersionExpiration></Rule>
+<Rule><ID>tmp</ID><Filter><And><Prefix>data/</Prefix><Tag><Key>tmp</Key><Value>yes</Value></Tag></And></Filter><Status>Disabled</Status><Expiration><Date>2030-01-01T00:00:00Z</Date></Expiration></Rule>
+<Rule><ID>uploads</ID><Prefix></Prefix><Status>Enabled</Status><AbortIncompleteMultipartUpload><DaysAfterInitiation>3</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule>
+</LifecycleConfiguration>`
+	req := httptest.NewRequest(http.MethodPut, "/lifecyclebucket?lifecycle", strings.NewReader(config))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a configuration without Content-MD5 to be rejected, got %d", w.Code)
+	}
+	if w := put(config); w.Code != http.StatusOK {
+		t.Fatalf("Expected the configuration to be stored, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lifecyclebucket?lifecycle", nil))
+	var got dto.LifecycleConfiguration
+	if err := xml.Unmarshal(w.Body.Bytes(), &got); err != nil || len(got.Rules) != 3 {
+		t.Fatalf("Unexpected configuration %s (%v)", w.Body.String(), err)
+	}
+	if rule := got.Rules[0]; rule.Filter == nil || rule.Filter.Prefix == nil || *rule.Filter.Prefix != "logs/" || rule.Expiration.Days != 30 || rule.NoncurrentVersionExpiration.NoncurrentDays != 7 {
+		t.Errorf("Unexpected first rule %+v", rule)
+	}
+	if rule := got.Rules[1]; rule.Status != "Disabled" || rule.Filter.And == nil || len(rule.Filter.And.Tags) != 1 || rule.Expiration.Date != "2030-01-01T00:00:00Z" {
+		t.Errorf("Unexpected second rule %+v", rule)
+	}
+
+	invalid := map[string]string{
+		"no action":         `<LifecycleConfiguration><Rule><Filter><Prefix/></Filter><Status>Enabled</Status></Rule></LifecycleConfiguration>`,
+		"days and date":     `<LifecycleConfiguration><Rule><Filter><Prefix/></Filter><Status>Enabled</Status><Expiration><Days>1</Days><Date>2030-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`,
+		"date not midnight": `<LifecycleConfiguration><Rule><Filter><Prefix/></Filter><Status>Enabled</Status><Expiration><Date>2030-01-01T10:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`,
+		"abort with tag":    `<LifecycleConfiguration><Rule><Filter><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><AbortIncompleteMultipartUpload><DaysAfterInitiation>1</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`,
+		"unknown status":    `<LifecycleConfiguration><Rule><Filter><Prefix/></Filter><Status>On</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`,
+		"duplicate id":      `<LifecycleConfiguration><Rule><ID>a</ID><Prefix/><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule><Rule><ID>a</ID><Prefix/><Status>Enabled</Status><Expiration><Days>2</Days></Expiration></Rule></LifecycleConfiguration>`,
+	}
+	for name, body := range invalid {
+		if w := put(body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d %s", name, w.Code, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/lifecyclebucket?lifecycle", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected the configuration to be deleted, got %d", w.Code)
+	}
+
+	// Étiquettes d'objet, sur lesquelles filtrent les règles
+	req = httptest.NewRequest(http.MethodPut, "/lifecyclebucket/tagged.txt", strings.NewReader("x"))
+	req.Header.Set("x-amz-tagging", "tmp=yes&team=a%20b")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/lifecyclebucket/tagged.txt", nil))
+	if w.Header().Get("x-amz-tagging-count") != "2" {
+		t.Errorf("Expected 2 tags, got %v", w.Header())
+	}
+	req = httptest.NewRequest(http.MethodPut, "/lifecyclebucket/bad.txt", strings.NewReader("x"))
+	req.Header.Set("x-amz-tagging", "a=1&a=2")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected duplicate tag keys to be rejected, got %d", w.Code)
+	}
+}
@@ -2,8 +2,11 @@
 package config
 
 import (
+	"encoding/base64"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +17,21 @@ type Config struct {
 	SecretAccessKey string
 	Region          string
 	StoragePath     string
+	ListenAddr      string
+	CredentialsFile string
+	// LegacyBucketOwner reçoit au démarrage les buckets sans propriétaire enregistré (défaut : ACCESS_KEY_ID,
+	// l'utilisateur des credentials par défaut)
+	LegacyBucketOwner string
+	// ETagVerifyInterval est la période du vérificateur d'ETags (0 : désactivé)
+	ETagVerifyInterval time.Duration
+	// SSEMasterKey est la clé maître AES-256 qui enveloppe les clés de données SSE-S3 (nil : SSE-S3 désactivé)
+	SSEMasterKey []byte
+	// SSEPreviousMasterKey est l'ancienne clé maître pendant une rotation (commande rotate-master-key)
+	SSEPreviousMasterKey []byte
+	// LifecycleInterval est la période d'évaluation du cycle de vie des buckets (0 : désactivé)
+	LifecycleInterval time.Duration
+	// LifecycleDryRun journalise les actions du cycle de vie sans les effectuer
+	LifecycleDryRun bool
 }
 
 // LoadConfig charge les variables d'environnement depuis le fichier .env
@@ -29,12 +47,19 @@ func LoadConfig() Config {
 		SecretAccessKey: os.Getenv("SECRET_ACCESS_KEY"),
 		Region:          os.Getenv("REGION"),
 		StoragePath:     os.Getenv("STORAGE_PATH"),
+		ListenAddr:      os.Getenv("LISTEN_ADDR"),
+		CredentialsFile: os.Getenv("CREDENTIALS_FILE"),
+
+		LegacyBucketOwner: os.Getenv("LEGACY_BUCKET_OWNER"),
 	}
 
 	// Définir des valeurs par défaut si nécessaire
 	if cfg.AccessKeyID == "" {
 		cfg.AccessKeyID = "default_access_key"
 	}
+	if cfg.LegacyBucketOwner == "" {
+		cfg.LegacyBucketOwner = cfg.AccessKeyID
+	}
 	if cfg.SecretAccessKey == "" {
 		cfg.SecretAccessKey = "default_secret_key"
 	}
@@ -44,6 +69,48 @@ func LoadConfig() Config {
 	if cfg.StoragePath == "" {
 		cfg.StoragePath = "./data/"
 	}
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = ":9000"
+	}
+	if interval := os.Getenv("ETAG_VERIFY_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			log.Printf("ETAG_VERIFY_INTERVAL invalide (%q), vérificateur d'ETags désactivé", interval)
+		}
+		cfg.ETagVerifyInterval = d
+	}
+	if interval := os.Getenv("LIFECYCLE_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			log.Printf("LIFECYCLE_INTERVAL invalide (%q), cycle de vie désactivé", interval)
+		}
+		cfg.LifecycleInterval = d
+	}
+	if dryRun := os.Getenv("LIFECYCLE_DRY_RUN"); dryRun != "" {
+		enabled, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			// Dans le doute, aucune suppression
+			log.Printf("LIFECYCLE_DRY_RUN invalide (%q), cycle de vie en simulation", dryRun)
+			enabled = true
+		}
+		cfg.LifecycleDryRun = enabled
+	}
+	cfg.SSEMasterKey = loadMasterKey("SSE_MASTER_KEY")
+	cfg.SSEPreviousMasterKey = loadMasterKey("SSE_PREVIOUS_MASTER_KEY")
 
 	return cfg
 }
+
+// loadMasterKey lit une clé maître SSE-S3 (32 octets encodés en base64) ; nil si elle est absente ou invalide
+func loadMasterKey(name string) []byte {
+	encoded := os.Getenv(name)
+	if encoded == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		log.Printf("%s invalide (32 octets encodés en base64 attendus), clé ignorée", name)
+		return nil
+	}
+	return key
+}
@@ -5,3 +5,5 @@ go 1.18
 require github.com/joho/godotenv v1.5.1
 
 require github.com/gorilla/mux v1.8.1
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,138 @@
+// internal/acl/acl.go
+package acl
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Permissions pouvant être accordées par une ACL
+const (
+	PermissionRead        = "READ"
+	PermissionWrite       = "WRITE"
+	PermissionReadACP     = "READ_ACP"
+	PermissionWriteACP    = "WRITE_ACP"
+	PermissionFullControl = "FULL_CONTROL"
+)
+
+// Types de bénéficiaires d'une autorisation
+const (
+	GranteeCanonicalUser = "CanonicalUser"
+	GranteeGroup         = "Group"
+)
+
+// Groupes prédéfinis S3
+const (
+	AllUsersURI           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	AuthenticatedUsersURI = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// Erreurs retournées lors de la construction ou de la validation d'une ACL
+var (
+	ErrInvalidCannedACL = errors.New("acl: ACL prédéfinie inconnue")
+	ErrMalformedACL     = errors.New("acl: ACL invalide")
+)
+
+// Owner identifie le propriétaire d'une ressource
+type Owner struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// Grantee désigne le bénéficiaire d'une autorisation : un utilisateur (ID) ou un groupe (URI)
+type Grantee struct {
+	Type        string `json:"type"`
+	ID          string `json:"id,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	URI         string `json:"uri,omitempty"`
+}
+
+// Grant accorde une permission à un bénéficiaire
+type Grant struct {
+	Grantee    Grantee `json:"grantee"`
+	Permission string  `json:"permission"`
+}
+
+// ACL est la liste de contrôle d'accès d'un bucket ou d'un objet
+type ACL struct {
+	Owner  Owner   `json:"owner"`
+	Grants []Grant `json:"grants"`
+}
+
+// Private retourne l'ACL par défaut : contrôle total pour le propriétaire uniquement
+func Private(owner Owner) ACL {
+	return ACL{
+		Owner: owner,
+		Grants: []Grant{{
+			Grantee:    Grantee{Type: GranteeCanonicalUser, ID: owner.ID, DisplayName: owner.DisplayName},
+			Permission: PermissionFullControl,
+		}},
+	}
+}
+
+// Canned construit une ACL prédéfinie (en-tête x-amz-acl)
+func Canned(name string, owner Owner) (ACL, error) {
+	a := Private(owner)
+	switch name {
+	case "", "private":
+	case "public-read":
+		a.Grants = append(a.Grants, groupGrant(AllUsersURI, PermissionRead))
+	case "public-read-write":
+		a.Grants = append(a.Grants, groupGrant(AllUsersURI, PermissionRead), groupGrant(AllUsersURI, PermissionWrite))
+	case "authenticated-read":
+		a.Grants = append(a.Grants, groupGrant(AuthenticatedUsersURI, PermissionRead))
+	default:
+		return ACL{}, fmt.Errorf("%w: %q", ErrInvalidCannedACL, name)
+	}
+	return a, nil
+}
+
+// groupGrant accorde une permission à un groupe prédéfini
+func groupGrant(uri, permission string) Grant {
+	return Grant{Grantee: Grantee{Type: GranteeGroup, URI: uri}, Permission: permission}
+}
+
+// Validate vérifie les permissions et les bénéficiaires d'une ACL
+func (a ACL) Validate() error {
+	for i, grant := range a.Grants {
+		switch grant.Permission {
+		case PermissionRead, PermissionWrite, PermissionReadACP, PermissionWriteACP, PermissionFullControl:
+		default:
+			return fmt.Errorf("%w: autorisation %d : permission %q", ErrMalformedACL, i, grant.Permission)
+		}
+		switch grant.Grantee.Type {
+		case GranteeCanonicalUser:
+			if grant.Grantee.ID == "" {
+				return fmt.Errorf("%w: autorisation %d : ID du bénéficiaire manquant", ErrMalformedACL, i)
+			}
+		case GranteeGroup:
+			if grant.Grantee.URI != AllUsersURI && grant.Grantee.URI != AuthenticatedUsersURI {
+				return fmt.Errorf("%w: autorisation %d : groupe %q", ErrMalformedACL, i, grant.Grantee.URI)
+			}
+		default:
+			return fmt.Errorf("%w: autorisation %d : type de bénéficiaire %q", ErrMalformedACL, i, grant.Grantee.Type)
+		}
+	}
+	return nil
+}
+
+// Allows indique si l'ACL accorde la permission à l'appelant (userID vide pour une requête anonyme).
+// FULL_CONTROL inclut toutes les permissions.
+func (a ACL) Allows(userID, permission string) bool {
+	for _, grant := range a.Grants {
+		if grant.Permission != permission && grant.Permission != PermissionFullControl {
+			continue
+		}
+		switch grant.Grantee.Type {
+		case GranteeCanonicalUser:
+			if userID != "" && grant.Grantee.ID == userID {
+				return true
+			}
+		case GranteeGroup:
+			if grant.Grantee.URI == AllUsersURI || (grant.Grantee.URI == AuthenticatedUsersURI && userID != "") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package acl
+
+import (
+	"errors"
+	"testing"
+)
+
+// Test des ACL prédéfinies et des permissions accordées
+func TestCannedAllows(t *testing.T) {
+	owner := Owner{ID: "alice", DisplayName: "Alice"}
+	tests := []struct {
+		canned     string
+		userID     string
+		permission string
+		want       bool
+	}{
+		{"private", "alice", PermissionWriteACP, true},
+		{"private", "bob", PermissionRead, false},
+		{"private", "", PermissionRead, false},
+		{"public-read", "", PermissionRead, true},
+		{"public-read", "", PermissionWrite, false},
+		{"public-read-write", "", PermissionWrite, true},
+		{"authenticated-read", "bob", PermissionRead, true},
+		{"authenticated-read", "", PermissionRead, false},
+	}
+	for _, tt := range tests {
+		a, err := Canned(tt.canned, owner)
+		if err != nil {
+			t.Fatalf("Canned(%q): %v", tt.canned, err)
+		}
+		if got := a.Allows(tt.userID, tt.permission); got != tt.want {
+			t.Errorf("%s: Allows(%q, %s) = %v, expected %v", tt.canned, tt.userID, tt.permission, got, tt.want)
+		}
+	}
+
+	if _, err := Canned("everyone", owner); !errors.Is(err, ErrInvalidCannedACL) {
+		t.Errorf("Expected ErrInvalidCannedACL, got %v", err)
+	}
+}
+
+// Test de la validation des ACL explicites
+func TestValidate(t *testing.T) {
+	valid := ACL{Grants: []Grant{
+		{Grantee: Grantee{Type: GranteeCanonicalUser, ID: "bob"}, Permission: PermissionRead},
+		{Grantee: Grantee{Type: GranteeGroup, URI: AllUsersURI}, Permission: PermissionRead},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid ACL, got %v", err)
+	}
+
+	invalid := []Grant{
+		{Grantee: Grantee{Type: GranteeCanonicalUser, ID: "bob"}, Permission: "EVERYTHING"},
+		{Grantee: Grantee{Type: GranteeCanonicalUser}, Permission: PermissionRead},
+		{Grantee: Grantee{Type: GranteeGroup, URI: "http://example.com/group"}, Permission: PermissionRead},
+		{Grantee: Grantee{Type: "AmazonCustomerByEmail"}, Permission: PermissionRead},
+	}
+	for _, grant := range invalid {
+		if err := (ACL{Grants: []Grant{grant}}).Validate(); !errors.Is(err, ErrMalformedACL) {
+			t.Errorf("Grant %+v: expected ErrMalformedACL, got %v", grant, err)
+		}
+	}
+}
@@ -5,88 +5,135 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"io"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"plateforme-mys3/config"
 	"sort"
 	"strings"
 	"time"
 )
 
-// VerifyAWSSignature vérifie la signature AWS SigV4 d'une requête
-func VerifyAWSSignature(r *http.Request, cfg config.Config) bool {
+// Erreurs retournées par VerifyAWSSignature, en plus de ErrInvalidAccessKeyID et ErrSignatureMismatch
+var (
+	ErrMalformedAuthorization = errors.New("auth: en-tête Authorization invalide")
+	ErrRequestTimeTooSkewed   = errors.New("auth: heure de la requête trop éloignée de celle du serveur")
+)
+
+// VerifyAWSSignature vérifie la signature AWS SigV4 d'une requête et retourne l'identité de l'appelant.
+// Si elle est valide, le corps de la requête est remplacé par un lecteur qui vérifie le contenu au fil de l'eau
+// (SHA-256 déclaré ou signatures des chunks aws-chunked).
+func VerifyAWSSignature(r *http.Request, cfg config.Config, store CredentialStore) (Identity, error) {
 	// Étape 1 : Extraire l'en-tête Authorization
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
 		log.Println("Authorization header missing")
-		return false
+		return Identity{}, ErrMalformedAuthorization
 	}
 
 	// Étape 2 : Valider le préfixe de l'en-tête
 	if !strings.HasPrefix(authHeader, "AWS4-HMAC-SHA256 ") {
 		log.Println("Authorization header does not start with AWS4-HMAC-SHA256")
-		return false
+		return Identity{}, ErrMalformedAuthorization
 	}
 
 	// Étape 3 : Parser l'en-tête Authorization
 	authParams := parseAuthorizationHeader(authHeader)
 	if authParams == nil {
 		log.Println("Failed to parse Authorization header")
-		return false
+		return Identity{}, ErrMalformedAuthorization
 	}
 
-	// Étape 4 : Vérifier l'Access Key ID
+	// Étape 4 : Vérifier l'Access Key ID (Credential=<access key>/<date>/<région>/s3/aws4_request)
 	if len(authParams["Credential"]) == 0 {
 		log.Println("Credential field missing in Authorization header")
-		return false
+		return Identity{}, ErrMalformedAuthorization
 	}
-	accessKeyID := authParams["Credential"][0]
-	if accessKeyID != cfg.AccessKeyID {
+	credential := strings.Split(authParams["Credential"][0], "/")
+	if len(credential) != 5 || credential[3] != "s3" || credential[4] != "aws4_request" {
+		log.Printf("Malformed Credential: %s", authParams["Credential"][0])
+		return Identity{}, ErrMalformedAuthorization
+	}
+	accessKeyID := credential[0]
+	secret, err := store.Lookup(accessKeyID)
+	if err != nil {
 		log.Printf("Invalid Access Key ID: %s", accessKeyID)
-		return false
+		return Identity{}, err
 	}
 
 	// Étape 5 : Récupérer les SignedHeaders
 	if len(authParams["SignedHeaders"]) == 0 {
 		log.Println("SignedHeaders field missing in Authorization header")
-		return false
+		return Identity{}, ErrMalformedAuthorization
 	}
 	signedHeaders := strings.Split(authParams["SignedHeaders"][0], ";")
 	log.Printf("Signed Headers: %v", signedHeaders)
 
 	// Étape 6 : Recalculer la signature
-	canonicalRequest, err := buildCanonicalRequest(r, signedHeaders)
+	payloadHash, err := getPayloadHash(r)
+	if err != nil {
+		log.Printf("Error reading payload hash: %v", err)
+		return Identity{}, fmt.Errorf("%w: %v", ErrMalformedAuthorization, err)
+	}
+	canonicalRequest, err := buildCanonicalRequest(r, signedHeaders, payloadHash)
 	if err != nil {
 		log.Printf("Error building canonical request: %v", err)
-		return false
+		return Identity{}, err
 	}
 	log.Printf("Canonical Request: %s", canonicalRequest)
 
-	stringToSign, err := buildStringToSign(r, canonicalRequest, cfg)
+	// La date signée (x-amz-date, à défaut Date) est obligatoire et doit être celle du scope du Credential
+	t, err := getTimestamp(r)
+	if err != nil {
+		log.Printf("Missing or invalid request date: %v", err)
+		return Identity{}, ErrMalformedAuthorization
+	}
+	if credential[1] != t.Format("20060102") {
+		log.Printf("Credential scope date %s does not match request date %s", credential[1], t.Format("20060102"))
+		return Identity{}, ErrMalformedAuthorization
+	}
+	if skew := now().UTC().Sub(t); skew > maxClockSkew || skew < -maxClockSkew {
+		log.Printf("Request time too skewed: %s", t)
+		return Identity{}, ErrRequestTimeTooSkewed
+	}
+	stringToSign, err := buildStringToSign(t, canonicalRequest, cfg)
 	if err != nil {
 		log.Printf("Error building string to sign: %v", err)
-		return false
+		return Identity{}, err
 	}
 	log.Printf("String to Sign: %s", stringToSign)
 
-	// Correction ici : Utiliser getTimestamp(r).Format("20060102") au lieu de getDate(r)
-	t := getTimestamp(r)
-	signingKey := getSignatureKey(cfg.SecretAccessKey, t.Format("20060102"), cfg.Region, "s3")
+	signingKey := getSignatureKey(secret.SecretAccessKey, t.Format("20060102"), cfg.Region, "s3")
 	expectedSignature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
 	log.Printf("Expected Signature: %s", expectedSignature)
 
 	// Étape 7 : Comparer les signatures
 	if len(authParams["Signature"]) == 0 {
 		log.Println("Signature field missing in Authorization header")
-		return false
+		return Identity{}, ErrMalformedAuthorization
 	}
 	providedSignature := authParams["Signature"][0]
 	log.Printf("Provided Signature: %s", providedSignature)
 
 	result := hmac.Equal([]byte(expectedSignature), []byte(providedSignature))
 	log.Printf("Signature Valid: %v", result)
-	return result
+	if !result {
+		return Identity{}, ErrSignatureMismatch
+	}
+
+	// Étape 8 : Préparer la vérification du corps (SHA-256 ou chunks signés à partir de la signature d'amorce)
+	if err := wrapPayload(r, &signingContext{
+		key:       signingKey,
+		timestamp: t.Format("20060102T150405Z"),
+		scope:     getCredentialScope(t, cfg),
+		seed:      providedSignature,
+	}); err != nil {
+		log.Printf("Invalid payload encoding: %v", err)
+		return Identity{}, err
+	}
+	return secret.identity(), nil
 }
 
 // parseAuthorizationHeader analyse l'en-tête Authorization et retourne un map des paramètres
@@ -111,15 +158,15 @@ func parseAuthorizationHeader(authHeader string) map[string][]string {
 }
 
 // buildCanonicalRequest construit la requête canonique selon les spécifications AWS SigV4
-func buildCanonicalRequest(r *http.Request, signedHeaders []string) (string, error) {
+func buildCanonicalRequest(r *http.Request, signedHeaders []string, payloadHash string) (string, error) {
 	// Méthode HTTP
 	method := r.Method
 
 	// URI
 	uri := getCanonicalURI(r.URL.Path)
 
-	// Query String
-	canonicalQueryString := getCanonicalQueryString(r.URL.RawQuery)
+	// Query String (X-Amz-Signature est exclue pour les URL présignées)
+	canonicalQueryString := getCanonicalQueryString(r.URL.Query(), "X-Amz-Signature")
 
 	// Headers
 	canonicalHeaders, err := getCanonicalHeaders(r, signedHeaders)
@@ -130,9 +177,6 @@ func buildCanonicalRequest(r *http.Request, signedHeaders []string) (string, err
 	// Liste des Signed Headers
 	signedHeadersStr := strings.Join(signedHeaders, ";")
 
-	// Hash du corps
-	payloadHash := getPayloadHash(r)
-
 	// Construire la requête canonique
 	canonicalRequest := strings.Join([]string{
 		method,
@@ -161,19 +205,49 @@ func getCanonicalURI(path string) string {
 
 // escapePath encode les segments du chemin selon les spécifications AWS SigV4
 func escapePath(seg string) string {
-	return strings.ReplaceAll(strings.ReplaceAll(seg, " ", "%20"), "/", "%2F")
+	return uriEncode(seg)
 }
 
-// getCanonicalQueryString formate la query string selon les spécifications AWS SigV4
-func getCanonicalQueryString(rawQuery string) string {
-	if rawQuery == "" {
-		return ""
+// getCanonicalQueryString formate la query string selon les spécifications AWS SigV4 :
+// clés et valeurs encodées, triées par clé puis par valeur, en ignorant les paramètres exclus
+func getCanonicalQueryString(query url.Values, excluded ...string) string {
+	var params []string
+	for key, values := range query {
+		if containsString(excluded, key) {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, uriEncode(key)+"="+uriEncode(value))
+		}
 	}
-	params := strings.Split(rawQuery, "&")
 	sort.Strings(params)
 	return strings.Join(params, "&")
 }
 
+// uriEncode encode une chaîne comme le fait AWS : tout sauf A-Z, a-z, 0-9, '-', '_', '.' et '~'
+func uriEncode(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// containsString indique si une liste contient une valeur
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
 // getCanonicalHeaders formate les en-têtes selon les spécifications AWS SigV4
 func getCanonicalHeaders(r *http.Request, signedHeaders []string) (string, error) {
 	var headers []string
@@ -181,6 +255,10 @@ func getCanonicalHeaders(r *http.Request, signedHeaders []string) (string, error
 		// Les en-têtes doivent être en minuscules
 		headerLower := strings.ToLower(header)
 		values := r.Header[http.CanonicalHeaderKey(headerLower)]
+		if headerLower == "host" {
+			// net/http retire l'en-tête Host de r.Header pour le placer dans r.Host
+			values = []string{r.Host}
+		}
 		if len(values) == 0 {
 			log.Printf("Signed header %s not present in request", headerLower)
 			continue // Selon les spécifications, ignorer les en-têtes manquants
@@ -195,19 +273,16 @@ func getCanonicalHeaders(r *http.Request, signedHeaders []string) (string, error
 	return strings.Join(headers, ""), nil
 }
 
-// getPayloadHash retourne le hash SHA256 du corps de la requête
-func getPayloadHash(r *http.Request) string {
-	if r.Body == nil {
-		return sha256Hex("")
+// getPayloadHash retourne le hash du corps déclaré par le client dans x-amz-content-sha256.
+// Le corps n'est pas lu ici : il est vérifié au fil de l'eau par WrapPayload.
+func getPayloadHash(r *http.Request) (string, error) {
+	if hash := r.Header.Get("x-amz-content-sha256"); hash != "" {
+		return hash, nil
 	}
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Printf("Error reading request body: %v", err)
-		return sha256Hex("")
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return sha256Hex(""), nil
 	}
-	// Remettre le corps dans le lecteur pour une utilisation ultérieure
-	r.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
-	return sha256Hex(string(bodyBytes))
+	return "", errors.New("en-tête x-amz-content-sha256 manquant pour une requête avec corps")
 }
 
 // sha256Hex retourne le hash SHA256 hexadécimal d'une chaîne donnée
@@ -217,12 +292,11 @@ func sha256Hex(data string) string {
 }
 
 // buildStringToSign construit la chaîne à signer selon les spécifications AWS SigV4
-func buildStringToSign(r *http.Request, canonicalRequest string, cfg config.Config) (string, error) {
+func buildStringToSign(t time.Time, canonicalRequest string, cfg config.Config) (string, error) {
 	// Algorithme
 	algorithm := "AWS4-HMAC-SHA256"
 
 	// Horodatage (format ISO8601)
-	t := getTimestamp(r)
 	credentialScope := getCredentialScope(t, cfg)
 
 	// Hash de la requête canonique
@@ -239,18 +313,17 @@ func buildStringToSign(r *http.Request, canonicalRequest string, cfg config.Conf
 	return stringToSign, nil
 }
 
-// getTimestamp extrait le timestamp de la requête ou utilise l'heure actuelle
-func getTimestamp(r *http.Request) time.Time {
-	// Chercher l'en-tête x-amz-date
-	dateHeader := r.Header.Get("x-amz-date")
-	if dateHeader != "" {
-		t, err := time.Parse("20060102T150405Z", dateHeader)
-		if err == nil {
-			return t
-		}
+// getTimestamp extrait la date de la requête : x-amz-date (format ISO8601) ou, à défaut, l'en-tête Date.
+// Sans l'une ou l'autre, la requête ne peut pas être signée : l'heure du serveur ne la remplace jamais.
+func getTimestamp(r *http.Request) (time.Time, error) {
+	if dateHeader := r.Header.Get("x-amz-date"); dateHeader != "" {
+		return time.Parse("20060102T150405Z", dateHeader)
+	}
+	if dateHeader := r.Header.Get("Date"); dateHeader != "" {
+		t, err := http.ParseTime(dateHeader)
+		return t.UTC(), err
 	}
-	// Sinon, utiliser l'heure actuelle
-	return time.Now().UTC()
+	return time.Time{}, errors.New("auth: en-têtes x-amz-date et Date absents")
 }
 
 // getCredentialScope construit le scope de credential
@@ -0,0 +1,286 @@
+// internal/auth/chunked.go
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Erreurs levées pendant le décodage d'un corps aws-chunked
+var (
+	ErrMalformedChunk         = errors.New("auth: encodage aws-chunked invalide")
+	ErrChunkSignatureMismatch = errors.New("auth: signature de chunk invalide")
+	ErrIncompleteBody         = errors.New("auth: taille décodée différente de x-amz-decoded-content-length")
+)
+
+// maxChunkLineSize borne la taille d'une ligne d'en-tête de chunk ou de trailer
+const maxChunkLineSize = 4096
+
+// chunkedReader décode un corps aws-chunked :
+//
+//	<taille hexa>[;chunk-signature=<signature>]\r\n<données>\r\n ... 0[;chunk-signature=<signature>]\r\n[trailers]\r\n
+//
+// Chaque signature de chunk est chaînée à la précédente, la première étant la signature de la requête.
+// Sans contexte de signature (STREAMING-UNSIGNED-PAYLOAD-TRAILER), les chunks ne sont pas signés.
+type chunkedReader struct {
+	body          io.Closer
+	r             *bufio.Reader
+	signing       *signingContext
+	prevSignature string
+	hasTrailer    bool
+	trailer       http.Header
+
+	decodedLength int64 // -1 si inconnue
+	decoded       int64
+
+	remaining      int64     // octets restants dans le chunk courant
+	chunkSignature string    // signature annoncée du chunk courant
+	chunkHash      hash.Hash // SHA-256 des données du chunk courant
+	inChunk        bool
+	err            error
+}
+
+// newChunkedReader construit un lecteur aws-chunked ; les trailers décodés sont ajoutés à trailer
+func newChunkedReader(body io.ReadCloser, signing *signingContext, decodedLength int64, hasTrailer bool, trailer http.Header) *chunkedReader {
+	c := &chunkedReader{
+		body:          body,
+		r:             bufio.NewReaderSize(body, 64*1024),
+		signing:       signing,
+		hasTrailer:    hasTrailer,
+		trailer:       trailer,
+		decodedLength: decodedLength,
+		chunkHash:     sha256.New(),
+	}
+	if signing != nil {
+		c.prevSignature = signing.seed
+	}
+	return c
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	for c.remaining == 0 {
+		if c.inChunk {
+			// Fin des données du chunk courant : CRLF puis vérification de sa signature
+			if err := c.expectCRLF(); err != nil {
+				return 0, c.fail(err)
+			}
+			if err := c.verifyChunk(); err != nil {
+				return 0, c.fail(err)
+			}
+			c.inChunk = false
+		}
+
+		size, err := c.readChunkHeader()
+		if err != nil {
+			return 0, c.fail(err)
+		}
+		if size == 0 {
+			return 0, c.fail(c.finish())
+		}
+		c.remaining = size
+		c.inChunk = true
+		c.chunkHash.Reset()
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.chunkHash.Write(p[:n])
+	c.remaining -= int64(n)
+	c.decoded += int64(n)
+	if c.decodedLength >= 0 && c.decoded > c.decodedLength {
+		return n, c.fail(ErrIncompleteBody)
+	}
+	if err == io.EOF {
+		return n, c.fail(ErrMalformedChunk)
+	}
+	if err != nil {
+		return n, c.fail(err)
+	}
+	return n, nil
+}
+
+func (c *chunkedReader) Close() error {
+	return c.body.Close()
+}
+
+// fail mémorise l'erreur (ou io.EOF) pour les lectures suivantes
+func (c *chunkedReader) fail(err error) error {
+	c.err = err
+	return err
+}
+
+// readChunkHeader lit la ligne "<taille>[;chunk-signature=<sig>]" et retourne la taille du chunk
+func (c *chunkedReader) readChunkHeader() (int64, error) {
+	line, err := c.readLine()
+	if err == io.EOF {
+		// Le corps s'arrête sans chunk final : il a été tronqué
+		return 0, ErrMalformedChunk
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	sizeField, extensions, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+	if err != nil || size < 0 {
+		return 0, ErrMalformedChunk
+	}
+
+	c.chunkSignature = ""
+	for _, extension := range strings.Split(extensions, ";") {
+		if name, value, ok := strings.Cut(extension, "="); ok && strings.TrimSpace(name) == "chunk-signature" {
+			c.chunkSignature = strings.TrimSpace(value)
+		}
+	}
+	if c.signing != nil && c.chunkSignature == "" {
+		return 0, ErrMalformedChunk
+	}
+	return size, nil
+}
+
+// verifyChunk contrôle la signature du chunk qui vient d'être lu
+func (c *chunkedReader) verifyChunk() error {
+	if c.signing == nil {
+		return nil
+	}
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256-PAYLOAD",
+		c.signing.timestamp,
+		c.signing.scope,
+		c.prevSignature,
+		sha256Hex(""),
+		hex.EncodeToString(c.chunkHash.Sum(nil)),
+	}, "\n")
+	expected := hex.EncodeToString(hmacSHA256(c.signing.key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(c.chunkSignature)) {
+		return ErrChunkSignatureMismatch
+	}
+	c.prevSignature = expected
+	return nil
+}
+
+// finish traite le chunk final (taille 0), les éventuels trailers, et contrôle la taille décodée
+func (c *chunkedReader) finish() error {
+	c.chunkHash.Reset()
+	if err := c.verifyChunk(); err != nil {
+		return err
+	}
+
+	if c.hasTrailer {
+		if err := c.readTrailers(); err != nil {
+			return err
+		}
+	} else if err := c.expectCRLF(); err != nil {
+		return err
+	}
+
+	if c.decodedLength >= 0 && c.decoded != c.decodedLength {
+		return ErrIncompleteBody
+	}
+	return io.EOF
+}
+
+// readTrailers lit les en-têtes de fin ("nom:valeur") jusqu'à la ligne vide et vérifie x-amz-trailer-signature
+func (c *chunkedReader) readTrailers() error {
+	var names []string
+	values := make(map[string]string)
+	trailerSignature := ""
+	for {
+		line, err := c.readLine()
+		if err == io.EOF && len(values) > 0 {
+			// Certains clients omettent la ligne vide finale
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return ErrMalformedChunk
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if name == "x-amz-trailer-signature" {
+			trailerSignature = value
+			continue
+		}
+		names = append(names, name)
+		values[name] = value
+	}
+
+	if c.signing != nil {
+		sort.Strings(names)
+		var canonical bytes.Buffer
+		for _, name := range names {
+			canonical.WriteString(name + ":" + values[name] + "\n")
+		}
+		stringToSign := strings.Join([]string{
+			"AWS4-HMAC-SHA256-TRAILER",
+			c.signing.timestamp,
+			c.signing.scope,
+			c.prevSignature,
+			sha256Hex(canonical.String()),
+		}, "\n")
+		expected := hex.EncodeToString(hmacSHA256(c.signing.key, stringToSign))
+		if !hmac.Equal([]byte(expected), []byte(trailerSignature)) {
+			return ErrChunkSignatureMismatch
+		}
+	}
+
+	for _, name := range names {
+		c.trailer.Set(name, values[name])
+	}
+	return nil
+}
+
+// readLine lit une ligne terminée par CRLF (sans le CRLF)
+func (c *chunkedReader) readLine() (string, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull || len(line) > maxChunkLineSize {
+		return "", ErrMalformedChunk
+	}
+	if err == io.EOF && len(line) == 0 {
+		return "", io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return "", ErrMalformedChunk
+	}
+	return string(line[:len(line)-2]), nil
+}
+
+// expectCRLF consomme le CRLF qui suit les données d'un chunk
+func (c *chunkedReader) expectCRLF() error {
+	line, err := c.readLine()
+	if err != nil {
+		if err == io.EOF {
+			return ErrMalformedChunk
+		}
+		return err
+	}
+	if line != "" {
+		return ErrMalformedChunk
+	}
+	return nil
+}
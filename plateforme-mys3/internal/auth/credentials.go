@@ -0,0 +1,209 @@
+// internal/auth/credentials.go
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"plateforme-mys3/config"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidAccessKeyID est retournée par un CredentialStore pour une access key inconnue
+var ErrInvalidAccessKeyID = errors.New("auth: access key inconnue")
+
+// Credential associe une access key à son secret et à l'utilisateur qui la possède
+type Credential struct {
+	AccessKeyID     string `json:"accessKeyId" yaml:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey" yaml:"secretAccessKey"`
+	UserID          string `json:"userId" yaml:"userId"`
+	DisplayName     string `json:"displayName" yaml:"displayName"`
+}
+
+// Identity décrit l'appelant authentifié d'une requête
+type Identity struct {
+	AccessKeyID string
+	UserID      string
+	DisplayName string
+}
+
+// identity retourne l'identité portée par une credential
+func (c Credential) identity() Identity {
+	return Identity{
+		AccessKeyID: c.AccessKeyID,
+		UserID:      c.UserID,
+		DisplayName: c.DisplayName,
+	}
+}
+
+// CredentialStore retrouve le secret et le propriétaire d'une access key
+type CredentialStore interface {
+	Lookup(accessKeyID string) (Credential, error)
+}
+
+// NewCredentialStore construit le store décrit par la configuration : le fichier CREDENTIALS_FILE s'il est
+// renseigné, sinon un store en mémoire contenant uniquement le couple ACCESS_KEY_ID / SECRET_ACCESS_KEY
+func NewCredentialStore(cfg config.Config) (CredentialStore, error) {
+	if cfg.CredentialsFile != "" {
+		return NewFileCredentialStore(cfg.CredentialsFile)
+	}
+	return NewMemoryCredentialStore(Credential{
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+		UserID:          cfg.AccessKeyID,
+		DisplayName:     "owner",
+	}), nil
+}
+
+// MemoryCredentialStore est un store de credentials en mémoire, utilisé par défaut et dans les tests
+type MemoryCredentialStore struct {
+	mu          sync.RWMutex
+	credentials map[string]Credential
+}
+
+// NewMemoryCredentialStore initialise un store en mémoire avec les credentials fournies
+func NewMemoryCredentialStore(credentials ...Credential) *MemoryCredentialStore {
+	m := &MemoryCredentialStore{credentials: make(map[string]Credential)}
+	for _, c := range credentials {
+		m.Add(c)
+	}
+	return m
+}
+
+// Add ajoute ou remplace une credential
+func (m *MemoryCredentialStore) Add(c Credential) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.credentials[c.AccessKeyID] = c
+}
+
+// Lookup retrouve une credential par son access key
+func (m *MemoryCredentialStore) Lookup(accessKeyID string) (Credential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.credentials[accessKeyID]
+	if !ok {
+		return Credential{}, ErrInvalidAccessKeyID
+	}
+	return c, nil
+}
+
+// credentialFile est le format du fichier de credentials (JSON ou YAML selon l'extension)
+type credentialFile struct {
+	Credentials []Credential `json:"credentials" yaml:"credentials"`
+}
+
+// FileCredentialStore lit les credentials depuis un fichier JSON ou YAML.
+// Le fichier est relu lorsqu'il est modifié, ce qui permet d'ajouter des utilisateurs sans redémarrer.
+type FileCredentialStore struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	store   *MemoryCredentialStore
+}
+
+// NewFileCredentialStore charge le fichier de credentials indiqué
+func NewFileCredentialStore(path string) (*FileCredentialStore, error) {
+	f := &FileCredentialStore{path: path}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Lookup retrouve une credential, en rechargeant le fichier s'il a changé
+func (f *FileCredentialStore) Lookup(accessKeyID string) (Credential, error) {
+	if err := f.reload(); err != nil {
+		// On continue avec la dernière version valide du fichier
+		log.Printf("Erreur lors du rechargement de %s : %v", f.path, err)
+	}
+	f.mu.Lock()
+	store := f.store
+	f.mu.Unlock()
+	return store.Lookup(accessKeyID)
+}
+
+// reload relit le fichier si sa date de modification a changé
+func (f *FileCredentialStore) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.store != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	var file credentialFile
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return fmt.Errorf("fichier de credentials %s invalide : %w", f.path, err)
+	}
+
+	store := NewMemoryCredentialStore()
+	for _, c := range file.Credentials {
+		if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+			return fmt.Errorf("fichier de credentials %s : accessKeyId et secretAccessKey sont obligatoires", f.path)
+		}
+		if c.UserID == "" {
+			c.UserID = c.AccessKeyID
+		}
+		store.Add(c)
+	}
+	f.store = store
+	f.modTime = info.ModTime()
+	return nil
+}
+
+// identityKey est la clé de contexte de l'identité authentifiée
+type identityKey struct{}
+
+// WithIdentity attache l'identité authentifiée au contexte de la requête
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext retourne l'identité authentifiée attachée au contexte, si elle existe
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// Authorizer décide si l'appelant d'une requête peut effectuer une action S3 sur un objet.
+// Il sert aux requêtes qui portent sur plusieurs objets (suppression multiple), autorisés un par un.
+type Authorizer func(bucketName, objectName, action string) bool
+
+// authorizerKey est la clé de contexte de l'Authorizer de la requête
+type authorizerKey struct{}
+
+// WithAuthorizer attache l'Authorizer de l'appelant au contexte de la requête
+func WithAuthorizer(ctx context.Context, authorizer Authorizer) context.Context {
+	return context.WithValue(ctx, authorizerKey{}, authorizer)
+}
+
+// Authorize applique l'Authorizer attaché au contexte. Sans Authorizer (routeur utilisé sans le middleware
+// d'authentification), l'action est refusée.
+func Authorize(ctx context.Context, bucketName, objectName, action string) bool {
+	authorizer, ok := ctx.Value(authorizerKey{}).(Authorizer)
+	return ok && authorizer(bucketName, objectName, action)
+}
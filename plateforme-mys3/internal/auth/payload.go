@@ -0,0 +1,103 @@
+// internal/auth/payload.go
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Valeurs spéciales de x-amz-content-sha256
+const (
+	UnsignedPayload                 = "UNSIGNED-PAYLOAD"
+	StreamingPayload                = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	StreamingPayloadTrailer         = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	StreamingUnsignedPayloadTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+)
+
+// ErrContentSHA256Mismatch est retournée à la fin du corps si son SHA-256 ne correspond pas à x-amz-content-sha256
+var ErrContentSHA256Mismatch = errors.New("auth: le SHA-256 du corps ne correspond pas à x-amz-content-sha256")
+
+// signingContext regroupe les éléments d'une signature validée, nécessaires pour vérifier les chunks du corps
+type signingContext struct {
+	key       []byte // clé de signature dérivée
+	timestamp string // x-amz-date
+	scope     string // date/région/s3/aws4_request
+	seed      string // signature de la requête, qui amorce la chaîne des signatures de chunks
+}
+
+// wrapPayload remplace le corps de la requête par un lecteur adapté à x-amz-content-sha256.
+// Le corps n'est jamais chargé en mémoire : les erreurs de vérification sont levées pendant la lecture,
+// ce qui permet au stockage d'abandonner l'écriture avant de valider l'objet.
+func wrapPayload(r *http.Request, signing *signingContext) error {
+	contentSHA256, err := getPayloadHash(r)
+	if err != nil {
+		return err
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	switch contentSHA256 {
+	case UnsignedPayload:
+		return nil
+	case StreamingPayload, StreamingPayloadTrailer, StreamingUnsignedPayloadTrailer:
+		decodedLength := int64(-1)
+		if value := r.Header.Get("x-amz-decoded-content-length"); value != "" {
+			decodedLength, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || decodedLength < 0 {
+				return fmt.Errorf("x-amz-decoded-content-length invalide: %q", value)
+			}
+		}
+		if contentSHA256 == StreamingUnsignedPayloadTrailer {
+			signing = nil
+		} else if signing == nil {
+			return errors.New("corps aws-chunked signé sans signature d'amorce")
+		}
+		hasTrailer := contentSHA256 != StreamingPayload
+		if hasTrailer && r.Trailer == nil {
+			r.Trailer = http.Header{}
+		}
+		r.Body = newChunkedReader(r.Body, signing, decodedLength, hasTrailer, r.Trailer)
+		if decodedLength >= 0 {
+			r.ContentLength = decodedLength
+		}
+		return nil
+	}
+
+	if _, err := hex.DecodeString(contentSHA256); err != nil || len(contentSHA256) != sha256.Size*2 {
+		return fmt.Errorf("x-amz-content-sha256 invalide: %q", contentSHA256)
+	}
+	r.Body = &sha256Reader{
+		body:     r.Body,
+		hash:     sha256.New(),
+		expected: strings.ToLower(contentSHA256),
+	}
+	return nil
+}
+
+// sha256Reader calcule le SHA-256 des données lues et le compare à la valeur attendue en fin de flux
+type sha256Reader struct {
+	body     io.ReadCloser
+	hash     hash.Hash
+	expected string
+}
+
+func (v *sha256Reader) Read(p []byte) (int, error) {
+	n, err := v.body.Read(p)
+	v.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(v.hash.Sum(nil)) != v.expected {
+		return n, ErrContentSHA256Mismatch
+	}
+	return n, err
+}
+
+func (v *sha256Reader) Close() error {
+	return v.body.Close()
+}
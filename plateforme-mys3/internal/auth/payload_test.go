@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test de la vérification en flux de x-amz-content-sha256
+func TestWrapPayload(t *testing.T) {
+	body := "contenu de l'objet"
+	sum := sha256.Sum256([]byte(body))
+
+	req := httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader(body))
+	req.Header.Set("x-amz-content-sha256", hex.EncodeToString(sum[:]))
+	if err := wrapPayload(req, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil || string(data) != body {
+		t.Errorf("Expected body %q without error, got %q (%v)", body, data, err)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader("contenu modifié"))
+	req.Header.Set("x-amz-content-sha256", hex.EncodeToString(sum[:]))
+	if err := wrapPayload(req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(req.Body); !errors.Is(err, ErrContentSHA256Mismatch) {
+		t.Errorf("Expected ErrContentSHA256Mismatch, got %v", err)
+	}
+}
+
+// testSigningContext retourne un contexte de signature déterministe pour les tests
+func testSigningContext() *signingContext {
+	return &signingContext{
+		key:       getSignatureKey("secret", "20240101", "us-east-1", "s3"),
+		timestamp: "20240101T000000Z",
+		scope:     "20240101/us-east-1/s3/aws4_request",
+		seed:      "seedsignature",
+	}
+}
+
+// encodeChunked produit un corps aws-chunked signé comme le ferait un SDK AWS
+func encodeChunked(signing *signingContext, chunks []string, trailer string) string {
+	var b strings.Builder
+	prev := signing.seed
+	sign := func(data string) string {
+		stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256-PAYLOAD", signing.timestamp, signing.scope, prev, sha256Hex(""), sha256Hex(data)}, "\n")
+		prev = hex.EncodeToString(hmacSHA256(signing.key, stringToSign))
+		return prev
+	}
+	for _, chunk := range append(chunks, "") {
+		fmt.Fprintf(&b, "%x;chunk-signature=%s\r\n%s", len(chunk), sign(chunk), chunk)
+		if chunk != "" {
+			b.WriteString("\r\n")
+		}
+	}
+	if trailer == "" {
+		b.WriteString("\r\n")
+		return b.String()
+	}
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256-TRAILER", signing.timestamp, signing.scope, prev, sha256Hex(trailer + "\n")}, "\n")
+	fmt.Fprintf(&b, "%s\r\nx-amz-trailer-signature:%s\r\n\r\n", trailer, hex.EncodeToString(hmacSHA256(signing.key, stringToSign)))
+	return b.String()
+}
+
+// Test du décodage d'un corps STREAMING-AWS4-HMAC-SHA256-PAYLOAD
+func TestChunkedPayload(t *testing.T) {
+	signing := testSigningContext()
+	body := encodeChunked(signing, []string{"hello ", "world"}, "")
+
+	req := httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader(body))
+	req.Header.Set("x-amz-content-sha256", StreamingPayload)
+	req.Header.Set("x-amz-decoded-content-length", "11")
+	if err := wrapPayload(req, signing); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil || string(data) != "hello world" {
+		t.Errorf("Expected decoded body %q, got %q (%v)", "hello world", data, err)
+	}
+
+	// Un chunk modifié invalide la chaîne de signatures
+	tampered := strings.Replace(body, "world", "WORLD", 1)
+	req = httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader(tampered))
+	req.Header.Set("x-amz-content-sha256", StreamingPayload)
+	if err := wrapPayload(req, signing); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(req.Body); !errors.Is(err, ErrChunkSignatureMismatch) {
+		t.Errorf("Expected ErrChunkSignatureMismatch, got %v", err)
+	}
+
+	// Un corps tronqué avant le chunk final est rejeté
+	req = httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader(body[:strings.Index(body, "world")+7]))
+	req.Header.Set("x-amz-content-sha256", StreamingPayload)
+	if err := wrapPayload(req, signing); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(req.Body); !errors.Is(err, ErrMalformedChunk) {
+		t.Errorf("Expected ErrMalformedChunk, got %v", err)
+	}
+}
+
+// Test du décodage des variantes avec trailer de checksum
+func TestChunkedPayloadTrailer(t *testing.T) {
+	signing := testSigningContext()
+	body := encodeChunked(signing, []string{"hello"}, "x-amz-checksum-crc32:NhCmhg==")
+
+	req := httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader(body))
+	req.Header.Set("x-amz-content-sha256", StreamingPayloadTrailer)
+	req.Header.Set("x-amz-trailer", "x-amz-checksum-crc32")
+	if err := wrapPayload(req, signing); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("Expected decoded body %q, got %q (%v)", "hello", data, err)
+	}
+	if got := req.Trailer.Get("x-amz-checksum-crc32"); got != "NhCmhg==" {
+		t.Errorf("Expected trailer checksum %q, got %q", "NhCmhg==", got)
+	}
+
+	// Variante non signée
+	unsigned := "5\r\nhello\r\n0\r\nx-amz-checksum-crc32:NhCmhg==\r\n\r\n"
+	req = httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader(unsigned))
+	req.Header.Set("x-amz-content-sha256", StreamingUnsignedPayloadTrailer)
+	if err := wrapPayload(req, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err = io.ReadAll(req.Body)
+	if err != nil || string(data) != "hello" || req.Trailer.Get("x-amz-checksum-crc32") != "NhCmhg==" {
+		t.Errorf("Unexpected unsigned trailer decoding: %q (%v), trailer %v", data, err, req.Trailer)
+	}
+}
@@ -0,0 +1,121 @@
+// internal/auth/presigned.go
+package auth
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"plateforme-mys3/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPresignedExpires est la durée de validité maximale d'une URL présignée (7 jours)
+	maxPresignedExpires = 7 * 24 * 60 * 60
+	// maxClockSkew est l'avance tolérée sur X-Amz-Date
+	maxClockSkew = 15 * time.Minute
+)
+
+// Erreurs retournées par VerifyPresignedURL
+var (
+	ErrMalformedPresignedURL = errors.New("auth: paramètres d'URL présignée invalides")
+	ErrRequestExpired        = errors.New("auth: URL présignée expirée")
+	ErrRequestNotYetValid    = errors.New("auth: URL présignée pas encore valide")
+	ErrSignatureMismatch     = errors.New("auth: signature invalide")
+)
+
+// now est l'horloge utilisée pour l'expiration (remplaçable dans les tests)
+var now = time.Now
+
+// IsPresignedRequest indique si la requête porte une signature SigV4 dans sa query string
+func IsPresignedRequest(r *http.Request) bool {
+	return r.URL.Query().Get("X-Amz-Algorithm") != ""
+}
+
+// VerifyPresignedURL vérifie une URL présignée (X-Amz-Algorithm, X-Amz-Credential, X-Amz-Date,
+// X-Amz-Expires, X-Amz-SignedHeaders, X-Amz-Signature) et son expiration, et retourne l'identité de l'appelant
+func VerifyPresignedURL(r *http.Request, cfg config.Config, store CredentialStore) (Identity, error) {
+	query := r.URL.Query()
+
+	// Étape 1 : Algorithme
+	if query.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" {
+		log.Printf("Unsupported presigned algorithm: %s", query.Get("X-Amz-Algorithm"))
+		return Identity{}, ErrMalformedPresignedURL
+	}
+
+	// Étape 2 : Credential (<access key>/<date>/<région>/s3/aws4_request)
+	credential := strings.Split(query.Get("X-Amz-Credential"), "/")
+	if len(credential) != 5 || credential[3] != "s3" || credential[4] != "aws4_request" {
+		log.Printf("Malformed X-Amz-Credential: %s", query.Get("X-Amz-Credential"))
+		return Identity{}, ErrMalformedPresignedURL
+	}
+	secret, err := store.Lookup(credential[0])
+	if err != nil {
+		log.Printf("Invalid Access Key ID: %s", credential[0])
+		return Identity{}, err
+	}
+
+	// Étape 3 : Date et durée de validité
+	t, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil || credential[1] != t.Format("20060102") || credential[2] != cfg.Region {
+		log.Printf("Invalid X-Amz-Date or credential scope: %s / %s", query.Get("X-Amz-Date"), query.Get("X-Amz-Credential"))
+		return Identity{}, ErrMalformedPresignedURL
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 1 || expires > maxPresignedExpires {
+		log.Printf("Invalid X-Amz-Expires: %s", query.Get("X-Amz-Expires"))
+		return Identity{}, ErrMalformedPresignedURL
+	}
+	current := now().UTC()
+	if t.After(current.Add(maxClockSkew)) {
+		return Identity{}, ErrRequestNotYetValid
+	}
+	if current.After(t.Add(time.Duration(expires) * time.Second)) {
+		log.Printf("Presigned URL expired at %s", t.Add(time.Duration(expires)*time.Second))
+		return Identity{}, ErrRequestExpired
+	}
+
+	// Étape 4 : Recalculer la signature. Le corps n'est pas signé, sauf si le client a fourni x-amz-content-sha256.
+	signedHeaders := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	if !containsString(signedHeaders, "host") {
+		log.Printf("X-Amz-SignedHeaders must include host: %s", query.Get("X-Amz-SignedHeaders"))
+		return Identity{}, ErrMalformedPresignedURL
+	}
+	payloadHash := UnsignedPayload
+	if hash := r.Header.Get("x-amz-content-sha256"); hash != "" {
+		payloadHash = hash
+	}
+	canonicalRequest, err := buildCanonicalRequest(r, signedHeaders, payloadHash)
+	if err != nil {
+		return Identity{}, err
+	}
+	stringToSign, err := buildStringToSign(t, canonicalRequest, cfg)
+	if err != nil {
+		return Identity{}, err
+	}
+	signingKey := getSignatureKey(secret.SecretAccessKey, t.Format("20060102"), cfg.Region, "s3")
+	expectedSignature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	// Étape 5 : Comparer les signatures
+	if !hmac.Equal([]byte(expectedSignature), []byte(query.Get("X-Amz-Signature"))) {
+		log.Printf("Presigned signature mismatch for %s %s", r.Method, r.URL.Path)
+		return Identity{}, ErrSignatureMismatch
+	}
+
+	// Étape 6 : Vérifier le corps au fil de l'eau si son hash a été déclaré
+	if payloadHash != UnsignedPayload {
+		if err := wrapPayload(r, &signingContext{
+			key:       signingKey,
+			timestamp: t.Format("20060102T150405Z"),
+			scope:     getCredentialScope(t, cfg),
+			seed:      query.Get("X-Amz-Signature"),
+		}); err != nil {
+			return Identity{}, err
+		}
+	}
+	return secret.identity(), nil
+}
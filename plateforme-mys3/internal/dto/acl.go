@@ -0,0 +1,44 @@
+// internal/dto/acl.go
+package dto
+
+import "encoding/xml"
+
+type AccessControlPolicy struct {
+	XMLName           xml.Name          `xml:"AccessControlPolicy"`
+	XMLNS             string            `xml:"xmlns,attr,omitempty"`
+	Owner             Owner             `xml:"Owner"`
+	AccessControlList AccessControlList `xml:"AccessControlList"`
+}
+
+type AccessControlList struct {
+	Grants []Grant `xml:"Grant"`
+}
+
+type Grant struct {
+	Grantee    Grantee `xml:"Grantee"`
+	Permission string  `xml:"Permission"`
+}
+
+type Grantee struct {
+	XMLNSXSI    string `xml:"xmlns:xsi,attr,omitempty"`
+	Type        string `xml:"xsi:type,attr"`
+	ID          string `xml:"ID,omitempty"`
+	DisplayName string `xml:"DisplayName,omitempty"`
+	URI         string `xml:"URI,omitempty"`
+}
+
+// UnmarshalXML lit l'attribut xsi:type, que encoding/xml résout avec son espace de noms
+func (g *Grantee) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type grantee Grantee
+	var v grantee
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "type" {
+			v.Type = attr.Value
+		}
+	}
+	*g = Grantee(v)
+	return nil
+}
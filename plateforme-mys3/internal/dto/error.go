@@ -0,0 +1,12 @@
+// internal/dto/error.go
+package dto
+
+import "encoding/xml"
+
+type Error struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource,omitempty"`
+	RequestId string   `xml:"RequestId"`
+}
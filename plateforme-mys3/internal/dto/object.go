@@ -4,14 +4,35 @@ package dto
 import "encoding/xml"
 
 type ListBucketResult struct {
-	XMLName     xml.Name `xml:"ListBucketResult"`
-	XMLNS       string   `xml:"xmlns,attr"`
-	Name        string   `xml:"Name"`
-	Prefix      string   `xml:"Prefix"`
-	Marker      string   `xml:"Marker"`
-	MaxKeys     int      `xml:"MaxKeys"`
-	IsTruncated bool     `xml:"IsTruncated"`
-	Contents    []Object `xml:"Contents"`
+	XMLName        xml.Name       `xml:"ListBucketResult"`
+	XMLNS          string         `xml:"xmlns,attr"`
+	Name           string         `xml:"Name"`
+	Prefix         string         `xml:"Prefix"`
+	Marker         string         `xml:"Marker"`
+	NextMarker     string         `xml:"NextMarker,omitempty"`
+	Delimiter      string         `xml:"Delimiter,omitempty"`
+	MaxKeys        int            `xml:"MaxKeys"`
+	EncodingType   string         `xml:"EncodingType,omitempty"`
+	IsTruncated    bool           `xml:"IsTruncated"`
+	Contents       []Object       `xml:"Contents"`
+	CommonPrefixes []CommonPrefix `xml:"CommonPrefixes"`
+}
+
+type ListBucketResultV2 struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	XMLNS                 string         `xml:"xmlns,attr"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	KeyCount              int            `xml:"KeyCount"`
+	EncodingType          string         `xml:"EncodingType,omitempty"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	Contents              []Object       `xml:"Contents"`
+	CommonPrefixes        []CommonPrefix `xml:"CommonPrefixes"`
 }
 
 type Object struct {
@@ -19,5 +40,10 @@ type Object struct {
 	LastModified string `xml:"LastModified"`
 	ETag         string `xml:"ETag"`
 	Size         int64  `xml:"Size"`
+	Owner        *Owner `xml:"Owner,omitempty"`
 	StorageClass string `xml:"StorageClass"`
 }
+
+type CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
@@ -0,0 +1,163 @@
+// internal/handlers/acl.go
+package handlers
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"plateforme-mys3/internal/acl"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// maxACLSize est la taille maximale d'un document AccessControlPolicy accepté
+const maxACLSize = 64 * 1024
+
+// BucketACLHandler gère l'ACL d'un bucket (?acl : GET, PUT)
+func BucketACLHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucket"]
+
+		current, err := s.GetBucketACL(bucketName)
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeACL(w, current)
+		case http.MethodPut:
+			updated, err := aclFromRequest(r, current.Owner)
+			if err != nil {
+				log.Printf("ACL refusée pour le bucket %s: %v", bucketName, err)
+				s3err.WriteError(w, r, err)
+				return
+			}
+			if err := s.PutBucketACL(bucketName, updated); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			s3err.Write(w, r, s3err.ErrMethodNotAllowed)
+		}
+	}
+}
+
+// ObjectACLHandler gère l'ACL d'un objet (?acl : GET, PUT)
+func ObjectACLHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		bucketName := vars["bucket"]
+		objectName := vars["object"]
+
+		if _, err := s.GetObjectMetadata(bucketName, objectName); err != nil {
+			writeObjectError(w, r, s, bucketName, err)
+			return
+		}
+		current, err := s.GetObjectACL(bucketName, objectName)
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeACL(w, current)
+		case http.MethodPut:
+			updated, err := aclFromRequest(r, current.Owner)
+			if err != nil {
+				log.Printf("ACL refusée pour l'objet %s/%s: %v", bucketName, objectName, err)
+				s3err.WriteError(w, r, err)
+				return
+			}
+			if err := s.PutObjectACL(bucketName, objectName, updated); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			s3err.Write(w, r, s3err.ErrMethodNotAllowed)
+		}
+	}
+}
+
+// requestACLOwner retourne le propriétaire des ressources créées par l'appelant
+func requestACLOwner(r *http.Request) acl.Owner {
+	owner := requestOwner(r)
+	return acl.Owner{ID: owner.ID, DisplayName: owner.DisplayName}
+}
+
+// objectOwner retourne le propriétaire d'un objet écrit par l'appelant ;
+// un objet écrit anonymement (bucket public-read-write) appartient au propriétaire du bucket
+func objectOwner(r *http.Request, s storage.Backend, bucketName string) acl.Owner {
+	if callerID(r) != "" {
+		return requestACLOwner(r)
+	}
+	owner, _ := s.BucketOwner(bucketName)
+	return acl.Owner{ID: owner, DisplayName: owner}
+}
+
+// cannedACLFromRequest construit l'ACL demandée par l'en-tête x-amz-acl (present vaut false sans en-tête)
+func cannedACLFromRequest(r *http.Request, owner acl.Owner) (a acl.ACL, present bool, err error) {
+	name := r.Header.Get("x-amz-acl")
+	if name == "" {
+		return acl.ACL{}, false, nil
+	}
+	a, err = acl.Canned(name, owner)
+	return a, true, err
+}
+
+// aclFromRequest lit la nouvelle ACL d'une requête PUT ?acl : en-tête x-amz-acl ou corps AccessControlPolicy.
+// Le propriétaire de la ressource ne change pas.
+func aclFromRequest(r *http.Request, owner acl.Owner) (acl.ACL, error) {
+	if a, present, err := cannedACLFromRequest(r, owner); present {
+		return a, err
+	}
+
+	var policy dto.AccessControlPolicy
+	if err := xml.NewDecoder(io.LimitReader(r.Body, maxACLSize)).Decode(&policy); err != nil {
+		return acl.ACL{}, acl.ErrMalformedACL
+	}
+	a := acl.ACL{Owner: owner}
+	for _, grant := range policy.AccessControlList.Grants {
+		a.Grants = append(a.Grants, acl.Grant{
+			Grantee: acl.Grantee{
+				Type:        grant.Grantee.Type,
+				ID:          grant.Grantee.ID,
+				DisplayName: grant.Grantee.DisplayName,
+				URI:         grant.Grantee.URI,
+			},
+			Permission: grant.Permission,
+		})
+	}
+	return a, a.Validate()
+}
+
+// writeACL renvoie une ACL au format AccessControlPolicy
+func writeACL(w http.ResponseWriter, a acl.ACL) {
+	response := dto.AccessControlPolicy{
+		XMLNS: "http://s3.amazonaws.com/doc/2006-03-01/",
+		Owner: dto.Owner{ID: a.Owner.ID, DisplayName: a.Owner.DisplayName},
+	}
+	for _, grant := range a.Grants {
+		response.AccessControlList.Grants = append(response.AccessControlList.Grants, dto.Grant{
+			Grantee: dto.Grantee{
+				XMLNSXSI:    "http://www.w3.org/2001/XMLSchema-instance",
+				Type:        grant.Grantee.Type,
+				ID:          grant.Grantee.ID,
+				DisplayName: grant.Grantee.DisplayName,
+				URI:         grant.Grantee.URI,
+			},
+			Permission: grant.Permission,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(response)
+}
@@ -2,48 +2,76 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/xml"
+	"fmt"
+	"io"
 	"log" // Importer le package log
 	"net/http"
+	"plateforme-mys3/internal/auth"
 	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
 	"plateforme-mys3/internal/storage"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// defaultOwner est le propriétaire renvoyé dans les réponses XML
+var defaultOwner = dto.Owner{
+	ID:          "1234567890",
+	DisplayName: "owner",
+}
+
+// requestOwner retourne le propriétaire à afficher pour l'appelant authentifié de la requête
+func requestOwner(r *http.Request) dto.Owner {
+	identity, ok := auth.IdentityFromContext(r.Context())
+	if !ok {
+		return defaultOwner
+	}
+	return dto.Owner{ID: identity.UserID, DisplayName: identity.DisplayName}
+}
+
+// callerID retourne l'identifiant de l'appelant authentifié (vide si la requête n'est pas authentifiée)
+func callerID(r *http.Request) string {
+	identity, _ := auth.IdentityFromContext(r.Context())
+	return identity.UserID
+}
+
 // ListBucketsHandler gère la liste de tous les buckets
-func ListBucketsHandler(s *storage.Storage) http.HandlerFunc {
+func ListBucketsHandler(s storage.Backend) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Println("ListBucketsHandler appelé") // Log ajouté
 
 		if r.Method != http.MethodGet {
 			log.Printf("Méthode non autorisée: %s", r.Method)
-			w.WriteHeader(http.StatusMethodNotAllowed)
+			s3err.Write(w, r, s3err.ErrMethodNotAllowed)
 			return
 		}
 
 		bucketsInfo, err := s.ListBuckets()
 		if err != nil {
 			log.Printf("Erreur lors de la liste des buckets: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			s3err.WriteError(w, r, err)
 			return
 		}
 
+		// Seuls les buckets de l'appelant sont listés
+		caller := callerID(r)
 		var buckets []dto.Bucket
 		for _, info := range bucketsInfo {
+			if owner, _ := s.BucketOwner(info.Name); caller != "" && owner != caller {
+				continue
+			}
 			buckets = append(buckets, dto.Bucket{
-				Name:         info.Name(),
-				CreationDate: info.ModTime().Format(time.RFC3339),
+				Name:         info.Name,
+				CreationDate: info.CreationDate.Format(time.RFC3339),
 			})
 		}
 
 		response := dto.ListAllMyBucketsResult{
 			XMLNS: "http://s3.amazonaws.com/doc/2006-03-01/",
-			Owner: dto.Owner{
-				ID:          "1234567890",
-				DisplayName: "owner",
-			},
+			Owner: requestOwner(r),
 			Buckets: dto.Buckets{
 				Bucket: buckets,
 			},
@@ -54,8 +82,33 @@ func ListBucketsHandler(s *storage.Storage) http.HandlerFunc {
 	}
 }
 
-// BucketHandler gère les opérations sur un bucket spécifique (PUT, DELETE)
-func BucketHandler(s *storage.Storage) http.HandlerFunc {
+// maxCreateBucketConfigSize borne la taille du corps CreateBucketConfiguration accepté
+const maxCreateBucketConfigSize = 4 * 1024
+
+// checkLocationConstraint lit le corps CreateBucketConfiguration facultatif d'une création de bucket :
+// la contrainte de localisation, si elle est donnée, doit être la région du serveur
+func checkLocationConstraint(r *http.Request, region string) error {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxCreateBucketConfigSize+1))
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	var config dto.CreateBucketConfiguration
+	if len(data) > maxCreateBucketConfigSize || xml.Unmarshal(data, &config) != nil {
+		return s3err.ErrMalformedXML
+	}
+	if config.LocationConstraint != "" && config.LocationConstraint != region {
+		return s3err.ErrIllegalLocationConstraint.WithMessage(fmt.Sprintf(
+			"The %s location constraint is incompatible for the region specific endpoint this request was sent to.", config.LocationConstraint))
+	}
+	return nil
+}
+
+// BucketHandler gère les opérations sur un bucket spécifique (PUT, DELETE, HEAD) ;
+// region est la seule contrainte de localisation acceptée à la création
+func BucketHandler(s storage.Backend, region string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		bucketName := vars["bucket"]
@@ -64,26 +117,49 @@ func BucketHandler(s *storage.Storage) http.HandlerFunc {
 
 		switch r.Method {
 		case http.MethodPut:
-			err := s.CreateBucket(bucketName)
+			bucketACL, hasACL, err := cannedACLFromRequest(r, requestACLOwner(r))
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			if err := checkLocationConstraint(r, region); err != nil {
+				log.Printf("Configuration de création refusée pour le bucket %s: %v", bucketName, err)
+				s3err.WriteError(w, r, err)
+				return
+			}
+			err = s.CreateBucket(bucketName, callerID(r))
 			if err != nil {
 				log.Printf("Erreur lors de la création du bucket %s: %v", bucketName, err)
-				w.WriteHeader(http.StatusInternalServerError)
+				s3err.WriteError(w, r, err)
 				return
 			}
+			if hasACL {
+				if err := s.PutBucketACL(bucketName, bucketACL); err != nil {
+					log.Printf("Erreur lors de l'enregistrement de l'ACL du bucket %s: %v", bucketName, err)
+					s3err.WriteError(w, r, err)
+					return
+				}
+			}
 			log.Printf("Bucket %s créé avec succès", bucketName)
 			w.WriteHeader(http.StatusOK)
 		case http.MethodDelete:
 			err := s.DeleteBucket(bucketName)
 			if err != nil {
 				log.Printf("Erreur lors de la suppression du bucket %s: %v", bucketName, err)
-				w.WriteHeader(http.StatusInternalServerError)
+				s3err.WriteError(w, r, err)
 				return
 			}
 			log.Printf("Bucket %s supprimé avec succès", bucketName)
 			w.WriteHeader(http.StatusNoContent)
+		case http.MethodHead:
+			if !s.BucketExists(bucketName) {
+				s3err.Write(w, r, s3err.ErrNoSuchBucket)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
 		default:
 			log.Printf("Méthode non autorisée: %s", r.Method)
-			w.WriteHeader(http.StatusMethodNotAllowed)
+			s3err.Write(w, r, s3err.ErrMethodNotAllowed)
 		}
 	}
 }
@@ -0,0 +1,124 @@
+// internal/handlers/checksum.go
+package handlers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+	"strings"
+)
+
+// checksumHeader retourne l'en-tête x-amz-checksum-* d'un algorithme de somme de contrôle
+func checksumHeader(algorithm string) string {
+	return "x-amz-checksum-" + strings.ToLower(algorithm)
+}
+
+// digestReader vérifie en fin de flux les empreintes annoncées pour le corps d'une requête : Content-MD5 et
+// somme de contrôle x-amz-checksum-*, transmise en en-tête ou en trailer d'un corps aws-chunked.
+// Une empreinte fausse fait échouer la lecture : le stockage abandonne alors l'écriture de l'objet.
+type digestReader struct {
+	r           io.Reader
+	request     *http.Request
+	md5         hash.Hash
+	expectedMD5 []byte
+	algorithm   string    // algorithme de la somme de contrôle, vide si aucune n'est annoncée
+	checksum    hash.Hash // calculée seulement si une valeur est à vérifier
+	expected    string    // valeur annoncée en en-tête ; vide si elle arrive en trailer
+	verified    bool
+}
+
+// newDigestReader prépare la vérification du corps d'une requête d'après ses en-têtes. Un Content-MD5 mal formé
+// donne InvalidDigest ; plusieurs sommes de contrôle, un algorithme inconnu ou une valeur mal formée, InvalidRequest.
+func newDigestReader(r *http.Request) (*digestReader, error) {
+	d := &digestReader{r: r.Body, request: r}
+
+	if header := r.Header.Get("Content-MD5"); header != "" {
+		expected, err := base64.StdEncoding.DecodeString(header)
+		if err != nil || len(expected) != md5.Size {
+			return nil, s3err.ErrInvalidDigest
+		}
+		d.md5, d.expectedMD5 = md5.New(), expected
+	}
+
+	multiple := s3err.ErrInvalidRequest.WithMessage("Expecting a single x-amz-checksum- header. Multiple checksum Types are not allowed.")
+	trailer := strings.ToLower(strings.TrimSpace(r.Header.Get("x-amz-trailer")))
+	inTrailer := false
+	for _, algorithm := range storage.ChecksumAlgorithms {
+		name := checksumHeader(algorithm)
+		value := r.Header.Get(name)
+		if value == "" && trailer != name {
+			continue
+		}
+		if d.algorithm != "" || (value != "" && trailer == name) {
+			return nil, multiple
+		}
+		d.algorithm, d.expected, inTrailer = algorithm, value, trailer == name
+	}
+
+	// x-amz-sdk-checksum-algorithm seul demande au serveur de calculer la somme sans la vérifier
+	if sdkAlgorithm := strings.ToUpper(r.Header.Get("x-amz-sdk-checksum-algorithm")); sdkAlgorithm != "" {
+		if _, ok := storage.NewChecksumHash(sdkAlgorithm); !ok || (d.algorithm != "" && d.algorithm != sdkAlgorithm) {
+			return nil, s3err.ErrInvalidRequest.WithMessage("Value for x-amz-sdk-checksum-algorithm header is invalid.")
+		}
+		d.algorithm = sdkAlgorithm
+	}
+
+	if d.expected != "" || inTrailer {
+		d.checksum, _ = storage.NewChecksumHash(d.algorithm)
+	}
+	if d.expected != "" && !validChecksum(d.expected, d.checksum) {
+		return nil, s3err.ErrInvalidRequest.WithMessage(fmt.Sprintf("Value for %s header is invalid.", checksumHeader(d.algorithm)))
+	}
+	return d, nil
+}
+
+// validChecksum indique si une valeur annoncée a le format d'une somme de l'algorithme (base64 de la bonne taille)
+func validChecksum(value string, h hash.Hash) bool {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	return err == nil && len(decoded) == h.Size()
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if d.md5 != nil {
+		d.md5.Write(p[:n])
+	}
+	if d.checksum != nil {
+		d.checksum.Write(p[:n])
+	}
+	if err == io.EOF && !d.verified {
+		d.verified = true
+		if verifyErr := d.verify(); verifyErr != nil {
+			return n, verifyErr
+		}
+	}
+	return n, err
+}
+
+// verify compare les empreintes calculées à celles annoncées, une fois le corps entièrement lu
+// (les trailers d'un corps aws-chunked ne sont connus qu'à ce moment)
+func (d *digestReader) verify() error {
+	if d.md5 != nil && !bytes.Equal(d.md5.Sum(nil), d.expectedMD5) {
+		return s3err.ErrBadDigest
+	}
+	if d.checksum == nil {
+		return nil
+	}
+	expected := d.expected
+	if expected == "" {
+		expected = d.request.Trailer.Get(checksumHeader(d.algorithm))
+		if expected == "" || !validChecksum(expected, d.checksum) {
+			return s3err.ErrInvalidRequest.WithMessage(fmt.Sprintf("Value for %s trailing header is invalid.", checksumHeader(d.algorithm)))
+		}
+	}
+	if base64.StdEncoding.EncodeToString(d.checksum.Sum(nil)) != expected {
+		return s3err.ErrBadDigest.WithMessage(fmt.Sprintf("The %s you specified did not match the calculated checksum.", d.algorithm))
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+// internal/handlers/copy.go
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"plateforme-mys3/internal/acl"
+	"plateforme-mys3/internal/auth"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCopySize est la taille maximale copiée par une seule requête CopyObject ou UploadPartCopy (5 Go)
+const maxCopySize = 5 << 30
+
+// copySource est l'objet source désigné par l'en-tête x-amz-copy-source
+type copySource struct {
+	bucket, key, versionID string
+}
+
+// parseCopySource lit l'en-tête x-amz-copy-source : "[/]bucket/clé" encodé en URL, suivi d'un éventuel "?versionId=..."
+func parseCopySource(header string) (copySource, error) {
+	invalid := s3err.ErrInvalidArgument.WithMessage("Copy Source must mention the source bucket and key: sourcebucket/sourcekey")
+
+	// Le "?" de la version n'est pas encodé, contrairement à celui d'une clé
+	path, query, _ := strings.Cut(header, "?")
+	var src copySource
+	if query != "" {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return copySource{}, invalid
+		}
+		src.versionID = values.Get("versionId")
+		if _, ok := values["versionId"]; ok && src.versionID == "" {
+			return copySource{}, s3err.ErrInvalidArgument.WithMessage("Version id cannot be the empty string")
+		}
+	}
+	path, err := url.PathUnescape(path)
+	if err != nil {
+		return copySource{}, invalid
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if bucket == "" || key == "" {
+		return copySource{}, invalid
+	}
+	src.bucket, src.key = bucket, key
+	return src, nil
+}
+
+// checkCopyPreconditions évalue les en-têtes x-amz-copy-source-if-* sur l'objet source ; contrairement à
+// une lecture, tout échec donne 412. Les règles de priorité sont celles de checkPreconditions.
+func checkCopyPreconditions(r *http.Request, metadata storage.ObjectMetadata) bool {
+	lastModified := metadata.LastModified.UTC().Truncate(time.Second)
+
+	if ifMatch := r.Header.Get("x-amz-copy-source-if-match"); ifMatch != "" {
+		if !etagMatches(ifMatch, metadata.ETag) {
+			return false
+		}
+	} else if since, ok := headerTime(r, "x-amz-copy-source-if-unmodified-since"); ok && lastModified.After(since) {
+		return false
+	}
+
+	if ifNoneMatch := r.Header.Get("x-amz-copy-source-if-none-match"); ifNoneMatch != "" {
+		if etagMatches(ifNoneMatch, metadata.ETag) {
+			return false
+		}
+	} else if since, ok := headerTime(r, "x-amz-copy-source-if-modified-since"); ok && !lastModified.After(since) {
+		return false
+	}
+	return true
+}
+
+// openCopySource ouvre l'objet source d'une copie après avoir vérifié que le client peut le lire et que
+// les conditions x-amz-copy-source-if-* sont remplies. En cas d'échec, l'erreur S3 est déjà écrite.
+func openCopySource(w http.ResponseWriter, r *http.Request, s storage.Backend) (io.ReadSeekCloser, storage.ObjectMetadata, copySource, bool) {
+	src, err := parseCopySource(r.Header.Get("x-amz-copy-source"))
+	if err != nil {
+		s3err.WriteError(w, r, err)
+		return nil, storage.ObjectMetadata{}, src, false
+	}
+	customerKey, err := customerKeyFromRequest(r, sseCopySourceCustomerPrefix)
+	if err != nil {
+		s3err.WriteError(w, r, err)
+		return nil, storage.ObjectMetadata{}, src, false
+	}
+
+	// La source est autorisée comme une lecture, la destination l'ayant été par le middleware
+	action := "s3:GetObject"
+	if src.versionID != "" {
+		action = "s3:GetObjectVersion"
+	}
+	if !auth.Authorize(r.Context(), src.bucket, src.key, action) {
+		s3err.Write(w, r, s3err.ErrAccessDenied)
+		return nil, storage.ObjectMetadata{}, src, false
+	}
+
+	// Une source SSE-C se lit avec les en-têtes x-amz-copy-source-server-side-encryption-customer-*
+	file, metadata, err := s.OpenObjectVersion(src.bucket, src.key, src.versionID, customerKey)
+	if err != nil {
+		writeObjectError(w, r, s, src.bucket, err)
+		return nil, storage.ObjectMetadata{}, src, false
+	}
+	if metadata.DeleteMarker {
+		if src.versionID == "" {
+			s3err.Write(w, r, s3err.ErrNoSuchKey)
+		} else {
+			s3err.Write(w, r, s3err.ErrInvalidRequest.WithMessage("The source of a copy request may not specifically refer to a delete marker by version id."))
+		}
+		return nil, storage.ObjectMetadata{}, src, false
+	}
+	if !checkCopyPreconditions(r, metadata) {
+		file.Close()
+		s3err.Write(w, r, s3err.ErrPreconditionFailed)
+		return nil, storage.ObjectMetadata{}, src, false
+	}
+	return file, metadata, src, true
+}
+
+// copiedMetadata retourne les métadonnées de la source reprises par une copie (directive COPY) ;
+// ETag, taille, date, version et somme de contrôle sont recalculés par le stockage
+func copiedMetadata(source storage.ObjectMetadata) storage.ObjectMetadata {
+	metadata := storage.ObjectMetadata{
+		ContentType:        source.ContentType,
+		ContentEncoding:    source.ContentEncoding,
+		ContentDisposition: source.ContentDisposition,
+		ContentLanguage:    source.ContentLanguage,
+		CacheControl:       source.CacheControl,
+		Expires:            source.Expires,
+		ChecksumAlgorithm:  source.ChecksumAlgorithm,
+	}
+	if len(source.UserMetadata) > 0 {
+		metadata.UserMetadata = make(map[string]string, len(source.UserMetadata))
+		for key, value := range source.UserMetadata {
+			metadata.UserMetadata[key] = value
+		}
+	}
+	if len(source.Tags) > 0 {
+		metadata.Tags = make(map[string]string, len(source.Tags))
+		for key, value := range source.Tags {
+			metadata.Tags[key] = value
+		}
+	}
+	return metadata
+}
+
+// copyObject gère CopyObject (PUT avec x-amz-copy-source) : le contenu de la source est copié en flux
+// vers la destination, avec ses métadonnées (COPY, par défaut) ou celles de la requête (REPLACE).
+// Les étiquettes suivent de même x-amz-tagging-directive. Le chiffrement de la destination est celui demandé
+// par la requête, quel que soit celui de la source.
+func copyObject(w http.ResponseWriter, r *http.Request, s storage.Backend, bucketName, objectName string, objectACL acl.ACL, hasACL bool) {
+	directive := strings.ToUpper(r.Header.Get("x-amz-metadata-directive"))
+	if directive == "" {
+		directive = "COPY"
+	}
+	if directive != "COPY" && directive != "REPLACE" {
+		s3err.Write(w, r, s3err.ErrInvalidArgument.WithMessage("Unknown metadata directive."))
+		return
+	}
+	taggingDirective := strings.ToUpper(r.Header.Get("x-amz-tagging-directive"))
+	if taggingDirective == "" {
+		taggingDirective = "COPY"
+	}
+	if taggingDirective != "COPY" && taggingDirective != "REPLACE" {
+		s3err.Write(w, r, s3err.ErrInvalidArgument.WithMessage("Unknown tagging directive."))
+		return
+	}
+	encryption, err := encryptionFromRequest(r)
+	if err != nil {
+		s3err.WriteError(w, r, err)
+		return
+	}
+
+	file, sourceMetadata, src, ok := openCopySource(w, r, s)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	if sourceMetadata.Size > maxCopySize {
+		s3err.Write(w, r, s3err.ErrInvalidRequest.WithMessage(fmt.Sprintf("The specified copy source is larger than the maximum allowable size for a copy source: %d", int64(maxCopySize))))
+		return
+	}
+	// Copier la dernière version d'un objet sur lui-même ne change rien sans nouvelles métadonnées ni nouveau chiffrement
+	if src.bucket == bucketName && src.key == objectName && src.versionID == "" && directive == "COPY" && encryption == nil {
+		s3err.Write(w, r, s3err.ErrInvalidRequest.WithMessage("This copy request is illegal because it is trying to copy an object to itself without changing the object's metadata, storage class, website redirect location or encryption attributes."))
+		return
+	}
+
+	metadata := copiedMetadata(sourceMetadata)
+	if directive == "REPLACE" {
+		if metadata, err = metadataFromRequest(r); err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+	}
+	if taggingDirective == "COPY" {
+		metadata.Tags = copiedMetadata(sourceMetadata).Tags
+	} else if metadata.Tags, err = tagsFromRequest(r); err != nil {
+		s3err.WriteError(w, r, err)
+		return
+	}
+	if metadata.Encryption, err = withBucketEncryption(s, bucketName, encryption); err != nil {
+		s3err.WriteError(w, r, err)
+		return
+	}
+
+	if hasACL {
+		metadata.ACL = &objectACL
+	}
+	result, err := s.PutObject(bucketName, objectName, file, metadata)
+	if err != nil {
+		log.Printf("Erreur lors de la copie de %s/%s vers %s/%s: %v", src.bucket, src.key, bucketName, objectName, err)
+		s3err.WriteError(w, r, err)
+		return
+	}
+
+	lastModified := time.Now()
+	if written, err := s.GetObjectVersionMetadata(bucketName, objectName, result.VersionID); err == nil {
+		lastModified = written.LastModified
+	}
+	if sourceMetadata.VersionID != "" {
+		w.Header().Set("x-amz-copy-source-version-id", sourceMetadata.VersionID)
+	}
+	if result.VersionID != "" {
+		w.Header().Set("x-amz-version-id", result.VersionID)
+	}
+	writeEncryptionHeaders(w.Header(), metadata.Encryption)
+	log.Printf("Objet %s/%s copié vers %s/%s (%d octets)", src.bucket, src.key, bucketName, objectName, result.Size)
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(dto.CopyObjectResult{
+		XMLNS:        "http://s3.amazonaws.com/doc/2006-03-01/",
+		ETag:         "\"" + result.ETag + "\"",
+		LastModified: lastModified.UTC().Format(time.RFC3339),
+	})
+}
+
+// parseCopySourceRange lit l'en-tête x-amz-copy-source-range ("bytes=premier-dernier", bornes incluses),
+// qui doit désigner une plage entièrement contenue dans la source
+func parseCopySourceRange(header string, size int64) (byteRange, error) {
+	ok := strings.HasPrefix(header, "bytes=")
+	first, last, found := strings.Cut(strings.TrimPrefix(header, "bytes="), "-")
+	start, errStart := strconv.ParseInt(first, 10, 64)
+	end, errEnd := strconv.ParseInt(last, 10, 64)
+	if !ok || !found || errStart != nil || errEnd != nil || start < 0 || end < start {
+		return byteRange{}, s3err.ErrInvalidArgument.WithMessage("The x-amz-copy-source-range value must be of the form bytes=first-last where first and last are the zero-based offsets of the first and last bytes to copy")
+	}
+	if end >= size {
+		return byteRange{}, s3err.ErrInvalidArgument.WithMessage(fmt.Sprintf("Range specified is not valid for source object of size: %d", size))
+	}
+	return byteRange{start: start, end: end}, nil
+}
+
+// copyPart gère UploadPartCopy (PUT ?partNumber&uploadId avec x-amz-copy-source) : la part est lue
+// dans l'objet source, entièrement ou sur la plage x-amz-copy-source-range
+func copyPart(w http.ResponseWriter, r *http.Request, s storage.Backend, bucketName, objectName, uploadID string, partNumber int) {
+	file, sourceMetadata, src, ok := openCopySource(w, r, s)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	br := byteRange{start: 0, end: sourceMetadata.Size - 1}
+	if header := r.Header.Get("x-amz-copy-source-range"); header != "" {
+		var err error
+		if br, err = parseCopySourceRange(header, sourceMetadata.Size); err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+	}
+	if br.length() > maxCopySize {
+		s3err.Write(w, r, s3err.ErrInvalidRequest.WithMessage(fmt.Sprintf("The specified copy range is larger than the maximum allowable size for a part: %d", int64(maxCopySize))))
+		return
+	}
+	if _, err := file.Seek(br.start, io.SeekStart); err != nil {
+		log.Printf("Erreur lors du positionnement dans %s/%s: %v", src.bucket, src.key, err)
+		s3err.WriteError(w, r, err)
+		return
+	}
+
+	// La part d'un upload SSE-C est chiffrée avec la clé du client, comme pour UploadPart
+	customerKey, err := customerKeyFromRequest(r, sseCustomerPrefix)
+	if err != nil {
+		s3err.WriteError(w, r, err)
+		return
+	}
+	part, err := s.UploadPart(bucketName, objectName, uploadID, partNumber, io.LimitReader(file, br.length()), customerKey)
+	if err != nil {
+		log.Printf("Erreur lors de la copie de %s/%s dans la part %d de l'upload %s: %v", src.bucket, src.key, partNumber, uploadID, err)
+		s3err.WriteError(w, r, err)
+		return
+	}
+
+	if sourceMetadata.VersionID != "" {
+		w.Header().Set("x-amz-copy-source-version-id", sourceMetadata.VersionID)
+	}
+	writeCustomerKeyHeaders(w.Header(), customerKey)
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(dto.CopyPartResult{
+		XMLNS:        "http://s3.amazonaws.com/doc/2006-03-01/",
+		ETag:         "\"" + part.ETag + "\"",
+		LastModified: part.LastModified.UTC().Format(time.RFC3339),
+	})
+}
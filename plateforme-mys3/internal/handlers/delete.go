@@ -0,0 +1,132 @@
+// internal/handlers/delete.go
+package handlers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"plateforme-mys3/internal/auth"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxDeleteObjects est le nombre maximal de clés d'une suppression multiple
+	maxDeleteObjects = 1000
+	// maxDeleteRequestSize borne la taille du corps d'une suppression multiple (1000 clés de 1024 octets et leur XML)
+	maxDeleteRequestSize = 2 << 20
+)
+
+// checkContentMD5 vérifie l'en-tête Content-MD5 d'une requête dont le corps est déjà lu.
+// Il est obligatoire, sauf si le client fournit une somme de contrôle x-amz-checksum-* à la place.
+func checkContentMD5(r *http.Request, body []byte) error {
+	header := r.Header.Get("Content-MD5")
+	if header == "" {
+		for name := range r.Header {
+			if strings.HasPrefix(strings.ToLower(name), "x-amz-checksum-") {
+				return nil
+			}
+		}
+		return s3err.ErrInvalidRequest.WithMessage("Missing required header for this request: Content-MD5")
+	}
+	expected, err := base64.StdEncoding.DecodeString(header)
+	if err != nil || len(expected) != md5.Size {
+		return s3err.ErrInvalidDigest
+	}
+	sum := md5.Sum(body)
+	if !bytes.Equal(expected, sum[:]) {
+		return s3err.ErrBadDigest
+	}
+	return nil
+}
+
+// DeleteObjectsHandler gère la suppression multiple (POST /{bucket}?delete) : jusqu'à 1000 clés, chacune
+// supprimée comme par DELETE (versionId compris) et autorisée individuellement. Le résultat liste les clés
+// supprimées (sauf en mode Quiet) et les erreurs rencontrées.
+func DeleteObjectsHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucket"]
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxDeleteRequestSize+1))
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+		if len(body) > maxDeleteRequestSize {
+			s3err.Write(w, r, s3err.ErrMalformedXML)
+			return
+		}
+		if err := checkContentMD5(r, body); err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+		var request dto.Delete
+		if err := xml.Unmarshal(body, &request); err != nil || len(request.Objects) == 0 || len(request.Objects) > maxDeleteObjects {
+			log.Printf("Corps Delete invalide pour le bucket %s (%d clés): %v", bucketName, len(request.Objects), err)
+			s3err.Write(w, r, s3err.ErrMalformedXML)
+			return
+		}
+		if !s.BucketExists(bucketName) {
+			s3err.Write(w, r, s3err.ErrNoSuchBucket)
+			return
+		}
+
+		response := dto.DeleteResult{XMLNS: "http://s3.amazonaws.com/doc/2006-03-01/"}
+		for _, object := range request.Objects {
+			action := "s3:DeleteObject"
+			if object.VersionId != "" {
+				action = "s3:DeleteObjectVersion"
+			}
+			var apiErr *s3err.APIError
+			switch {
+			case object.Key == "":
+				e := s3err.ErrInvalidArgument.WithMessage("The key of an object to delete must be specified.")
+				apiErr = &e
+			case !auth.Authorize(r.Context(), bucketName, object.Key, action):
+				apiErr = &s3err.ErrAccessDenied
+			}
+
+			var result storage.DeleteResult
+			if apiErr == nil {
+				result, err = s.DeleteObject(bucketName, object.Key, object.VersionId)
+				if err != nil {
+					log.Printf("Erreur lors de la suppression de %s/%s: %v", bucketName, object.Key, err)
+					e := s3err.FromError(err)
+					apiErr = &e
+				}
+			}
+			if apiErr != nil {
+				response.Errors = append(response.Errors, dto.DeleteError{
+					Key:       object.Key,
+					VersionId: object.VersionId,
+					Code:      apiErr.Code,
+					Message:   apiErr.Message,
+				})
+				continue
+			}
+			if request.Quiet {
+				continue
+			}
+
+			deleted := dto.DeletedObject{Key: object.Key, VersionId: object.VersionId}
+			if result.DeleteMarker {
+				// Marqueur créé (sans versionId) ou marqueur supprimé (avec versionId)
+				deleted.DeleteMarker = true
+				deleted.DeleteMarkerVersionId = result.VersionID
+			}
+			response.Deleted = append(response.Deleted, deleted)
+		}
+
+		log.Printf("Suppression multiple dans le bucket %s : %d clé(s), %d erreur(s)", bucketName, len(request.Objects), len(response.Errors))
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,93 @@
+// internal/handlers/encryption.go
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// maxEncryptionConfigSize borne la taille d'une configuration de chiffrement acceptée
+const maxEncryptionConfigSize = 4 * 1024
+
+// BucketEncryptionHandler gère le chiffrement par défaut d'un bucket (?encryption : PUT, GET, DELETE)
+func BucketEncryptionHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucket"]
+
+		switch r.Method {
+		case http.MethodPut:
+			data, err := io.ReadAll(io.LimitReader(r.Body, maxEncryptionConfigSize+1))
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			var config dto.ServerSideEncryptionConfiguration
+			// Comme S3, la configuration compte exactement une règle
+			if len(data) > maxEncryptionConfigSize || xml.Unmarshal(data, &config) != nil ||
+				len(config.Rules) != 1 || config.Rules[0].ApplyServerSideEncryptionByDefault == nil {
+				s3err.Write(w, r, s3err.ErrMalformedXML)
+				return
+			}
+			rule := config.Rules[0]
+			if rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID != "" {
+				s3err.Write(w, r, s3err.ErrInvalidArgument.WithMessage("a KMSMasterKeyID is not applicable if the default sse algorithm is not aws:kms"))
+				return
+			}
+			err = s.PutBucketEncryption(bucketName, storage.BucketEncryption{
+				SSEAlgorithm:     rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm,
+				BucketKeyEnabled: rule.BucketKeyEnabled,
+			})
+			if err != nil {
+				log.Printf("Configuration de chiffrement refusée pour le bucket %s: %v", bucketName, err)
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			config, err := s.GetBucketEncryption(bucketName)
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			xml.NewEncoder(w).Encode(dto.ServerSideEncryptionConfiguration{
+				XMLNS: "http://s3.amazonaws.com/doc/2006-03-01/",
+				Rules: []dto.ServerSideEncryptionRule{{
+					ApplyServerSideEncryptionByDefault: &dto.ServerSideEncryptionByDefault{SSEAlgorithm: config.SSEAlgorithm},
+					BucketKeyEnabled:                   config.BucketKeyEnabled,
+				}},
+			})
+		case http.MethodDelete:
+			if err := s.DeleteBucketEncryption(bucketName); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			s3err.Write(w, r, s3err.ErrMethodNotAllowed)
+		}
+	}
+}
+
+// withBucketEncryption complète le chiffrement demandé par une requête (nil si aucun) avec le chiffrement par
+// défaut du bucket : un objet écrit sans en-tête de chiffrement dans un bucket chiffré par défaut est chiffré en SSE-S3
+func withBucketEncryption(s storage.Backend, bucketName string, info *storage.EncryptionInfo) (*storage.EncryptionInfo, error) {
+	if info != nil {
+		return info, nil
+	}
+	if _, err := s.GetBucketEncryption(bucketName); err != nil {
+		if errors.Is(err, storage.ErrNoSuchEncryptionConfiguration) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &storage.EncryptionInfo{}, nil
+}
@@ -0,0 +1,248 @@
+// internal/handlers/lifecycle.go
+package handlers
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxLifecycleRules est le nombre maximal de règles d'une configuration du cycle de vie
+	maxLifecycleRules = 1000
+	// maxLifecycleConfigSize borne la taille d'une configuration du cycle de vie acceptée
+	maxLifecycleConfigSize = 1 << 20
+	// maxLifecycleRuleIDLength est la longueur maximale de l'identifiant d'une règle
+	maxLifecycleRuleIDLength = 255
+)
+
+// BucketLifecycleHandler gère la configuration du cycle de vie d'un bucket (?lifecycle : PUT, GET, DELETE).
+// Les règles sont appliquées par le planificateur du serveur (storage.RunLifecycle).
+func BucketLifecycleHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucket"]
+
+		switch r.Method {
+		case http.MethodPut:
+			data, err := io.ReadAll(io.LimitReader(r.Body, maxLifecycleConfigSize+1))
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			if len(data) > maxLifecycleConfigSize {
+				s3err.Write(w, r, s3err.ErrMalformedXML)
+				return
+			}
+			if err := checkContentMD5(r, data); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			var request dto.LifecycleConfiguration
+			if err := xml.Unmarshal(data, &request); err != nil {
+				s3err.Write(w, r, s3err.ErrMalformedXML)
+				return
+			}
+			config, err := lifecycleFromRequest(request)
+			if err != nil {
+				log.Printf("Cycle de vie refusé pour le bucket %s: %v", bucketName, err)
+				s3err.WriteError(w, r, err)
+				return
+			}
+			if err := s.PutBucketLifecycle(bucketName, config); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			config, err := s.GetBucketLifecycle(bucketName)
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			xml.NewEncoder(w).Encode(lifecycleResponse(config))
+		case http.MethodDelete:
+			if err := s.DeleteBucketLifecycle(bucketName); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			s3err.Write(w, r, s3err.ErrMethodNotAllowed)
+		}
+	}
+}
+
+// lifecycleFromRequest valide une configuration du cycle de vie reçue et la convertit pour le stockage
+func lifecycleFromRequest(request dto.LifecycleConfiguration) (storage.LifecycleConfiguration, error) {
+	var config storage.LifecycleConfiguration
+	if len(request.Rules) == 0 || len(request.Rules) > maxLifecycleRules {
+		return config, s3err.ErrMalformedXML
+	}
+	ids := make(map[string]bool)
+	for _, rule := range request.Rules {
+		if len(rule.ID) > maxLifecycleRuleIDLength {
+			return config, s3err.ErrInvalidArgument.WithMessage("ID length should not exceed allowed limit of 255")
+		}
+		if rule.ID != "" && ids[rule.ID] {
+			return config, s3err.ErrInvalidArgument.WithMessage("Rule ID must be unique. Found same ID for more than one rule")
+		}
+		ids[rule.ID] = true
+		if rule.Status != "Enabled" && rule.Status != "Disabled" {
+			return config, s3err.ErrMalformedXML
+		}
+
+		converted := storage.LifecycleRule{ID: rule.ID, Enabled: rule.Status == "Enabled"}
+		if err := lifecycleFilter(rule, &converted); err != nil {
+			return config, err
+		}
+		if err := lifecycleActions(rule, &converted); err != nil {
+			return config, err
+		}
+		config.Rules = append(config.Rules, converted)
+	}
+	return config, nil
+}
+
+// lifecycleFilter lit le filtre d'une règle : Filter (Prefix, Tag ou And) ou Prefix hérité, l'un ou l'autre
+func lifecycleFilter(rule dto.LifecycleRule, converted *storage.LifecycleRule) error {
+	switch {
+	case rule.Filter != nil && rule.Prefix != nil, rule.Filter == nil && rule.Prefix == nil:
+		return s3err.ErrMalformedXML
+	case rule.Prefix != nil:
+		converted.Prefix = *rule.Prefix
+		return nil
+	}
+
+	filter := rule.Filter
+	set := 0
+	for _, present := range []bool{filter.Prefix != nil, filter.Tag != nil, filter.And != nil} {
+		if present {
+			set++
+		}
+	}
+	if set > 1 {
+		return s3err.ErrMalformedXML
+	}
+	var tags []dto.Tag
+	switch {
+	case filter.Prefix != nil:
+		converted.Prefix = *filter.Prefix
+	case filter.Tag != nil:
+		tags = []dto.Tag{*filter.Tag}
+	case filter.And != nil:
+		converted.Prefix = filter.And.Prefix
+		tags = filter.And.Tags
+	}
+	for _, tag := range tags {
+		if tag.Key == "" || len(tag.Key) > maxTagKeyLength || len(tag.Value) > maxTagValueLength {
+			return s3err.ErrInvalidArgument.WithMessage("The TagKey you have provided is invalid")
+		}
+		if converted.Tags == nil {
+			converted.Tags = make(map[string]string)
+		}
+		if _, found := converted.Tags[tag.Key]; found {
+			return s3err.ErrInvalidRequest.WithMessage("Duplicate Tag Keys are not allowed.")
+		}
+		converted.Tags[tag.Key] = tag.Value
+	}
+	return nil
+}
+
+// lifecycleActions lit les actions d'une règle ; il en faut au moins une
+func lifecycleActions(rule dto.LifecycleRule, converted *storage.LifecycleRule) error {
+	if rule.Expiration == nil && rule.NoncurrentVersionExpiration == nil && rule.AbortIncompleteMultipartUpload == nil {
+		return s3err.ErrInvalidRequest.WithMessage("At least one action needs to be specified in a rule")
+	}
+
+	if expiration := rule.Expiration; expiration != nil {
+		switch {
+		case (expiration.Days != 0) == (expiration.Date != ""):
+			return s3err.ErrMalformedXML
+		case expiration.Days < 0:
+			return s3err.ErrInvalidArgument.WithMessage("'Days' for Expiration action must be a positive integer")
+		case expiration.Date != "":
+			date, err := time.Parse(time.RFC3339, expiration.Date)
+			if err != nil {
+				return s3err.ErrInvalidArgument.WithMessage("Error parsing parameter 'Date'. Date must be in ISO 8601 format")
+			}
+			if !date.UTC().Truncate(24 * time.Hour).Equal(date) {
+				return s3err.ErrInvalidArgument.WithMessage("'Date' must be at midnight GMT")
+			}
+			converted.ExpirationDate = date.UTC()
+		default:
+			converted.ExpirationDays = expiration.Days
+		}
+	}
+
+	if noncurrent := rule.NoncurrentVersionExpiration; noncurrent != nil {
+		if noncurrent.NoncurrentDays <= 0 {
+			return s3err.ErrInvalidArgument.WithMessage("'NoncurrentDays' for NoncurrentVersionExpiration action must be a positive integer")
+		}
+		converted.NoncurrentDays = noncurrent.NoncurrentDays
+	}
+
+	if abort := rule.AbortIncompleteMultipartUpload; abort != nil {
+		if abort.DaysAfterInitiation <= 0 {
+			return s3err.ErrInvalidArgument.WithMessage("'DaysAfterInitiation' for AbortIncompleteMultipartUpload action must be a positive integer")
+		}
+		// Un upload n'a pas encore d'étiquettes
+		if len(converted.Tags) > 0 {
+			return s3err.ErrInvalidRequest.WithMessage("Tag-based filter cannot be used with AbortIncompleteMultipartUpload action")
+		}
+		converted.AbortIncompleteDays = abort.DaysAfterInitiation
+	}
+	return nil
+}
+
+// lifecycleResponse convertit une configuration enregistrée en réponse GET ?lifecycle, avec le filtre le plus simple
+func lifecycleResponse(config storage.LifecycleConfiguration) dto.LifecycleConfiguration {
+	response := dto.LifecycleConfiguration{XMLNS: "http://s3.amazonaws.com/doc/2006-03-01/"}
+	for _, rule := range config.Rules {
+		converted := dto.LifecycleRule{ID: rule.ID, Status: "Disabled", Filter: &dto.LifecycleFilter{}}
+		if rule.Enabled {
+			converted.Status = "Enabled"
+		}
+
+		keys := make([]string, 0, len(rule.Tags))
+		for key := range rule.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var tags []dto.Tag
+		for _, key := range keys {
+			tags = append(tags, dto.Tag{Key: key, Value: rule.Tags[key]})
+		}
+		switch {
+		case len(tags) == 0:
+			prefix := rule.Prefix
+			converted.Filter.Prefix = &prefix
+		case len(tags) == 1 && rule.Prefix == "":
+			converted.Filter.Tag = &tags[0]
+		default:
+			converted.Filter.And = &dto.LifecycleAnd{Prefix: rule.Prefix, Tags: tags}
+		}
+
+		if rule.ExpirationDays > 0 {
+			converted.Expiration = &dto.LifecycleExpiration{Days: rule.ExpirationDays}
+		} else if !rule.ExpirationDate.IsZero() {
+			converted.Expiration = &dto.LifecycleExpiration{Date: rule.ExpirationDate.UTC().Format(time.RFC3339)}
+		}
+		if rule.NoncurrentDays > 0 {
+			converted.NoncurrentVersionExpiration = &dto.NoncurrentVersionExpiration{NoncurrentDays: rule.NoncurrentDays}
+		}
+		if rule.AbortIncompleteDays > 0 {
+			converted.AbortIncompleteMultipartUpload = &dto.AbortIncompleteMultipartUpload{DaysAfterInitiation: rule.AbortIncompleteDays}
+		}
+		response.Rules = append(response.Rules, converted)
+	}
+	return response
+}
@@ -0,0 +1,171 @@
+// internal/handlers/list.go
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"net/url"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxListKeys est le nombre maximal de clés retournées par page, comme sur S3
+const maxListKeys = 1000
+
+// Erreurs renvoyées pour les paramètres de listing invalides
+var (
+	errInvalidMaxKeys      = s3err.ErrInvalidArgument.WithMessage("Provided max-keys not an integer or within integer range")
+	errInvalidEncodingType = s3err.ErrInvalidArgument.WithMessage("Invalid Encoding Method specified in Request")
+)
+
+// ListObjectsHandler gère la liste des objets d'un bucket : ListObjects (V1) ou ListObjectsV2 (list-type=2)
+func ListObjectsHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucket"]
+		query := r.URL.Query()
+
+		maxKeys, ok := intQueryParam(query.Get("max-keys"), maxListKeys)
+		if !ok {
+			s3err.Write(w, r, errInvalidMaxKeys)
+			return
+		}
+		if maxKeys > maxListKeys {
+			maxKeys = maxListKeys
+		}
+		encodingType := query.Get("encoding-type")
+		if encodingType != "" && encodingType != "url" {
+			s3err.Write(w, r, errInvalidEncodingType)
+			return
+		}
+
+		opts := storage.ListObjectsOptions{
+			Prefix:    query.Get("prefix"),
+			Delimiter: query.Get("delimiter"),
+			MaxKeys:   maxKeys,
+		}
+		v2 := query.Get("list-type") == "2"
+		if v2 {
+			opts.StartAfter = query.Get("start-after")
+			if token := query.Get("continuation-token"); token != "" {
+				key, err := decodeContinuationToken(token)
+				if err != nil {
+					log.Printf("Jeton de continuation invalide pour le bucket %s: %q", bucketName, token)
+					s3err.Write(w, r, s3err.ErrInvalidArgument.WithMessage("The continuation token provided is incorrect"))
+					return
+				}
+				opts.StartAfter = key
+			}
+		} else {
+			opts.StartAfter = query.Get("marker")
+		}
+
+		result, err := s.ListObjects(bucketName, opts)
+		if err != nil {
+			log.Printf("Erreur lors du listing du bucket %s: %v", bucketName, err)
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		encode := func(value string) string { return value }
+		if encodingType == "url" {
+			encode = encodeListValue
+		}
+		// ListObjects (V1) renvoie toujours le propriétaire des objets, ListObjectsV2 seulement avec fetch-owner
+		withOwner := !v2 || query.Get("fetch-owner") == "true"
+		contents := listedObjects(s, bucketName, result.Objects, withOwner, encode)
+		var prefixes []dto.CommonPrefix
+		for _, prefix := range result.CommonPrefixes {
+			prefixes = append(prefixes, dto.CommonPrefix{Prefix: encode(prefix)})
+		}
+
+		var response interface{}
+		if v2 {
+			responseV2 := dto.ListBucketResultV2{
+				XMLNS:             "http://s3.amazonaws.com/doc/2006-03-01/",
+				Name:              bucketName,
+				Prefix:            encode(opts.Prefix),
+				Delimiter:         encode(opts.Delimiter),
+				MaxKeys:           maxKeys,
+				KeyCount:          len(contents) + len(prefixes),
+				EncodingType:      encodingType,
+				IsTruncated:       result.IsTruncated,
+				ContinuationToken: query.Get("continuation-token"),
+				StartAfter:        encode(query.Get("start-after")),
+				Contents:          contents,
+				CommonPrefixes:    prefixes,
+			}
+			if result.IsTruncated {
+				responseV2.NextContinuationToken = encodeContinuationToken(result.NextMarker)
+			}
+			response = responseV2
+		} else {
+			responseV1 := dto.ListBucketResult{
+				XMLNS:          "http://s3.amazonaws.com/doc/2006-03-01/",
+				Name:           bucketName,
+				Prefix:         encode(opts.Prefix),
+				Marker:         encode(opts.StartAfter),
+				Delimiter:      encode(opts.Delimiter),
+				MaxKeys:        maxKeys,
+				EncodingType:   encodingType,
+				IsTruncated:    result.IsTruncated,
+				Contents:       contents,
+				CommonPrefixes: prefixes,
+			}
+			// Comme S3, NextMarker n'est renvoyé qu'avec un délimiteur (sinon la dernière clé fait office de marqueur)
+			if result.IsTruncated && opts.Delimiter != "" {
+				responseV1.NextMarker = encode(result.NextMarker)
+			}
+			response = responseV1
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(response)
+	}
+}
+
+// listedObjects convertit les objets listés en entrées Contents
+func listedObjects(s storage.Backend, bucketName string, objects []storage.ObjectInfo, withOwner bool, encode func(string) string) []dto.Object {
+	var contents []dto.Object
+	for _, object := range objects {
+		entry := dto.Object{
+			Key:          encode(object.Key),
+			LastModified: object.Metadata.LastModified.UTC().Format(time.RFC3339),
+			ETag:         "\"" + object.Metadata.ETag + "\"",
+			Size:         object.Metadata.Size,
+			StorageClass: "STANDARD",
+		}
+		if withOwner {
+			if objectACL, err := s.GetObjectACL(bucketName, object.Key); err == nil {
+				entry.Owner = &dto.Owner{ID: objectACL.Owner.ID, DisplayName: objectACL.Owner.DisplayName}
+			}
+		}
+		contents = append(contents, entry)
+	}
+	return contents
+}
+
+// encodeContinuationToken rend opaque la clé à partir de laquelle reprendre un listing V2
+func encodeContinuationToken(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeContinuationToken retrouve la clé encodée dans un jeton de continuation
+func decodeContinuationToken(token string) (string, error) {
+	key, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
+
+// encodeListValue encode une clé pour encoding-type=url ; les "/" sont conservés comme sur S3
+func encodeListValue(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "%2F", "/")
+}
@@ -0,0 +1,138 @@
+// internal/handlers/metadata.go
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+	"strconv"
+	"strings"
+)
+
+const (
+	// userMetadataPrefix préfixe les en-têtes de métadonnées utilisateur
+	userMetadataPrefix = "x-amz-meta-"
+	// maxUserMetadataSize est la taille maximale des métadonnées utilisateur acceptée par S3 (2 Ko)
+	maxUserMetadataSize = 2 * 1024
+	// defaultContentType est le type renvoyé par S3 pour un objet écrit sans Content-Type
+	defaultContentType = "binary/octet-stream"
+	// maxObjectTags, maxTagKeyLength et maxTagValueLength sont les limites S3 des étiquettes d'un objet
+	maxObjectTags     = 10
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// metadataFromRequest extrait des en-têtes de la requête les métadonnées à enregistrer avec l'objet
+func metadataFromRequest(r *http.Request) (storage.ObjectMetadata, error) {
+	metadata := storage.ObjectMetadata{
+		ContentType:        r.Header.Get("Content-Type"),
+		ContentEncoding:    contentEncoding(r.Header.Get("Content-Encoding")),
+		ContentDisposition: r.Header.Get("Content-Disposition"),
+		ContentLanguage:    r.Header.Get("Content-Language"),
+		CacheControl:       r.Header.Get("Cache-Control"),
+		Expires:            r.Header.Get("Expires"),
+	}
+
+	size := 0
+	for name, values := range r.Header {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, userMetadataPrefix) || len(values) == 0 {
+			continue
+		}
+		key := strings.TrimPrefix(name, userMetadataPrefix)
+		value := strings.Join(values, ",")
+		size += len(key) + len(value)
+		if metadata.UserMetadata == nil {
+			metadata.UserMetadata = make(map[string]string)
+		}
+		metadata.UserMetadata[key] = value
+	}
+	if size > maxUserMetadataSize {
+		return storage.ObjectMetadata{}, s3err.ErrMetadataTooLarge
+	}
+
+	tags, err := tagsFromRequest(r)
+	if err != nil {
+		return storage.ObjectMetadata{}, err
+	}
+	metadata.Tags = tags
+	return metadata, nil
+}
+
+// tagsFromRequest lit les étiquettes d'un objet dans l'en-tête x-amz-tagging (clé1=valeur1&clé2=valeur2)
+func tagsFromRequest(r *http.Request) (map[string]string, error) {
+	header := r.Header.Get("x-amz-tagging")
+	if header == "" {
+		return nil, nil
+	}
+	values, err := url.ParseQuery(header)
+	if err != nil {
+		return nil, s3err.ErrInvalidArgument.WithMessage("The header 'x-amz-tagging' shall be encoded as UTF-8 then URLEncoded URL query parameters without tag name duplicates.")
+	}
+	if len(values) > maxObjectTags {
+		return nil, s3err.ErrInvalidArgument.WithMessage("Object tags cannot be greater than 10")
+	}
+	tags := make(map[string]string, len(values))
+	for key, value := range values {
+		if len(value) > 1 {
+			return nil, s3err.ErrInvalidArgument.WithMessage("Cannot provide multiple Tags with the same key")
+		}
+		if key == "" || len(key) > maxTagKeyLength {
+			return nil, s3err.ErrInvalidArgument.WithMessage("The TagKey you have provided is invalid")
+		}
+		if len(value[0]) > maxTagValueLength {
+			return nil, s3err.ErrInvalidArgument.WithMessage("The TagValue you have provided is invalid")
+		}
+		tags[key] = value[0]
+	}
+	return tags, nil
+}
+
+// contentEncoding retire aws-chunked, propre au transport du corps signé, du Content-Encoding à conserver
+func contentEncoding(header string) string {
+	var encodings []string
+	for _, encoding := range strings.Split(header, ",") {
+		encoding = strings.TrimSpace(encoding)
+		if encoding != "" && !strings.EqualFold(encoding, "aws-chunked") {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return strings.Join(encodings, ",")
+}
+
+// writeObjectHeaders renvoie les en-têtes d'un objet comme S3 pour GET et HEAD
+func writeObjectHeaders(w http.ResponseWriter, metadata storage.ObjectMetadata) {
+	header := w.Header()
+	contentType := metadata.ContentType
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.FormatInt(metadata.Size, 10))
+	header.Set("ETag", "\""+metadata.ETag+"\"")
+	header.Set("Last-Modified", metadata.LastModified.UTC().Format(http.TimeFormat))
+
+	optional := map[string]string{
+		"Content-Encoding":    metadata.ContentEncoding,
+		"Content-Disposition": metadata.ContentDisposition,
+		"Content-Language":    metadata.ContentLanguage,
+		"Cache-Control":       metadata.CacheControl,
+		"Expires":             metadata.Expires,
+	}
+	for name, value := range optional {
+		if value != "" {
+			header.Set(name, value)
+		}
+	}
+	for key, value := range metadata.UserMetadata {
+		header.Set(userMetadataPrefix+key, value)
+	}
+	if len(metadata.Tags) > 0 {
+		header.Set("x-amz-tagging-count", strconv.Itoa(len(metadata.Tags)))
+	}
+	if metadata.VersionID != "" {
+		header.Set("x-amz-version-id", metadata.VersionID)
+	}
+	writeEncryptionHeaders(header, metadata.Encryption)
+}
@@ -0,0 +1,333 @@
+// internal/handlers/multipart.go
+package handlers
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxCompleteRequestSize borne la taille du corps d'un CompleteMultipartUpload (10 000 parts et leur XML)
+const maxCompleteRequestSize = 4 << 20
+
+// CreateMultipartUploadHandler gère POST /{bucket}/{object}?uploads
+func CreateMultipartUploadHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		bucketName := vars["bucket"]
+		objectName := vars["object"]
+
+		metadata, err := metadataFromRequest(r)
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+		// Les parts d'un upload SSE-S3 sont chiffrées dès leur réception, puis l'objet assemblé avec sa propre clé.
+		// Celles d'un upload SSE-C le sont avec la clé du client, renvoyée avec chaque part (voir UploadPartHandler).
+		encryption, err := encryptionFromRequest(r)
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+		if metadata.Encryption, err = withBucketEncryption(s, bucketName, encryption); err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		uploadID, err := s.CreateMultipartUpload(bucketName, objectName, objectOwner(r, s, bucketName), metadata)
+		if err != nil {
+			log.Printf("Erreur lors de l'initialisation de l'upload multipart %s/%s: %v", bucketName, objectName, err)
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		response := dto.InitiateMultipartUploadResult{
+			XMLNS:    "http://s3.amazonaws.com/doc/2006-03-01/",
+			Bucket:   bucketName,
+			Key:      objectName,
+			UploadID: uploadID,
+		}
+
+		writeEncryptionHeaders(w.Header(), metadata.Encryption)
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(response)
+	}
+}
+
+// UploadPartHandler gère PUT /{bucket}/{object}?partNumber=N&uploadId=X, et UploadPartCopy avec x-amz-copy-source
+func UploadPartHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		bucketName := vars["bucket"]
+		objectName := vars["object"]
+		uploadID := r.URL.Query().Get("uploadId")
+
+		partNumber, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		if err != nil {
+			s3err.WriteError(w, r, storage.ErrInvalidPartNumber)
+			return
+		}
+
+		if r.Header.Get("x-amz-copy-source") != "" {
+			copyPart(w, r, s, bucketName, objectName, uploadID, partNumber)
+			return
+		}
+
+		// Les empreintes annoncées pour la part sont vérifiées, sans être enregistrées
+		body, err := newDigestReader(r)
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+		// Les clients renvoient la clé SSE-C avec chaque part d'un upload SSE-C
+		customerKey, err := customerKeyFromRequest(r, sseCustomerPrefix)
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+		part, err := s.UploadPart(bucketName, objectName, uploadID, partNumber, body, customerKey)
+		if err != nil {
+			log.Printf("Erreur lors de l'envoi de la part %d de l'upload %s: %v", partNumber, uploadID, err)
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		writeCustomerKeyHeaders(w.Header(), customerKey)
+		w.Header().Set("ETag", "\""+part.ETag+"\"")
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// CompleteMultipartUploadHandler gère POST /{bucket}/{object}?uploadId=X
+func CompleteMultipartUploadHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		bucketName := vars["bucket"]
+		objectName := vars["object"]
+		uploadID := r.URL.Query().Get("uploadId")
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxCompleteRequestSize+1))
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+		if len(body) > maxCompleteRequestSize {
+			s3err.Write(w, r, s3err.ErrMalformedXML)
+			return
+		}
+		var request dto.CompleteMultipartUpload
+		if err := xml.Unmarshal(body, &request); err != nil {
+			log.Printf("Corps CompleteMultipartUpload invalide: %v", err)
+			s3err.Write(w, r, s3err.ErrMalformedXML)
+			return
+		}
+
+		parts := make([]storage.CompletePart, 0, len(request.Parts))
+		for _, part := range request.Parts {
+			parts = append(parts, storage.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+		}
+
+		result, err := s.CompleteMultipartUpload(bucketName, objectName, uploadID, parts)
+		if err != nil {
+			log.Printf("Erreur lors de la finalisation de l'upload %s: %v", uploadID, err)
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		response := dto.CompleteMultipartUploadResult{
+			XMLNS:    "http://s3.amazonaws.com/doc/2006-03-01/",
+			Location: "http://" + r.Host + "/" + url.PathEscape(bucketName) + "/" + escapeKeyPath(objectName),
+			Bucket:   bucketName,
+			Key:      objectName,
+			ETag:     "\"" + result.ETag + "\"",
+		}
+
+		if result.VersionID != "" {
+			w.Header().Set("x-amz-version-id", result.VersionID)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(response)
+	}
+}
+
+// AbortMultipartUploadHandler gère DELETE /{bucket}/{object}?uploadId=X
+func AbortMultipartUploadHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		uploadID := r.URL.Query().Get("uploadId")
+
+		if err := s.AbortMultipartUpload(vars["bucket"], vars["object"], uploadID); err != nil {
+			log.Printf("Erreur lors de l'annulation de l'upload %s: %v", uploadID, err)
+			s3err.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListPartsHandler gère GET /{bucket}/{object}?uploadId=X
+func ListPartsHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		bucketName := vars["bucket"]
+		objectName := vars["object"]
+		query := r.URL.Query()
+		uploadID := query.Get("uploadId")
+
+		maxParts, ok := intQueryParam(query.Get("max-parts"), 1000)
+		if !ok {
+			s3err.Write(w, r, s3err.ErrInvalidArgument.WithMessage("Provided max-parts not an integer or within integer range"))
+			return
+		}
+		marker, ok := intQueryParam(query.Get("part-number-marker"), 0)
+		if !ok {
+			s3err.Write(w, r, s3err.ErrInvalidArgument.WithMessage("Provided part-number-marker not an integer or within integer range"))
+			return
+		}
+
+		upload, err := s.GetMultipartUpload(bucketName, objectName, uploadID)
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+		stored, err := s.ListParts(bucketName, objectName, uploadID)
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		response := dto.ListPartsResult{
+			XMLNS:            "http://s3.amazonaws.com/doc/2006-03-01/",
+			Bucket:           bucketName,
+			Key:              objectName,
+			UploadID:         uploadID,
+			Initiator:        uploadInitiator(upload),
+			Owner:            uploadInitiator(upload),
+			StorageClass:     "STANDARD",
+			PartNumberMarker: marker,
+			MaxParts:         maxParts,
+		}
+		for _, part := range stored {
+			if part.PartNumber <= marker {
+				continue
+			}
+			if len(response.Parts) == maxParts {
+				response.IsTruncated = true
+				break
+			}
+			response.Parts = append(response.Parts, dto.Part{
+				PartNumber:   part.PartNumber,
+				LastModified: part.LastModified.UTC().Format(time.RFC3339),
+				ETag:         "\"" + part.ETag + "\"",
+				Size:         part.Size,
+			})
+			response.NextPartNumberMarker = part.PartNumber
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(response)
+	}
+}
+
+// ListMultipartUploadsHandler gère GET /{bucket}?uploads
+func ListMultipartUploadsHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucket"]
+		query := r.URL.Query()
+		prefix := query.Get("prefix")
+		keyMarker := query.Get("key-marker")
+		uploadIDMarker := query.Get("upload-id-marker")
+
+		maxUploads, ok := intQueryParam(query.Get("max-uploads"), 1000)
+		if !ok {
+			s3err.Write(w, r, s3err.ErrInvalidArgument.WithMessage("Provided max-uploads not an integer or within integer range"))
+			return
+		}
+
+		uploads, err := s.ListMultipartUploads(bucketName)
+		if err != nil {
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		response := dto.ListMultipartUploadsResult{
+			XMLNS:          "http://s3.amazonaws.com/doc/2006-03-01/",
+			Bucket:         bucketName,
+			KeyMarker:      keyMarker,
+			UploadIDMarker: uploadIDMarker,
+			Prefix:         prefix,
+			MaxUploads:     maxUploads,
+		}
+
+		// Les uploads sont triés par clé puis par date : on saute tout ce qui précède les marqueurs
+		passedIDMarker := false
+		for _, upload := range uploads {
+			if !strings.HasPrefix(upload.Key, prefix) || upload.Key < keyMarker {
+				continue
+			}
+			if keyMarker != "" && upload.Key == keyMarker {
+				if uploadIDMarker == "" || !passedIDMarker {
+					passedIDMarker = upload.UploadID == uploadIDMarker
+					continue
+				}
+			}
+			if len(response.Uploads) == maxUploads {
+				response.IsTruncated = true
+				break
+			}
+			response.Uploads = append(response.Uploads, dto.Upload{
+				Key:          upload.Key,
+				UploadID:     upload.UploadID,
+				Initiator:    uploadInitiator(upload),
+				Owner:        uploadInitiator(upload),
+				StorageClass: "STANDARD",
+				Initiated:    upload.Initiated.UTC().Format(time.RFC3339),
+			})
+			response.NextKeyMarker = upload.Key
+			response.NextUploadIDMarker = upload.UploadID
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(response)
+	}
+}
+
+// uploadInitiator retourne l'appelant qui a initialisé un upload, tel qu'affiché dans les listings
+// (propriétaire par défaut pour un upload initialisé sans authentification)
+func uploadInitiator(upload storage.MultipartUpload) dto.Owner {
+	if upload.Initiator.ID == "" {
+		return defaultOwner
+	}
+	return dto.Owner{ID: upload.Initiator.ID, DisplayName: upload.Initiator.DisplayName}
+}
+
+// escapeKeyPath encode une clé d'objet pour un chemin d'URL, segment par segment, en conservant les "/"
+func escapeKeyPath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// intQueryParam lit un paramètre entier positif de la query string avec une valeur par défaut
+func intQueryParam(value string, def int) (int, bool) {
+	if value == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
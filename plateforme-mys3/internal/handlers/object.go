@@ -2,122 +2,208 @@
 package handlers
 
 import (
-	"crypto/md5"
-	"encoding/hex"
-	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
-	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
 	"plateforme-mys3/internal/storage"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gorilla/mux"
 )
 
-// ObjectHandler gère les opérations sur les objets (PUT, GET, DELETE)
-func ObjectHandler(s *storage.Storage) http.HandlerFunc {
+// ObjectHandler gère les opérations sur les objets (PUT, GET, HEAD, DELETE), avec ?versionId pour GET, HEAD et DELETE ;
+// un PUT avec x-amz-copy-source est une copie côté serveur (CopyObject)
+func ObjectHandler(s storage.Backend) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		bucketName := vars["bucket"]
 		objectName := vars["object"]
+		versionID := r.URL.Query().Get("versionId")
 
 		switch r.Method {
 		case http.MethodPut:
-			bodyBytes, err := io.ReadAll(r.Body)
+			objectACL, hasACL, err := cannedACLFromRequest(r, objectOwner(r, s, bucketName))
 			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
+				s3err.WriteError(w, r, err)
 				return
 			}
-			r.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
+			if r.Header.Get("x-amz-copy-source") != "" {
+				copyObject(w, r, s, bucketName, objectName, objectACL, hasACL)
+				return
+			}
+			metadata, err := metadataFromRequest(r)
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			encryption, err := encryptionFromRequest(r)
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			if metadata.Encryption, err = withBucketEncryption(s, bucketName, encryption); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			body, err := newDigestReader(r)
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			metadata.ChecksumAlgorithm = body.algorithm
+			if hasACL {
+				metadata.ACL = &objectACL
+			}
 
-			err = s.PutObject(bucketName, objectName, r.Body)
+			// Le corps est transmis en flux au stockage, sans passer par la mémoire ; ses empreintes
+			// sont vérifiées en fin de lecture, avant que l'objet ne soit mis en place
+			result, err := s.PutObject(bucketName, objectName, body, metadata)
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
+				log.Printf("Erreur lors de l'écriture de l'objet %s/%s: %v", bucketName, objectName, err)
+				s3err.WriteError(w, r, err)
 				return
 			}
 
-			// Calculer l'ETag (MD5 du contenu)
-			etag := calculateETag(bodyBytes)
-			w.Header().Set("ETag", "\""+etag+"\"")
+			w.Header().Set("ETag", "\""+result.ETag+"\"")
+			writeEncryptionHeaders(w.Header(), metadata.Encryption)
+			if result.Checksum != "" {
+				w.Header().Set(checksumHeader(metadata.ChecksumAlgorithm), result.Checksum)
+			}
+			if result.VersionID != "" {
+				w.Header().Set("x-amz-version-id", result.VersionID)
+			}
 			w.WriteHeader(http.StatusOK)
 		case http.MethodGet:
-			file, err := s.GetObject(bucketName, objectName)
+			customerKey, err := customerKeyFromRequest(r, sseCustomerPrefix)
 			if err != nil {
-				w.WriteHeader(http.StatusNotFound)
+				s3err.WriteError(w, r, err)
+				return
+			}
+			// Le contenu est déchiffré par le stockage ; un objet SSE-C exige la clé du client
+			file, metadata, err := s.OpenObjectVersion(bucketName, objectName, versionID, customerKey)
+			if err != nil {
+				writeObjectError(w, r, s, bucketName, err)
+				return
+			}
+			if metadata.DeleteMarker {
+				writeDeleteMarker(w, r, metadata, versionID)
 				return
 			}
 			defer file.Close()
-			io.Copy(w, file)
+			serveObject(w, r, file, metadata)
+		case http.MethodHead:
+			customerKey, err := customerKeyFromRequest(r, sseCustomerPrefix)
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			metadata, err := s.GetObjectVersionMetadata(bucketName, objectName, versionID)
+			if err != nil {
+				writeObjectError(w, r, s, bucketName, err)
+				return
+			}
+			if metadata.DeleteMarker {
+				writeDeleteMarker(w, r, metadata, versionID)
+				return
+			}
+			if err := storage.VerifyCustomerKey(metadata, customerKey); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			serveObject(w, r, nil, metadata)
 		case http.MethodDelete:
-			err := s.DeleteObject(bucketName, objectName)
+			result, err := s.DeleteObject(bucketName, objectName, versionID)
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
+				log.Printf("Erreur lors de la suppression de l'objet %s/%s: %v", bucketName, objectName, err)
+				s3err.WriteError(w, r, err)
 				return
 			}
+			if result.VersionID != "" {
+				w.Header().Set("x-amz-version-id", result.VersionID)
+			}
+			if result.DeleteMarker {
+				w.Header().Set("x-amz-delete-marker", "true")
+			}
 			w.WriteHeader(http.StatusNoContent)
 		default:
-			w.WriteHeader(http.StatusMethodNotAllowed)
+			s3err.Write(w, r, s3err.ErrMethodNotAllowed)
 		}
 	}
 }
 
-// ListObjectsHandler gère la liste des objets dans un bucket
-func ListObjectsHandler(s *storage.Storage) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		bucketName := vars["bucket"]
-
-		objectsInfo, err := s.ListObjects(bucketName)
-		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
+// serveObject renvoie un objet, ou seulement ses en-têtes pour HEAD (file nil), en appliquant
+// les en-têtes conditionnels, la plage demandée (Range) et les paramètres response-*
+func serveObject(w http.ResponseWriter, r *http.Request, file io.ReadSeekCloser, metadata storage.ObjectMetadata) {
+	if status, ok := checkPreconditions(r, metadata); !ok {
+		if status == http.StatusPreconditionFailed {
+			s3err.Write(w, r, s3err.ErrPreconditionFailed)
 			return
 		}
+		// Comme S3, une réponse 304 n'a pas de corps mais rappelle l'ETag et la date de modification
+		w.Header().Set("ETag", "\""+metadata.ETag+"\"")
+		w.Header().Set("Last-Modified", metadata.LastModified.UTC().Format(http.TimeFormat))
+		w.WriteHeader(status)
+		return
+	}
 
-		var objects []dto.Object
-		for _, info := range objectsInfo {
-			if info.IsDir() {
-				continue
-			}
-			objects = append(objects, dto.Object{
-				Key:          info.Name(),
-				LastModified: info.ModTime().UTC().Format(time.RFC3339),
-				ETag:         "\"" + calculateETagFromFile(s.ObjectPath(bucketName, info.Name())) + "\"",
-				Size:         info.Size(),
-				StorageClass: "STANDARD",
-			})
-		}
+	writeObjectHeaders(w, metadata)
+	w.Header().Set("Accept-Ranges", "bytes")
+	applyResponseOverrides(w, r)
 
-		response := dto.ListBucketResult{
-			XMLNS:       "http://s3.amazonaws.com/doc/2006-03-01/",
-			Name:        bucketName,
-			Prefix:      "",
-			Marker:      "",
-			MaxKeys:     1000,
-			IsTruncated: false,
-			Contents:    objects,
+	br, hasRange, satisfiable := parseRange(r.Header.Get("Range"), metadata.Size)
+	if hasRange && !satisfiable {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", metadata.Size))
+		s3err.Write(w, r, s3err.ErrInvalidRange)
+		return
+	}
+	if !hasRange {
+		// La somme de contrôle porte sur l'objet entier : elle n'est renvoyée que sans Range, et sur demande
+		if metadata.Checksum != "" && strings.EqualFold(r.Header.Get("x-amz-checksum-mode"), "ENABLED") {
+			w.Header().Set(checksumHeader(metadata.ChecksumAlgorithm), metadata.Checksum)
 		}
+		w.WriteHeader(http.StatusOK)
+		if file != nil {
+			io.Copy(w, file)
+		}
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/xml")
-		xml.NewEncoder(w).Encode(response)
+	w.Header().Set("Content-Range", contentRange(br, metadata.Size))
+	w.Header().Set("Content-Length", strconv.FormatInt(br.length(), 10))
+	w.WriteHeader(http.StatusPartialContent)
+	if file == nil {
+		return
 	}
+	if _, err := file.Seek(br.start, io.SeekStart); err != nil {
+		log.Printf("Erreur lors du positionnement dans l'objet: %v", err)
+		return
+	}
+	io.CopyN(w, file, br.length())
 }
 
-// calculateETag calcule l'ETag (MD5) du contenu des données
-func calculateETag(data []byte) string {
-	hash := md5.Sum(data)
-	return hex.EncodeToString(hash[:])
+// writeDeleteMarker répond à une lecture dont la version est un marqueur de suppression :
+// 404 pour la dernière version d'un objet supprimé, 405 pour un marqueur désigné par son versionId
+func writeDeleteMarker(w http.ResponseWriter, r *http.Request, metadata storage.ObjectMetadata, versionID string) {
+	w.Header().Set("x-amz-delete-marker", "true")
+	w.Header().Set("x-amz-version-id", metadata.VersionID)
+	if versionID == "" {
+		s3err.Write(w, r, s3err.ErrNoSuchKey)
+		return
+	}
+	s3err.Write(w, r, s3err.ErrMethodNotAllowed)
 }
 
-// calculateETagFromFile calcule l'ETag (MD5) à partir du contenu d'un fichier
-func calculateETagFromFile(filePath string) string {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return ""
+// writeObjectError renvoie l'erreur S3 d'une opération sur un objet ; un objet introuvable
+// parce que son bucket n'existe pas donne NoSuchBucket plutôt que NoSuchKey
+func writeObjectError(w http.ResponseWriter, r *http.Request, s storage.Backend, bucketName string, err error) {
+	if errors.Is(err, os.ErrNotExist) && !s.BucketExists(bucketName) {
+		err = storage.ErrNoSuchBucket
 	}
-	defer file.Close()
-	hash := md5.New()
-	io.Copy(hash, file)
-	return hex.EncodeToString(hash.Sum(nil))
+	s3err.WriteError(w, r, err)
 }
@@ -0,0 +1,62 @@
+// internal/handlers/policy.go
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"plateforme-mys3/internal/policy"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// maxPolicySize est la taille maximale d'une politique de bucket acceptée par S3 (20 Ko)
+const maxPolicySize = 20 * 1024
+
+// BucketPolicyHandler gère les opérations sur la politique d'un bucket (?policy : PUT, GET, DELETE)
+func BucketPolicyHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucket"]
+
+		switch r.Method {
+		case http.MethodPut:
+			data, err := io.ReadAll(io.LimitReader(r.Body, maxPolicySize+1))
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			if len(data) > maxPolicySize {
+				s3err.Write(w, r, s3err.ErrMalformedPolicy.WithMessage("Policies must be smaller than 20KB"))
+				return
+			}
+			if _, err := policy.Parse(data, bucketName); err != nil {
+				log.Printf("Politique refusée pour le bucket %s: %v", bucketName, err)
+				s3err.WriteError(w, r, err)
+				return
+			}
+			if err := s.PutBucketPolicy(bucketName, data); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			data, err := s.GetBucketPolicy(bucketName)
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+		case http.MethodDelete:
+			if err := s.DeleteBucketPolicy(bucketName); err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			s3err.Write(w, r, s3err.ErrMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// internal/handlers/range.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"plateforme-mys3/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// responseOverrides associe les paramètres response-* de GET aux en-têtes de réponse qu'ils remplacent
+var responseOverrides = map[string]string{
+	"response-content-type":        "Content-Type",
+	"response-content-language":    "Content-Language",
+	"response-expires":             "Expires",
+	"response-cache-control":       "Cache-Control",
+	"response-content-disposition": "Content-Disposition",
+	"response-content-encoding":    "Content-Encoding",
+}
+
+// byteRange est une plage d'octets inclusive [start, end] d'un objet
+type byteRange struct {
+	start, end int64
+}
+
+// length retourne le nombre d'octets de la plage
+func (br byteRange) length() int64 {
+	return br.end - br.start + 1
+}
+
+// checkPreconditions évalue les en-têtes conditionnels d'une lecture comme S3 (RFC 7232) et retourne
+// le code à renvoyer à la place de l'objet : 412 (If-Match, If-Unmodified-Since) ou 304 (If-None-Match, If-Modified-Since).
+// If-Unmodified-Since n'est évalué qu'en l'absence de If-Match, If-Modified-Since qu'en l'absence de If-None-Match.
+func checkPreconditions(r *http.Request, metadata storage.ObjectMetadata) (int, bool) {
+	lastModified := metadata.LastModified.UTC().Truncate(time.Second)
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !etagMatches(ifMatch, metadata.ETag) {
+			return http.StatusPreconditionFailed, false
+		}
+	} else if since, ok := headerTime(r, "If-Unmodified-Since"); ok && lastModified.After(since) {
+		return http.StatusPreconditionFailed, false
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etagMatches(ifNoneMatch, metadata.ETag) {
+			return http.StatusNotModified, false
+		}
+	} else if since, ok := headerTime(r, "If-Modified-Since"); ok && !lastModified.After(since) {
+		return http.StatusNotModified, false
+	}
+	return http.StatusOK, true
+}
+
+// etagMatches indique si une liste d'ETags (If-Match, If-None-Match) contient l'ETag de l'objet ou "*"
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || strings.Trim(candidate, "\"") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// headerTime lit un en-tête de date HTTP ; une date invalide est ignorée, comme sur S3
+func headerTime(r *http.Request, name string) (time.Time, bool) {
+	value := r.Header.Get(name)
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// parseRange interprète un en-tête Range d'une seule plage (bytes=a-b, bytes=a- ou bytes=-n).
+// ok vaut false si l'en-tête est absent ou ignoré (syntaxe invalide, plusieurs plages) : l'objet entier est alors renvoyé.
+// satisfiable vaut false si la plage est hors de l'objet (416).
+func parseRange(header string, size int64) (br byteRange, ok, satisfiable bool) {
+	spec := strings.TrimSpace(header)
+	if !strings.HasPrefix(spec, "bytes=") {
+		return br, false, false
+	}
+	spec = strings.TrimSpace(strings.TrimPrefix(spec, "bytes="))
+	if strings.Contains(spec, ",") {
+		return br, false, false
+	}
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return br, false, false
+	}
+	first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+
+	if first == "" {
+		// Plage suffixe : les n derniers octets
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return br, false, false
+		}
+		if n == 0 || size == 0 {
+			return br, true, false
+		}
+		if n > size {
+			n = size
+		}
+		return byteRange{start: size - n, end: size - 1}, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return br, false, false
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return br, false, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return br, true, false
+	}
+	return byteRange{start: start, end: end}, true, true
+}
+
+// applyResponseOverrides remplace les en-têtes de la réponse par les paramètres response-* de la requête
+func applyResponseOverrides(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	for param, header := range responseOverrides {
+		if value := query.Get(param); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+}
+
+// contentRange formate l'en-tête Content-Range d'une réponse partielle
+func contentRange(br byteRange, size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", br.start, br.end, size)
+}
@@ -0,0 +1,89 @@
+// internal/handlers/sse.go
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+)
+
+const (
+	// sseHeader demande le chiffrement d'un objet avec la clé maître du serveur (SSE-S3)
+	sseHeader = "x-amz-server-side-encryption"
+	// sseCustomerPrefix préfixe les en-têtes SSE-C d'un objet lu ou écrit
+	sseCustomerPrefix = "x-amz-server-side-encryption-customer-"
+	// sseCopySourceCustomerPrefix préfixe les en-têtes SSE-C de la source d'une copie
+	sseCopySourceCustomerPrefix = "x-amz-copy-source-server-side-encryption-customer-"
+)
+
+// customerKeyFromRequest lit une clé SSE-C (en-têtes <prefix>algorithm, key et key-MD5) ; nil si aucun n'est présent.
+// Les trois en-têtes sont obligatoires, l'algorithme doit être AES256 et le MD5 doit correspondre à la clé.
+func customerKeyFromRequest(r *http.Request, prefix string) ([]byte, error) {
+	algorithm := r.Header.Get(prefix + "algorithm")
+	encodedKey := r.Header.Get(prefix + "key")
+	keyMD5 := r.Header.Get(prefix + "key-MD5")
+	if algorithm == "" && encodedKey == "" && keyMD5 == "" {
+		return nil, nil
+	}
+	if algorithm == "" {
+		return nil, s3err.ErrInvalidArgument.WithMessage("Requests specifying Server Side Encryption with Customer provided keys must provide a valid encryption algorithm.")
+	}
+	if algorithm != storage.SSEAlgorithm {
+		return nil, s3err.ErrInvalidArgument.WithMessage("The encryption algorithm specified is not valid.")
+	}
+	if encodedKey == "" {
+		return nil, s3err.ErrInvalidArgument.WithMessage("Requests specifying Server Side Encryption with Customer provided keys must provide an appropriate secret key.")
+	}
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != storage.SSEKeySize {
+		return nil, s3err.ErrInvalidArgument.WithMessage("The secret key was invalid for the specified algorithm.")
+	}
+	if keyMD5 == "" {
+		return nil, s3err.ErrInvalidArgument.WithMessage("Requests specifying Server Side Encryption with Customer provided keys must provide the client calculated MD5 of the secret key.")
+	}
+	if keyMD5 != storage.CustomerKeyMD5(key) {
+		return nil, s3err.ErrInvalidArgument.WithMessage("The calculated MD5 hash of the key did not match the hash that was provided.")
+	}
+	return key, nil
+}
+
+// encryptionFromRequest lit le chiffrement demandé pour un objet écrit : SSE-S3 (x-amz-server-side-encryption: AES256)
+// ou SSE-C. Retourne nil si l'objet doit être stocké en clair.
+func encryptionFromRequest(r *http.Request) (*storage.EncryptionInfo, error) {
+	customerKey, err := customerKeyFromRequest(r, sseCustomerPrefix)
+	if err != nil {
+		return nil, err
+	}
+	sse := r.Header.Get(sseHeader)
+	switch {
+	case sse != "" && customerKey != nil:
+		return nil, s3err.ErrInvalidArgument.WithMessage("Server Side Encryption with Customer provided key is incompatible with the encryption method specified")
+	case customerKey != nil:
+		return &storage.EncryptionInfo{CustomerKey: customerKey, CustomerKeyMD5: storage.CustomerKeyMD5(customerKey)}, nil
+	case sse == storage.SSEAlgorithm:
+		return &storage.EncryptionInfo{}, nil
+	case sse != "":
+		return nil, s3err.ErrInvalidArgument.WithMessage("The encryption method specified is not supported")
+	}
+	return nil, nil
+}
+
+// writeEncryptionHeaders renvoie les en-têtes décrivant le chiffrement d'un objet (aucun s'il est en clair)
+func writeEncryptionHeaders(header http.Header, info *storage.EncryptionInfo) {
+	switch {
+	case info == nil:
+	case info.CustomerProvided():
+		header.Set(sseCustomerPrefix+"algorithm", storage.SSEAlgorithm)
+		header.Set(sseCustomerPrefix+"key-MD5", info.CustomerKeyMD5)
+	default:
+		header.Set(sseHeader, storage.SSEAlgorithm)
+	}
+}
+
+// writeCustomerKeyHeaders renvoie les en-têtes SSE-C d'une requête accompagnée d'une clé du client (nil si aucune)
+func writeCustomerKeyHeaders(header http.Header, customerKey []byte) {
+	if customerKey != nil {
+		writeEncryptionHeaders(header, &storage.EncryptionInfo{CustomerKeyMD5: storage.CustomerKeyMD5(customerKey)})
+	}
+}
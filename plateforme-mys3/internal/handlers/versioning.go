@@ -0,0 +1,154 @@
+// internal/handlers/versioning.go
+package handlers
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxVersioningConfigSize borne la taille d'une configuration de versionnage acceptée
+const maxVersioningConfigSize = 4 * 1024
+
+// BucketVersioningHandler gère la configuration de versionnage d'un bucket (?versioning : PUT, GET)
+func BucketVersioningHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucket"]
+
+		switch r.Method {
+		case http.MethodPut:
+			data, err := io.ReadAll(io.LimitReader(r.Body, maxVersioningConfigSize+1))
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			var config dto.VersioningConfiguration
+			if len(data) > maxVersioningConfigSize || xml.Unmarshal(data, &config) != nil {
+				s3err.Write(w, r, s3err.ErrMalformedXML)
+				return
+			}
+			if err := s.PutBucketVersioning(bucketName, config.Status); err != nil {
+				log.Printf("Configuration de versionnage refusée pour le bucket %s: %v", bucketName, err)
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			status, err := s.GetBucketVersioning(bucketName)
+			if err != nil {
+				s3err.WriteError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			xml.NewEncoder(w).Encode(dto.VersioningConfiguration{
+				XMLNS:  "http://s3.amazonaws.com/doc/2006-03-01/",
+				Status: status,
+			})
+		default:
+			s3err.Write(w, r, s3err.ErrMethodNotAllowed)
+		}
+	}
+}
+
+// ListObjectVersionsHandler gère le listing des versions des objets d'un bucket (GET ?versions)
+func ListObjectVersionsHandler(s storage.Backend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucket"]
+		query := r.URL.Query()
+
+		maxKeys, ok := intQueryParam(query.Get("max-keys"), maxListKeys)
+		if !ok {
+			s3err.Write(w, r, errInvalidMaxKeys)
+			return
+		}
+		if maxKeys > maxListKeys {
+			maxKeys = maxListKeys
+		}
+		encodingType := query.Get("encoding-type")
+		if encodingType != "" && encodingType != "url" {
+			s3err.Write(w, r, errInvalidEncodingType)
+			return
+		}
+		keyMarker := query.Get("key-marker")
+		versionIDMarker := query.Get("version-id-marker")
+		// Comme S3, un version-id-marker n'a de sens qu'accompagné d'un key-marker
+		if versionIDMarker != "" && keyMarker == "" {
+			s3err.Write(w, r, s3err.ErrInvalidArgument.WithMessage("A version-id marker cannot be specified without a key marker."))
+			return
+		}
+
+		opts := storage.ListObjectsOptions{
+			Prefix:     query.Get("prefix"),
+			Delimiter:  query.Get("delimiter"),
+			StartAfter: keyMarker,
+			MaxKeys:    maxKeys,
+		}
+		result, err := s.ListObjectVersions(bucketName, opts, versionIDMarker)
+		if err != nil {
+			log.Printf("Erreur lors du listing des versions du bucket %s: %v", bucketName, err)
+			s3err.WriteError(w, r, err)
+			return
+		}
+
+		encode := func(value string) string { return value }
+		if encodingType == "url" {
+			encode = encodeListValue
+		}
+		var owner *dto.Owner
+		if bucketACL, err := s.GetBucketACL(bucketName); err == nil {
+			owner = &dto.Owner{ID: bucketACL.Owner.ID, DisplayName: bucketACL.Owner.DisplayName}
+		}
+
+		response := dto.ListVersionsResult{
+			XMLNS:           "http://s3.amazonaws.com/doc/2006-03-01/",
+			Name:            bucketName,
+			Prefix:          encode(opts.Prefix),
+			KeyMarker:       encode(keyMarker),
+			VersionIdMarker: versionIDMarker,
+			Delimiter:       encode(opts.Delimiter),
+			MaxKeys:         maxKeys,
+			EncodingType:    encodingType,
+			IsTruncated:     result.IsTruncated,
+		}
+		if result.IsTruncated {
+			response.NextKeyMarker = encode(result.NextKeyMarker)
+			response.NextVersionIdMarker = result.NextVersionIDMarker
+		}
+		for _, version := range result.Versions {
+			lastModified := version.Metadata.LastModified.UTC().Format(time.RFC3339)
+			if version.Metadata.DeleteMarker {
+				response.DeleteMarkers = append(response.DeleteMarkers, dto.DeleteMarkerEntry{
+					Key:          encode(version.Key),
+					VersionId:    version.Metadata.VersionID,
+					IsLatest:     version.IsLatest,
+					LastModified: lastModified,
+					Owner:        owner,
+				})
+				continue
+			}
+			response.Versions = append(response.Versions, dto.ObjectVersion{
+				Key:          encode(version.Key),
+				VersionId:    version.Metadata.VersionID,
+				IsLatest:     version.IsLatest,
+				LastModified: lastModified,
+				ETag:         "\"" + version.Metadata.ETag + "\"",
+				Size:         version.Metadata.Size,
+				Owner:        owner,
+				StorageClass: "STANDARD",
+			})
+		}
+		for _, prefix := range result.CommonPrefixes {
+			response.CommonPrefixes = append(response.CommonPrefixes, dto.CommonPrefix{Prefix: encode(prefix)})
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(response)
+	}
+}
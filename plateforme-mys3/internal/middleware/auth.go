@@ -6,25 +6,52 @@ import (
 	"net/http"
 	"plateforme-mys3/config"
 	"plateforme-mys3/internal/auth"
-	// "plateforme-mys3/internal/auth" // Commenté pour désactiver l'authentification
+	"plateforme-mys3/internal/s3err"
+	"plateforme-mys3/internal/storage"
 )
 
-// AuthMiddleware applique l'authentification AWS SigV4 à tous les handlers
-func AuthMiddleware(next http.Handler, cfg config.Config) http.Handler {
+// AuthMiddleware applique l'authentification AWS SigV4 (en-tête Authorization ou URL présignée) à tous les handlers.
+// L'identité de l'appelant est attachée au contexte de la requête (voir auth.IdentityFromContext),
+// puis la politique et les ACL du bucket ciblé sont appliquées (clé par clé pour une suppression multiple,
+// via auth.Authorize). Une requête sans signature est traitée
+// comme anonyme : elle n'aboutit que si une ACL ou la politique l'autorise.
+func AuthMiddleware(next http.Handler, cfg config.Config, store auth.CredentialStore, s storage.Backend) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Commenter la vérification de la signature pour désactiver l'authentification
-
 		log.Printf("Requête reçue: %s %s", r.Method, r.URL.Path)
-		if !auth.VerifyAWSSignature(r, cfg) {
-			log.Println("Vérification de la signature échouée")
-			w.Header().Set("Content-Type", "application/xml")
-			w.WriteHeader(http.StatusForbidden)
-			w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
-			return
+		w.Header().Set(s3err.RequestIDHeader, s3err.NewRequestID())
+
+		var identity auth.Identity
+		if auth.IsPresignedRequest(r) {
+			var err error
+			identity, err = auth.VerifyPresignedURL(r, cfg, store)
+			if err != nil {
+				log.Printf("Vérification de l'URL présignée échouée: %v", err)
+				s3err.WriteError(w, r, err)
+				return
+			}
+		} else if r.Header.Get("Authorization") == "" {
+			log.Printf("Requête anonyme: %s %s", r.Method, r.URL.Path)
+		} else {
+			var err error
+			identity, err = auth.VerifyAWSSignature(r, cfg, store)
+			if err != nil {
+				log.Printf("Vérification de la signature échouée: %v", err)
+				s3err.WriteError(w, r, err)
+				return
+			}
 		}
 
-		// Log pour vérifier que le middleware est appelé
-		log.Printf("Requête reçue (sans authentification): %s %s", r.Method, r.URL.Path)
-		next.ServeHTTP(w, r)
+		if !authorizeRequest(r, identity, s) {
+			s3err.Write(w, r, s3err.ErrAccessDenied)
+			return
+		}
+		ctx := auth.WithAuthorizer(r.Context(), func(bucketName, objectName, action string) bool {
+			return authorizeAction(r, identity, s, bucketName, objectName, action)
+		})
+		if identity.UserID != "" {
+			log.Printf("Requête authentifiée: %s %s (utilisateur %s)", r.Method, r.URL.Path, identity.UserID)
+			ctx = auth.WithIdentity(ctx, identity)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,292 @@
+// internal/middleware/authorize.go
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"plateforme-mys3/internal/acl"
+	"plateforme-mys3/internal/auth"
+	"plateforme-mys3/internal/policy"
+	"plateforme-mys3/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subresourceAction associe un sous-ressource de la query string (?policy, ?uploads...) aux actions S3 par méthode
+type subresourceAction struct {
+	param   string
+	actions map[string]string
+}
+
+// bucketSubresources liste les sous-ressources d'un bucket, par ordre de priorité
+var bucketSubresources = []subresourceAction{
+	{"policy", map[string]string{
+		http.MethodGet:    "s3:GetBucketPolicy",
+		http.MethodPut:    "s3:PutBucketPolicy",
+		http.MethodDelete: "s3:DeleteBucketPolicy",
+	}},
+	{"acl", map[string]string{
+		http.MethodGet: "s3:GetBucketAcl",
+		http.MethodPut: "s3:PutBucketAcl",
+	}},
+	{"uploads", map[string]string{
+		http.MethodGet: "s3:ListBucketMultipartUploads",
+	}},
+	{"versioning", map[string]string{
+		http.MethodGet: "s3:GetBucketVersioning",
+		http.MethodPut: "s3:PutBucketVersioning",
+	}},
+	{"versions", map[string]string{
+		http.MethodGet: "s3:ListBucketVersions",
+	}},
+	// Comme sur S3, la suppression du chiffrement par défaut relève de s3:PutEncryptionConfiguration
+	{"encryption", map[string]string{
+		http.MethodGet:    "s3:GetEncryptionConfiguration",
+		http.MethodPut:    "s3:PutEncryptionConfiguration",
+		http.MethodDelete: "s3:PutEncryptionConfiguration",
+	}},
+	{"lifecycle", map[string]string{
+		http.MethodGet:    "s3:GetLifecycleConfiguration",
+		http.MethodPut:    "s3:PutLifecycleConfiguration",
+		http.MethodDelete: "s3:PutLifecycleConfiguration",
+	}},
+	// Suppression multiple : chaque clé est autorisée par le handler (voir auth.Authorize)
+	{"delete", map[string]string{
+		http.MethodPost: "s3:DeleteObject",
+	}},
+}
+
+// objectSubresources liste les sous-ressources d'un objet, par ordre de priorité
+var objectSubresources = []subresourceAction{
+	{"acl", map[string]string{
+		http.MethodGet: "s3:GetObjectAcl",
+		http.MethodPut: "s3:PutObjectAcl",
+	}},
+	{"uploads", map[string]string{
+		http.MethodPost: "s3:PutObject",
+	}},
+	{"uploadId", map[string]string{
+		http.MethodGet:    "s3:ListMultipartUploadParts",
+		http.MethodPut:    "s3:PutObject",
+		http.MethodPost:   "s3:PutObject",
+		http.MethodDelete: "s3:AbortMultipartUpload",
+	}},
+	{"versionId", map[string]string{
+		http.MethodGet:    "s3:GetObjectVersion",
+		http.MethodHead:   "s3:GetObjectVersion",
+		http.MethodPut:    "s3:PutObject",
+		http.MethodDelete: "s3:DeleteObjectVersion",
+	}},
+}
+
+// bucketActions et objectActions donnent l'action S3 des requêtes sans sous-ressource
+var bucketActions = map[string]string{
+	http.MethodGet:    "s3:ListBucket",
+	http.MethodHead:   "s3:ListBucket",
+	http.MethodPut:    "s3:CreateBucket",
+	http.MethodDelete: "s3:DeleteBucket",
+}
+
+var objectActions = map[string]string{
+	http.MethodGet:    "s3:GetObject",
+	http.MethodHead:   "s3:GetObject",
+	http.MethodPut:    "s3:PutObject",
+	http.MethodDelete: "s3:DeleteObject",
+}
+
+// aclPermission est la permission d'ACL qui autorise une action, portée par l'ACL du bucket ou de l'objet
+type aclPermission struct {
+	permission string
+	onObject   bool
+}
+
+var aclPermissions = map[string]aclPermission{
+	"s3:ListBucket":                 {acl.PermissionRead, false},
+	"s3:ListBucketMultipartUploads": {acl.PermissionRead, false},
+	"s3:ListBucketVersions":         {acl.PermissionRead, false},
+	"s3:PutObject":                  {acl.PermissionWrite, false},
+	"s3:DeleteObject":               {acl.PermissionWrite, false},
+	"s3:DeleteObjectVersion":        {acl.PermissionWrite, false},
+	"s3:AbortMultipartUpload":       {acl.PermissionWrite, false},
+	"s3:ListMultipartUploadParts":   {acl.PermissionWrite, false},
+	"s3:GetBucketAcl":               {acl.PermissionReadACP, false},
+	"s3:PutBucketAcl":               {acl.PermissionWriteACP, false},
+	"s3:GetObject":                  {acl.PermissionRead, true},
+	"s3:GetObjectVersion":           {acl.PermissionRead, true},
+	"s3:GetObjectAcl":               {acl.PermissionReadACP, true},
+	"s3:PutObjectAcl":               {acl.PermissionWriteACP, true},
+}
+
+// resolveAction retrouve le bucket, la clé et l'action S3 d'une requête (adressage par chemin)
+func resolveAction(r *http.Request) (bucketName, objectName, action string) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path == "" {
+		return "", "", "s3:ListAllMyBuckets"
+	}
+	bucketName = path
+	if i := strings.Index(path, "/"); i >= 0 {
+		bucketName, objectName = path[:i], path[i+1:]
+	}
+
+	subresources, defaults := bucketSubresources, bucketActions
+	if objectName != "" {
+		subresources, defaults = objectSubresources, objectActions
+	}
+	query := r.URL.Query()
+	for _, sub := range subresources {
+		if _, ok := query[sub.param]; ok {
+			return bucketName, objectName, sub.actions[r.Method]
+		}
+	}
+	return bucketName, objectName, defaults[r.Method]
+}
+
+// resourceARN retourne l'ARN S3 d'un bucket ou d'un objet
+func resourceARN(bucketName, objectName string) string {
+	if objectName == "" {
+		return "arn:aws:s3:::" + bucketName
+	}
+	return "arn:aws:s3:::" + bucketName + "/" + objectName
+}
+
+// authorizeRequest applique la politique et les ACL du bucket ciblé : un Deny explicite l'emporte toujours,
+// le propriétaire du bucket a tous les droits, les autres appelants (y compris anonymes)
+// doivent être autorisés par un Allow de la politique ou par une ACL
+func authorizeRequest(r *http.Request, identity auth.Identity, s storage.Backend) bool {
+	bucketName, objectName, action := resolveAction(r)
+	if bucketName == "" || action == "s3:CreateBucket" {
+		// Liste des buckets (filtrée par propriétaire) ou création : réservées aux utilisateurs authentifiés
+		return identity.UserID != ""
+	}
+	if action == "" {
+		// Sous-ressource ou méthode sans action S3 (ex. : DELETE ?acl) : aucune règle ne peut l'autoriser
+		log.Printf("Requête sans action S3 refusée: %s %s", r.Method, r.URL.String())
+		return false
+	}
+	if objectName == "" && action == "s3:DeleteObject" {
+		// Suppression multiple : les clés sont autorisées une à une par le handler
+		return true
+	}
+	return authorizeAction(r, identity, s, bucketName, objectName, action)
+}
+
+// authorizeAction décide si l'appelant peut effectuer une action sur un bucket ou un objet (voir authorizeRequest)
+func authorizeAction(r *http.Request, identity auth.Identity, s storage.Backend, bucketName, objectName, action string) bool {
+	anonymous := identity.UserID == ""
+
+	owner, err := s.BucketOwner(bucketName)
+	if errors.Is(err, storage.ErrNoSuchBucket) {
+		// Le handler répondra que le bucket n'existe pas, mais seulement à un appelant authentifié :
+		// un anonyme ne peut ni sonder les noms de buckets, ni faire recevoir un corps pour un bucket absent
+		if anonymous {
+			log.Printf("Requête anonyme refusée sur le bucket inexistant %s", bucketName)
+		}
+		return !anonymous
+	}
+	if err != nil {
+		log.Printf("Erreur lors de la lecture du propriétaire du bucket %s: %v", bucketName, err)
+		return false
+	}
+
+	decision := policy.NotApplicable
+	if document, err := s.GetBucketPolicy(bucketName); err == nil {
+		bucketPolicy, err := policy.Parse(document, bucketName)
+		if err != nil {
+			log.Printf("Politique illisible pour le bucket %s: %v", bucketName, err)
+		} else {
+			decision = bucketPolicy.Evaluate(policy.Request{
+				Action:    action,
+				Resource:  resourceARN(bucketName, objectName),
+				Principal: principalOf(identity),
+				Context:   conditionContext(r, identity),
+			})
+		}
+	}
+
+	switch {
+	case decision == policy.Deny:
+		log.Printf("Accès refusé par la politique du bucket %s: %s sur %s", bucketName, action, resourceARN(bucketName, objectName))
+		return false
+	// Un bucket sans propriétaire enregistré n'appartient à personne : seules sa politique et ses ACL y donnent accès
+	case !anonymous && owner == identity.UserID:
+		return true
+	case decision == policy.Allow:
+		return true
+	default:
+		return aclAllows(s, bucketName, objectName, action, identity.UserID)
+	}
+}
+
+// aclAllows indique si l'ACL du bucket ou de l'objet accorde l'action à l'appelant
+func aclAllows(s storage.Backend, bucketName, objectName, action, userID string) bool {
+	required, ok := aclPermissions[action]
+	if !ok {
+		return false
+	}
+	var (
+		resourceACL acl.ACL
+		err         error
+	)
+	if required.onObject {
+		resourceACL, err = s.GetObjectACL(bucketName, objectName)
+	} else {
+		resourceACL, err = s.GetBucketACL(bucketName)
+	}
+	if err != nil {
+		log.Printf("Erreur lors de la lecture de l'ACL de %s: %v", resourceARN(bucketName, objectName), err)
+		return false
+	}
+	return resourceACL.Allows(userID, required.permission)
+}
+
+// principalOf retourne les identifiants sous lesquels l'appelant peut être désigné dans une politique
+func principalOf(identity auth.Identity) policy.Principal {
+	if identity.UserID == "" {
+		return policy.Principal{}
+	}
+	return policy.Principal{AWS: policy.StringList{
+		identity.UserID,
+		identity.AccessKeyID,
+		"arn:aws:iam::" + identity.UserID + ":root",
+	}}
+}
+
+// conditionContext construit les clés de condition disponibles pour une requête
+func conditionContext(r *http.Request, identity auth.Identity) map[string][]string {
+	now := time.Now().UTC()
+	context := map[string][]string{
+		"aws:securetransport": {strconv.FormatBool(r.TLS != nil)},
+		"aws:currenttime":     {now.Format(time.RFC3339)},
+		"aws:epochtime":       {strconv.FormatInt(now.Unix(), 10)},
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		context["aws:sourceip"] = []string{host}
+	}
+	if ua := r.UserAgent(); ua != "" {
+		context["aws:useragent"] = []string{ua}
+	}
+	if referer := r.Referer(); referer != "" {
+		context["aws:referer"] = []string{referer}
+	}
+	if identity.UserID != "" {
+		context["aws:userid"] = []string{identity.UserID}
+		context["aws:username"] = []string{identity.DisplayName}
+	}
+
+	query := r.URL.Query()
+	for _, key := range []string{"prefix", "delimiter", "max-keys", "versionId"} {
+		if values, ok := query[key]; ok {
+			context["s3:"+strings.ToLower(key)] = values
+		}
+	}
+	for name, values := range r.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") {
+			context["s3:"+name] = values
+		}
+	}
+	return context
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"plateforme-mys3/internal/auth"
+	"plateforme-mys3/internal/storage"
+	"testing"
+)
+
+// Test de la résolution de l'action S3 d'une requête
+func TestResolveAction(t *testing.T) {
+	tests := []struct {
+		method, target             string
+		bucket, object, wantAction string
+	}{
+		{http.MethodGet, "/", "", "", "s3:ListAllMyBuckets"},
+		{http.MethodGet, "/bucket", "bucket", "", "s3:ListBucket"},
+		{http.MethodPut, "/bucket?policy", "bucket", "", "s3:PutBucketPolicy"},
+		{http.MethodGet, "/bucket/?uploads", "bucket", "", "s3:ListBucketMultipartUploads"},
+		{http.MethodGet, "/bucket/dir/key.txt", "bucket", "dir/key.txt", "s3:GetObject"},
+		{http.MethodPost, "/bucket/key?uploads", "bucket", "key", "s3:PutObject"},
+		{http.MethodDelete, "/bucket/key?uploadId=abc", "bucket", "key", "s3:AbortMultipartUpload"},
+		{http.MethodPut, "/bucket?versioning", "bucket", "", "s3:PutBucketVersioning"},
+		{http.MethodGet, "/bucket?versions", "bucket", "", "s3:ListBucketVersions"},
+		{http.MethodDelete, "/bucket/key?versionId=abc", "bucket", "key", "s3:DeleteObjectVersion"},
+		{http.MethodPost, "/bucket?delete", "bucket", "", "s3:DeleteObject"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.target, nil)
+		bucketName, objectName, action := resolveAction(r)
+		if bucketName != tt.bucket || objectName != tt.object || action != tt.wantAction {
+			t.Errorf("%s %s: got (%q, %q, %q)", tt.method, tt.target, bucketName, objectName, action)
+		}
+	}
+}
+
+// Test de l'application de la politique d'un bucket au propriétaire et aux autres utilisateurs
+func TestAuthorizeRequest(t *testing.T) {
+	s := storage.NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	alice := auth.Identity{AccessKeyID: "AKALICE", UserID: "alice"}
+	bob := auth.Identity{AccessKeyID: "AKBOB", UserID: "bob"}
+
+	// Sans politique : seul le propriétaire accède au bucket
+	r := httptest.NewRequest(http.MethodGet, "/bucket/key.txt", nil)
+	if !authorizeRequest(r, alice, s) {
+		t.Error("Expected the owner to be allowed")
+	}
+	if authorizeRequest(r, bob, s) {
+		t.Error("Expected another user to be denied without a policy")
+	}
+
+	policy := `{
+		"Statement": [
+			{"Effect": "Allow", "Principal": {"AWS": "bob"}, "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"},
+			{"Effect": "Deny", "Principal": "*", "Action": "s3:DeleteObject", "Resource": "arn:aws:s3:::bucket/*"}
+		]
+	}`
+	if err := s.PutBucketPolicy("bucket", []byte(policy)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !authorizeRequest(r, bob, s) {
+		t.Error("Expected the policy to allow bob to read")
+	}
+	put := httptest.NewRequest(http.MethodPut, "/bucket/key.txt", nil)
+	if authorizeRequest(put, bob, s) {
+		t.Error("Expected bob to be denied writes")
+	}
+	del := httptest.NewRequest(http.MethodDelete, "/bucket/key.txt", nil)
+	if authorizeRequest(del, alice, s) {
+		t.Error("Expected an explicit Deny to apply to the owner")
+	}
+
+	// Suppression multiple : la requête passe, chaque clé est autorisée séparément
+	batch := httptest.NewRequest(http.MethodPost, "/bucket?delete", nil)
+	if !authorizeRequest(batch, bob, s) {
+		t.Error("Expected multi-object deletes to reach the handler")
+	}
+	if authorizeAction(batch, alice, s, "bucket", "key.txt", "s3:DeleteObject") {
+		t.Error("Expected the Deny to apply to each key of a multi-object delete")
+	}
+
+	// Bucket inexistant : la décision revient au handler
+	missing := httptest.NewRequest(http.MethodGet, "/missing/key.txt", nil)
+	if !authorizeRequest(missing, bob, s) {
+		t.Error("Expected requests on missing buckets to reach the handler")
+	}
+	if authorizeRequest(httptest.NewRequest(http.MethodPut, "/missing/key.txt", nil), auth.Identity{}, s) {
+		t.Error("Expected anonymous requests on missing buckets to be denied")
+	}
+}
+
+// Test du refus des couples sous-ressource / méthode sans action S3, même pour le propriétaire
+func TestAuthorizeUnmappedSubresource(t *testing.T) {
+	s := storage.NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	alice := auth.Identity{AccessKeyID: "AKALICE", UserID: "alice"}
+	bob := auth.Identity{AccessKeyID: "AKBOB", UserID: "bob"}
+
+	for _, tt := range []struct{ method, target string }{
+		{http.MethodDelete, "/bucket/key?acl"},
+		{http.MethodGet, "/bucket/key?uploads"},
+		{http.MethodPut, "/bucket/key?uploads"},
+		{http.MethodHead, "/bucket/key?uploadId=abc"},
+		{http.MethodDelete, "/bucket?versions"},
+		{http.MethodDelete, "/bucket?acl"},
+		{http.MethodPut, "/bucket?uploads"},
+		{http.MethodGet, "/bucket?delete"},
+	} {
+		r := httptest.NewRequest(tt.method, tt.target, nil)
+		if authorizeRequest(r, bob, s) {
+			t.Errorf("%s %s: expected another user to be denied", tt.method, tt.target)
+		}
+		if authorizeRequest(r, alice, s) {
+			t.Errorf("%s %s: expected the owner to be denied", tt.method, tt.target)
+		}
+	}
+
+	// La liste des buckets et la création restent ouvertes aux utilisateurs authentifiés
+	if !authorizeRequest(httptest.NewRequest(http.MethodGet, "/", nil), bob, s) {
+		t.Error("Expected authenticated users to list their buckets")
+	}
+	if !authorizeRequest(httptest.NewRequest(http.MethodPut, "/other", nil), bob, s) {
+		t.Error("Expected authenticated users to create buckets")
+	}
+}
+
+// Test d'un bucket sans propriétaire enregistré : aucun utilisateur n'y a accès sans politique ni ACL
+func TestAuthorizeOwnerlessBucket(t *testing.T) {
+	s := storage.NewStorage(t.TempDir())
+	if err := os.Mkdir(s.BucketPath("legacy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	bob := auth.Identity{AccessKeyID: "AKBOB", UserID: "bob"}
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		if authorizeRequest(httptest.NewRequest(method, "/legacy/key", nil), bob, s) {
+			t.Errorf("%s: expected an ownerless bucket to be closed", method)
+		}
+	}
+	if authorizeRequest(httptest.NewRequest(http.MethodDelete, "/legacy", nil), bob, s) {
+		t.Error("Expected an ownerless bucket not to be deletable")
+	}
+
+	// Une fois migré, le bucket appartient à l'administrateur désigné
+	if _, err := s.AssignLegacyOwners("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if !authorizeRequest(httptest.NewRequest(http.MethodGet, "/legacy/key", nil), bob, s) {
+		t.Error("Expected the assigned owner to be allowed")
+	}
+}
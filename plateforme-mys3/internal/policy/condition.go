@@ -0,0 +1,143 @@
+// internal/policy/condition.go
+package policy
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// operator compare les valeurs de la requête aux valeurs attendues d'une condition
+type operator struct {
+	match   func(actual, expected string) bool
+	negated bool // StringNotEquals... : vrai si aucune valeur ne correspond
+}
+
+// operators liste les opérateurs de condition supportés (sans le suffixe IfExists)
+var operators = map[string]operator{
+	"stringequals":              {match: func(a, e string) bool { return a == e }},
+	"stringnotequals":           {match: func(a, e string) bool { return a == e }, negated: true},
+	"stringequalsignorecase":    {match: strings.EqualFold},
+	"stringnotequalsignorecase": {match: strings.EqualFold, negated: true},
+	"stringlike":                {match: func(a, e string) bool { return wildcardMatch(e, a) }},
+	"stringnotlike":             {match: func(a, e string) bool { return wildcardMatch(e, a) }, negated: true},
+	"ipaddress":                 {match: ipMatch},
+	"notipaddress":              {match: ipMatch, negated: true},
+	"bool":                      {match: strings.EqualFold},
+	"numericequals":             {match: numericMatch(func(a, e float64) bool { return a == e })},
+	"numericnotequals":          {match: numericMatch(func(a, e float64) bool { return a == e }), negated: true},
+	"numericlessthan":           {match: numericMatch(func(a, e float64) bool { return a < e })},
+	"numericlessthanequals":     {match: numericMatch(func(a, e float64) bool { return a <= e })},
+	"numericgreaterthan":        {match: numericMatch(func(a, e float64) bool { return a > e })},
+	"numericgreaterthanequals":  {match: numericMatch(func(a, e float64) bool { return a >= e })},
+	"dateequals":                {match: dateMatch(func(a, e time.Time) bool { return a.Equal(e) })},
+	"datenotequals":             {match: dateMatch(func(a, e time.Time) bool { return a.Equal(e) }), negated: true},
+	"datelessthan":              {match: dateMatch(func(a, e time.Time) bool { return a.Before(e) })},
+	"datelessthanequals":        {match: dateMatch(func(a, e time.Time) bool { return !a.After(e) })},
+	"dategreaterthan":           {match: dateMatch(func(a, e time.Time) bool { return a.After(e) })},
+	"dategreaterthanequals":     {match: dateMatch(func(a, e time.Time) bool { return !a.Before(e) })},
+}
+
+// lookupOperator retrouve un opérateur par son nom (insensible à la casse, suffixe IfExists accepté)
+func lookupOperator(name string) (operator, bool) {
+	name = strings.ToLower(name)
+	if name == "null" {
+		return operator{}, true
+	}
+	op, ok := operators[strings.TrimSuffix(name, "ifexists")]
+	return op, ok
+}
+
+// evaluateConditions vérifie que toutes les conditions d'une déclaration sont satisfaites.
+// Entre clés et entre opérateurs : ET logique ; entre valeurs d'une même clé : OU logique.
+func evaluateConditions(conditions map[string]map[string]StringList, context map[string][]string) bool {
+	for name, keys := range conditions {
+		op, ok := lookupOperator(name)
+		if !ok {
+			return false
+		}
+		ifExists := strings.HasSuffix(strings.ToLower(name), "ifexists")
+		isNull := strings.EqualFold(name, "null")
+
+		for key, expected := range keys {
+			actual, present := context[strings.ToLower(key)]
+			present = present && len(actual) > 0
+
+			if isNull {
+				// Null: "true" si la clé doit être absente, "false" si elle doit être présente
+				want := len(expected) > 0 && strings.EqualFold(expected[0], "true")
+				if want == present {
+					return false
+				}
+				continue
+			}
+			if !present {
+				// Clé absente : satisfaite pour les opérateurs IfExists et les opérateurs négatifs
+				if ifExists || op.negated {
+					continue
+				}
+				return false
+			}
+
+			matched := false
+			for _, a := range actual {
+				for _, e := range expected {
+					if op.match(a, e) {
+						matched = true
+					}
+				}
+			}
+			if matched == op.negated {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ipMatch compare une adresse IP à une adresse ou un bloc CIDR
+func ipMatch(actual, expected string) bool {
+	ip := net.ParseIP(actual)
+	if ip == nil {
+		return false
+	}
+	if !strings.Contains(expected, "/") {
+		other := net.ParseIP(expected)
+		return other != nil && other.Equal(ip)
+	}
+	_, network, err := net.ParseCIDR(expected)
+	return err == nil && network.Contains(ip)
+}
+
+// numericMatch construit un comparateur numérique
+func numericMatch(cmp func(a, e float64) bool) func(actual, expected string) bool {
+	return func(actual, expected string) bool {
+		a, err1 := strconv.ParseFloat(actual, 64)
+		e, err2 := strconv.ParseFloat(expected, 64)
+		return err1 == nil && err2 == nil && cmp(a, e)
+	}
+}
+
+// dateMatch construit un comparateur de dates (RFC 3339 ou secondes epoch)
+func dateMatch(cmp func(a, e time.Time) bool) func(actual, expected string) bool {
+	return func(actual, expected string) bool {
+		a, ok1 := parseDate(actual)
+		e, ok2 := parseDate(expected)
+		return ok1 && ok2 && cmp(a, e)
+	}
+}
+
+// parseDate lit une date RFC 3339, une date seule ou un horodatage epoch
+func parseDate(value string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), true
+	}
+	return time.Time{}, false
+}
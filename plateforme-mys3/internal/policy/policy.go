@@ -0,0 +1,239 @@
+// internal/policy/policy.go
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMalformedPolicy est retournée par Parse pour un document de politique invalide
+var ErrMalformedPolicy = errors.New("policy: politique de bucket invalide")
+
+// Decision est le résultat de l'évaluation d'une politique
+type Decision int
+
+const (
+	// NotApplicable : aucune déclaration ne s'applique à la requête
+	NotApplicable Decision = iota
+	// Allow : au moins une déclaration Allow s'applique, aucune Deny
+	Allow
+	// Deny : une déclaration Deny explicite s'applique
+	Deny
+)
+
+// Policy représente une politique de bucket au format IAM
+type Policy struct {
+	Version   string      `json:"Version,omitempty"`
+	ID        string      `json:"Id,omitempty"`
+	Statement []Statement `json:"Statement"`
+}
+
+// Statement représente une déclaration d'une politique
+type Statement struct {
+	Sid          string                           `json:"Sid,omitempty"`
+	Effect       string                           `json:"Effect"`
+	Principal    *Principal                       `json:"Principal,omitempty"`
+	NotPrincipal *Principal                       `json:"NotPrincipal,omitempty"`
+	Action       StringList                       `json:"Action,omitempty"`
+	NotAction    StringList                       `json:"NotAction,omitempty"`
+	Resource     StringList                       `json:"Resource,omitempty"`
+	NotResource  StringList                       `json:"NotResource,omitempty"`
+	Condition    map[string]map[string]StringList `json:"Condition,omitempty"`
+}
+
+// Principal représente "*" ou {"AWS": [...]}
+type Principal struct {
+	AWS StringList `json:"AWS,omitempty"`
+}
+
+// UnmarshalJSON accepte "*" comme raccourci de {"AWS": "*"}
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var wildcard string
+	if err := json.Unmarshal(data, &wildcard); err == nil {
+		if wildcard != "*" {
+			return fmt.Errorf("%w: principal %q", ErrMalformedPolicy, wildcard)
+		}
+		p.AWS = StringList{"*"}
+		return nil
+	}
+	var principal struct {
+		AWS StringList `json:"AWS"`
+	}
+	if err := json.Unmarshal(data, &principal); err != nil {
+		return err
+	}
+	p.AWS = principal.AWS
+	return nil
+}
+
+// StringList accepte une chaîne seule ou un tableau de chaînes
+type StringList []string
+
+// UnmarshalJSON décode une chaîne ou un tableau de chaînes
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Request décrit l'accès à autoriser
+type Request struct {
+	Action    string              // ex. s3:GetObject
+	Resource  string              // ex. arn:aws:s3:::bucket/key
+	Principal Principal           // identifiants de l'appelant (user id, access key, ARN), vide si anonyme
+	Context   map[string][]string // clés de condition (aws:SourceIp, s3:prefix...), en minuscules
+}
+
+// Parse décode et valide une politique de bucket ; toutes les ressources doivent appartenir au bucket
+func Parse(data []byte, bucketName string) (*Policy, error) {
+	var p Policy
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedPolicy, err)
+	}
+	if p.Version != "" && p.Version != "2012-10-17" && p.Version != "2008-10-17" {
+		return nil, fmt.Errorf("%w: version %q", ErrMalformedPolicy, p.Version)
+	}
+	if len(p.Statement) == 0 {
+		return nil, fmt.Errorf("%w: aucune déclaration", ErrMalformedPolicy)
+	}
+
+	bucketARN := "arn:aws:s3:::" + bucketName
+	for i, st := range p.Statement {
+		if st.Effect != "Allow" && st.Effect != "Deny" {
+			return nil, fmt.Errorf("%w: déclaration %d : effet %q", ErrMalformedPolicy, i, st.Effect)
+		}
+		if (st.Principal == nil) == (st.NotPrincipal == nil) {
+			return nil, fmt.Errorf("%w: déclaration %d : Principal ou NotPrincipal obligatoire", ErrMalformedPolicy, i)
+		}
+		if (len(st.Action) == 0) == (len(st.NotAction) == 0) {
+			return nil, fmt.Errorf("%w: déclaration %d : Action ou NotAction obligatoire", ErrMalformedPolicy, i)
+		}
+		for _, action := range append(append(StringList{}, st.Action...), st.NotAction...) {
+			if action != "*" && !strings.HasPrefix(strings.ToLower(action), "s3:") {
+				return nil, fmt.Errorf("%w: déclaration %d : action %q", ErrMalformedPolicy, i, action)
+			}
+		}
+		if (len(st.Resource) == 0) == (len(st.NotResource) == 0) {
+			return nil, fmt.Errorf("%w: déclaration %d : Resource ou NotResource obligatoire", ErrMalformedPolicy, i)
+		}
+		for _, resource := range append(append(StringList{}, st.Resource...), st.NotResource...) {
+			if resource != bucketARN && !strings.HasPrefix(resource, bucketARN+"/") {
+				return nil, fmt.Errorf("%w: déclaration %d : la ressource %q n'appartient pas au bucket", ErrMalformedPolicy, i, resource)
+			}
+		}
+		for operator, conditions := range st.Condition {
+			if _, ok := lookupOperator(operator); !ok {
+				return nil, fmt.Errorf("%w: déclaration %d : opérateur de condition %q", ErrMalformedPolicy, i, operator)
+			}
+			if len(conditions) == 0 {
+				return nil, fmt.Errorf("%w: déclaration %d : condition %q vide", ErrMalformedPolicy, i, operator)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// Evaluate évalue la politique : un Deny explicite l'emporte sur tout Allow
+func (p *Policy) Evaluate(req Request) Decision {
+	decision := NotApplicable
+	for _, st := range p.Statement {
+		if !st.matches(req) {
+			continue
+		}
+		if st.Effect == "Deny" {
+			return Deny
+		}
+		decision = Allow
+	}
+	return decision
+}
+
+// matches indique si la déclaration s'applique à la requête
+func (st Statement) matches(req Request) bool {
+	if st.Principal != nil && !st.Principal.matches(req.Principal) {
+		return false
+	}
+	if st.NotPrincipal != nil && st.NotPrincipal.matches(req.Principal) {
+		return false
+	}
+	if len(st.Action) > 0 && !matchAny(st.Action, req.Action, true) {
+		return false
+	}
+	if len(st.NotAction) > 0 && matchAny(st.NotAction, req.Action, true) {
+		return false
+	}
+	if len(st.Resource) > 0 && !matchAny(st.Resource, req.Resource, false) {
+		return false
+	}
+	if len(st.NotResource) > 0 && matchAny(st.NotResource, req.Resource, false) {
+		return false
+	}
+	return evaluateConditions(st.Condition, req.Context)
+}
+
+// matches indique si l'un des identifiants de l'appelant figure dans le principal de la déclaration
+func (p Principal) matches(caller Principal) bool {
+	for _, expected := range p.AWS {
+		if expected == "*" {
+			return true
+		}
+		for _, id := range caller.AWS {
+			if expected == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAny indique si la valeur correspond à l'un des motifs (jokers * et ?)
+func matchAny(patterns []string, value string, ignoreCase bool) bool {
+	for _, pattern := range patterns {
+		if ignoreCase {
+			if wildcardMatch(strings.ToLower(pattern), strings.ToLower(value)) {
+				return true
+			}
+		} else if wildcardMatch(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch compare une valeur à un motif où * remplace toute suite de caractères et ? un caractère
+func wildcardMatch(pattern, value string) bool {
+	p, v := 0, 0
+	star, match := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, match = p, v
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case star >= 0:
+			p = star + 1
+			match++
+			v = match
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,102 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+const testPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Sid": "PublicRead",
+			"Effect": "Allow",
+			"Principal": "*",
+			"Action": "s3:GetObject",
+			"Resource": "arn:aws:s3:::bucket/public/*"
+		},
+		{
+			"Effect": "Allow",
+			"Principal": {"AWS": ["alice"]},
+			"Action": ["s3:ListBucket"],
+			"Resource": "arn:aws:s3:::bucket",
+			"Condition": {"StringLike": {"s3:prefix": "home/alice/*"}}
+		},
+		{
+			"Effect": "Deny",
+			"Principal": "*",
+			"Action": "s3:*",
+			"Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"],
+			"Condition": {"NotIpAddress": {"aws:SourceIp": "10.0.0.0/8"}}
+		}
+	]
+}`
+
+// Test de l'évaluation d'une politique : Allow, Deny explicite, conditions et jokers
+func TestEvaluate(t *testing.T) {
+	p, err := Parse([]byte(testPolicy), "bucket")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	alice := Principal{AWS: StringList{"alice"}}
+	internal := []string{"10.1.2.3"}
+
+	tests := []struct {
+		name string
+		req  Request
+		want Decision
+	}{
+		{"public read", Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::bucket/public/a.txt", Context: map[string][]string{"aws:sourceip": internal}}, Allow},
+		{"private read", Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::bucket/private/a.txt", Context: map[string][]string{"aws:sourceip": internal}}, NotApplicable},
+		{"action case", Request{Action: "S3:GETOBJECT", Resource: "arn:aws:s3:::bucket/public/a.txt", Context: map[string][]string{"aws:sourceip": internal}}, Allow},
+		{"list own prefix", Request{Action: "s3:ListBucket", Resource: "arn:aws:s3:::bucket", Principal: alice, Context: map[string][]string{"aws:sourceip": internal, "s3:prefix": {"home/alice/docs"}}}, Allow},
+		{"list other prefix", Request{Action: "s3:ListBucket", Resource: "arn:aws:s3:::bucket", Principal: alice, Context: map[string][]string{"aws:sourceip": internal, "s3:prefix": {"home/bob/"}}}, NotApplicable},
+		{"list without prefix", Request{Action: "s3:ListBucket", Resource: "arn:aws:s3:::bucket", Principal: alice, Context: map[string][]string{"aws:sourceip": internal}}, NotApplicable},
+		{"external ip", Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::bucket/public/a.txt", Context: map[string][]string{"aws:sourceip": {"192.168.1.1"}}}, Deny},
+	}
+	for _, tt := range tests {
+		if got := p.Evaluate(tt.req); got != tt.want {
+			t.Errorf("%s: expected decision %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+// Test du rejet des politiques invalides
+func TestParseMalformed(t *testing.T) {
+	policies := map[string]string{
+		"invalid json":     `{"Statement": [`,
+		"no statement":     `{"Version": "2012-10-17", "Statement": []}`,
+		"bad effect":       `{"Statement": [{"Effect": "Maybe", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`,
+		"no principal":     `{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`,
+		"foreign action":   `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "iam:CreateUser", "Resource": "arn:aws:s3:::bucket/*"}]}`,
+		"other bucket":     `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::other/*"}]}`,
+		"unknown operator": `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*", "Condition": {"StringMaybe": {"s3:prefix": "a"}}}]}`,
+		"unknown field":    `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*", "Extra": 1}]}`,
+	}
+	for name, document := range policies {
+		if _, err := Parse([]byte(document), "bucket"); !errors.Is(err, ErrMalformedPolicy) {
+			t.Errorf("%s: expected ErrMalformedPolicy, got %v", name, err)
+		}
+	}
+}
+
+// Test des jokers * et ?
+func TestWildcardMatch(t *testing.T) {
+	tests := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"*", "", true},
+		{"a*", "abc", true},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"*.txt", "dir/file.txt", true},
+		{"*.txt", "file.txt.gz", false},
+		{"a*b*c", "axxbyyc", true},
+	}
+	for _, tt := range tests {
+		if got := wildcardMatch(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("wildcardMatch(%q, %q) = %v, expected %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
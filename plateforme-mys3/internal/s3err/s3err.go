@@ -0,0 +1,173 @@
+// internal/s3err/s3err.go
+package s3err
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"os"
+	"plateforme-mys3/internal/acl"
+	"plateforme-mys3/internal/auth"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/policy"
+	"plateforme-mys3/internal/storage"
+	"strings"
+)
+
+// RequestIDHeader est l'en-tête portant l'identifiant de la requête, repris dans le corps des erreurs
+const RequestIDHeader = "x-amz-request-id"
+
+// APIError est une erreur S3 : le code renvoyé aux SDK, son message et son statut HTTP
+type APIError struct {
+	Code       string
+	Message    string
+	HTTPStatus int
+}
+
+func (e APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// WithMessage retourne la même erreur avec un message plus précis
+func (e APIError) WithMessage(message string) APIError {
+	e.Message = message
+	return e
+}
+
+// Erreurs S3 renvoyées par le serveur
+var (
+	ErrAccessDenied                      = APIError{"AccessDenied", "Access Denied", http.StatusForbidden}
+	ErrAuthorizationHeaderMalformed      = APIError{"AuthorizationHeaderMalformed", "The authorization header is malformed.", http.StatusBadRequest}
+	ErrAuthorizationQueryParametersError = APIError{"AuthorizationQueryParametersError", "Query-string authentication parameters are invalid.", http.StatusBadRequest}
+	ErrBadDigest                         = APIError{"BadDigest", "The Content-MD5 you specified did not match what we received.", http.StatusBadRequest}
+	ErrBucketAlreadyExists               = APIError{"BucketAlreadyExists", "The requested bucket name is not available. The bucket namespace is shared by all users of the system. Please select a different name and try again.", http.StatusConflict}
+	ErrBucketAlreadyOwnedByYou           = APIError{"BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.", http.StatusConflict}
+	ErrBucketNotEmpty                    = APIError{"BucketNotEmpty", "The bucket you tried to delete is not empty", http.StatusConflict}
+	ErrEntityTooSmall                    = APIError{"EntityTooSmall", "Your proposed upload is smaller than the minimum allowed object size.", http.StatusBadRequest}
+	ErrIllegalLocationConstraint         = APIError{"IllegalLocationConstraintException", "The location constraint is incompatible for the region specific endpoint this request was sent to.", http.StatusBadRequest}
+	ErrIncompleteBody                    = APIError{"IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header.", http.StatusBadRequest}
+	ErrInternalError                     = APIError{"InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError}
+	ErrInvalidAccessKeyID                = APIError{"InvalidAccessKeyId", "The AWS access key ID you provided does not exist in our records.", http.StatusForbidden}
+	ErrInvalidArgument                   = APIError{"InvalidArgument", "Invalid Argument", http.StatusBadRequest}
+	ErrInvalidBucketName                 = APIError{"InvalidBucketName", "The specified bucket is not valid.", http.StatusBadRequest}
+	ErrInvalidDigest                     = APIError{"InvalidDigest", "The Content-MD5 you specified is not valid.", http.StatusBadRequest}
+	ErrInvalidPart                       = APIError{"InvalidPart", "One or more of the specified parts could not be found. The part might not have been uploaded, or the specified entity tag might not have matched the part's entity tag.", http.StatusBadRequest}
+	ErrInvalidPartOrder                  = APIError{"InvalidPartOrder", "The list of parts was not in ascending order. The parts list must be specified in order by part number.", http.StatusBadRequest}
+	ErrInvalidRange                      = APIError{"InvalidRange", "The requested range is not satisfiable", http.StatusRequestedRangeNotSatisfiable}
+	ErrInvalidRequest                    = APIError{"InvalidRequest", "Invalid Request", http.StatusBadRequest}
+	ErrInvalidVersioningStatus           = APIError{"IllegalVersioningConfigurationException", "The Versioning element must be specified and must be either Enabled or Suspended.", http.StatusBadRequest}
+	ErrMalformedACL                      = APIError{"MalformedACLError", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest}
+	ErrMalformedPolicy                   = APIError{"MalformedPolicy", "Policies must be valid JSON and the first byte must be '{'", http.StatusBadRequest}
+	ErrMalformedXML                      = APIError{"MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest}
+	ErrMetadataTooLarge                  = APIError{"MetadataTooLarge", "Your metadata headers exceed the maximum allowed metadata size.", http.StatusBadRequest}
+	ErrMethodNotAllowed                  = APIError{"MethodNotAllowed", "The specified method is not allowed against this resource.", http.StatusMethodNotAllowed}
+	ErrNoSuchBucket                      = APIError{"NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound}
+	ErrNoSuchBucketPolicy                = APIError{"NoSuchBucketPolicy", "The bucket policy does not exist", http.StatusNotFound}
+	ErrNoSuchLifecycleConfiguration      = APIError{"NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist", http.StatusNotFound}
+	ErrNoSuchKey                         = APIError{"NoSuchKey", "The specified key does not exist.", http.StatusNotFound}
+	ErrNoSuchUpload                      = APIError{"NoSuchUpload", "The specified multipart upload does not exist. The upload ID might be invalid, or the multipart upload might have been aborted or completed.", http.StatusNotFound}
+	ErrNoSuchVersion                     = APIError{"NoSuchVersion", "The specified version does not exist.", http.StatusNotFound}
+	ErrNotImplemented                    = APIError{"NotImplemented", "A header you provided implies functionality that is not implemented.", http.StatusNotImplemented}
+	ErrPreconditionFailed                = APIError{"PreconditionFailed", "At least one of the pre-conditions you specified did not hold", http.StatusPreconditionFailed}
+	ErrRequestTimeTooSkewed              = APIError{"RequestTimeTooSkewed", "The difference between the request time and the server's time is too large.", http.StatusForbidden}
+	ErrSSEConfigurationNotFound          = APIError{"ServerSideEncryptionConfigurationNotFoundError", "The server side encryption configuration was not found", http.StatusNotFound}
+	ErrSignatureDoesNotMatch             = APIError{"SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided. Check your key and signing method.", http.StatusForbidden}
+	ErrXAmzContentSHA256Mismatch         = APIError{"XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed.", http.StatusBadRequest}
+)
+
+// errorMappings associe les erreurs des autres packages à leur erreur S3
+var errorMappings = []struct {
+	err    error
+	apiErr APIError
+}{
+	{storage.ErrNoSuchBucket, ErrNoSuchBucket},
+	{storage.ErrInvalidBucketName, ErrInvalidBucketName},
+	{storage.ErrBucketAlreadyOwnedByYou, ErrBucketAlreadyOwnedByYou},
+	{storage.ErrBucketAlreadyExists, ErrBucketAlreadyExists},
+	{storage.ErrBucketNotEmpty, ErrBucketNotEmpty},
+	{storage.ErrNoSuchBucketPolicy, ErrNoSuchBucketPolicy},
+	{storage.ErrNoSuchUpload, ErrNoSuchUpload},
+	{storage.ErrNoSuchVersion, ErrNoSuchVersion},
+	{storage.ErrInvalidPart, ErrInvalidPart},
+	{storage.ErrInvalidPartOrder, ErrInvalidPartOrder},
+	{storage.ErrEntityTooSmall, ErrEntityTooSmall},
+	{storage.ErrInvalidChecksumAlgorithm, ErrInvalidRequest.WithMessage("Checksum algorithm provided is unsupported. Please try again with any of the valid types: [CRC32, CRC32C, SHA1, SHA256]")},
+	{storage.ErrInvalidPartNumber, ErrInvalidArgument.WithMessage("Part number must be an integer between 1 and 10000, inclusive")},
+	{storage.ErrMalformedUploadXML, ErrMalformedXML},
+	{storage.ErrInvalidVersioningStatus, ErrInvalidVersioningStatus},
+	{storage.ErrSSENotConfigured, ErrNotImplemented.WithMessage("Server-side encryption with server-managed keys is not configured on this server.")},
+	{storage.ErrInvalidSSEKey, ErrInvalidArgument.WithMessage("The secret key was invalid for the specified algorithm.")},
+	{storage.ErrSSECustomerKeyRequired, ErrInvalidRequest.WithMessage("The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.")},
+	{storage.ErrSSECustomerKeyMismatch, ErrAccessDenied.WithMessage("The provided encryption parameters did not match the ones used originally.")},
+	{storage.ErrSSENotApplicable, ErrInvalidRequest.WithMessage("The encryption parameters are not applicable to this object.")},
+	{storage.ErrNoSuchEncryptionConfiguration, ErrSSEConfigurationNotFound},
+	{storage.ErrInvalidEncryptionAlgorithm, ErrInvalidArgument.WithMessage("The server side encryption algorithm is not supported. Only AES256 is supported.")},
+	{storage.ErrNoSuchLifecycleConfiguration, ErrNoSuchLifecycleConfiguration},
+	{os.ErrNotExist, ErrNoSuchKey},
+	{auth.ErrInvalidAccessKeyID, ErrInvalidAccessKeyID},
+	{auth.ErrSignatureMismatch, ErrSignatureDoesNotMatch},
+	{auth.ErrChunkSignatureMismatch, ErrSignatureDoesNotMatch},
+	{auth.ErrRequestTimeTooSkewed, ErrRequestTimeTooSkewed},
+	{auth.ErrRequestExpired, ErrAccessDenied.WithMessage("Request has expired")},
+	{auth.ErrRequestNotYetValid, ErrAccessDenied.WithMessage("Request is not valid yet")},
+	{auth.ErrMalformedPresignedURL, ErrAuthorizationQueryParametersError},
+	{auth.ErrMalformedAuthorization, ErrAuthorizationHeaderMalformed},
+	{auth.ErrContentSHA256Mismatch, ErrXAmzContentSHA256Mismatch},
+	{auth.ErrMalformedChunk, ErrIncompleteBody.WithMessage("The aws-chunked body is malformed.")},
+	{auth.ErrIncompleteBody, ErrIncompleteBody},
+	{acl.ErrInvalidCannedACL, ErrInvalidArgument.WithMessage("The canned ACL is not valid.")},
+	{acl.ErrMalformedACL, ErrMalformedACL},
+	{policy.ErrMalformedPolicy, ErrMalformedPolicy},
+}
+
+// FromError traduit une erreur du stockage, de l'authentification, des ACL ou des politiques en erreur S3 ;
+// une erreur inconnue devient InternalError
+func FromError(err error) APIError {
+	var apiErr APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping.apiErr
+		}
+	}
+	return ErrInternalError
+}
+
+// NewRequestID génère un identifiant de requête aléatoire
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+// Write renvoie une erreur S3 au format XML. Une réponse à HEAD n'a pas de corps : seul le statut est renvoyé.
+func Write(w http.ResponseWriter, r *http.Request, apiErr APIError) {
+	requestID := w.Header().Get(RequestIDHeader)
+	if requestID == "" {
+		requestID = NewRequestID()
+		w.Header().Set(RequestIDHeader, requestID)
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(apiErr.HTTPStatus)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(apiErr.HTTPStatus)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(dto.Error{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Resource:  r.URL.Path,
+		RequestId: requestID,
+	})
+}
+
+// WriteError renvoie l'erreur S3 correspondant à err (voir FromError)
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, FromError(err))
+}
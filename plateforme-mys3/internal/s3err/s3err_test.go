@@ -0,0 +1,49 @@
+package s3err
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"plateforme-mys3/internal/auth"
+	"plateforme-mys3/internal/dto"
+	"plateforme-mys3/internal/storage"
+	"testing"
+)
+
+// Test de la traduction des erreurs internes en erreurs S3
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{storage.ErrNoSuchBucket, "NoSuchBucket"},
+		{fmt.Errorf("lecture: %w", os.ErrNotExist), "NoSuchKey"},
+		{auth.ErrSignatureMismatch, "SignatureDoesNotMatch"},
+		{auth.ErrRequestTimeTooSkewed, "RequestTimeTooSkewed"},
+		{ErrBucketNotEmpty, "BucketNotEmpty"},
+		{fmt.Errorf("inattendue"), "InternalError"},
+	}
+	for _, tt := range tests {
+		if got := FromError(tt.err); got.Code != tt.want {
+			t.Errorf("FromError(%v) = %s, want %s", tt.err, got.Code, tt.want)
+		}
+	}
+}
+
+// Test du corps XML d'une erreur et de l'identifiant de requête
+func TestWrite(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	w := httptest.NewRecorder()
+	w.Header().Set(RequestIDHeader, "REQ123")
+	Write(w, r, ErrNoSuchKey)
+
+	var body dto.Error
+	if err := xml.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusNotFound || body.Code != "NoSuchKey" || body.Resource != "/bucket/key" || body.RequestId != "REQ123" {
+		t.Errorf("Unexpected error response %d %+v", w.Code, body)
+	}
+}
@@ -0,0 +1,76 @@
+// internal/storage/acl.go
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"plateforme-mys3/internal/acl"
+)
+
+// bucketOwnerACL retourne l'ACL par défaut (privée) d'une ressource du bucket
+func (s *Storage) bucketOwnerACL(bucketName string) (acl.ACL, error) {
+	owner, err := s.BucketOwner(bucketName)
+	if err != nil {
+		return acl.ACL{}, err
+	}
+	return acl.Private(acl.Owner{ID: owner, DisplayName: owner}), nil
+}
+
+// PutBucketACL enregistre l'ACL d'un bucket
+func (s *Storage) PutBucketACL(bucketName string, a acl.ACL) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return s.putBucketConfig(bucketName, "acl.json", data)
+}
+
+// GetBucketACL retourne l'ACL d'un bucket (privée par défaut)
+func (s *Storage) GetBucketACL(bucketName string) (acl.ACL, error) {
+	data, err := s.getBucketConfig(bucketName, "acl.json")
+	if os.IsNotExist(err) {
+		return s.bucketOwnerACL(bucketName)
+	}
+	if err != nil {
+		return acl.ACL{}, err
+	}
+	var a acl.ACL
+	err = json.Unmarshal(data, &a)
+	return a, err
+}
+
+// PutObjectACL enregistre l'ACL d'un objet existant
+func (s *Storage) PutObjectACL(bucketName, objectName string, a acl.ACL) error {
+	if !s.BucketExists(bucketName) {
+		return ErrNoSuchBucket
+	}
+	unlock := s.locks.lock(bucketName, objectName)
+	defer unlock()
+	if _, err := s.StatObject(bucketName, objectName); err != nil {
+		return err
+	}
+	return s.putObjectACL(bucketName, objectName, a)
+}
+
+// putObjectACL enregistre l'ACL d'un objet ; l'appelant détient le verrou de la clé
+func (s *Storage) putObjectACL(bucketName, objectName string, a acl.ACL) error {
+	path := s.objectSidecarPath(bucketName, objectName, "acl")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeJSON(path, a)
+}
+
+// GetObjectACL retourne l'ACL d'un objet (privée, au nom du propriétaire du bucket, par défaut)
+func (s *Storage) GetObjectACL(bucketName, objectName string) (acl.ACL, error) {
+	if !s.BucketExists(bucketName) {
+		return acl.ACL{}, ErrNoSuchBucket
+	}
+	var a acl.ACL
+	err := readJSON(s.objectSidecarPath(bucketName, objectName, "acl"), &a)
+	if os.IsNotExist(err) {
+		return s.bucketOwnerACL(bucketName)
+	}
+	return a, err
+}
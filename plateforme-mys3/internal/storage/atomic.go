@@ -0,0 +1,164 @@
+// internal/storage/atomic.go
+package storage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Écritures atomiques et résistantes aux pannes.
+//
+// Un fichier n'est jamais écrit à son emplacement final : son contenu est préparé dans un fichier temporaire
+// du même système de fichiers, synchronisé sur disque (fsync), puis renommé. Le renommage est atomique :
+// un lecteur voit l'ancien fichier ou le nouveau, jamais un fichier partiel. Le répertoire parent est
+// synchronisé à son tour pour que le renommage survive à une panne.
+//
+// Un objet est fait de plusieurs fichiers (contenu, métadonnées, ACL, versions archivées) : les écritures
+// d'une même clé sont sérialisées par un verrou par clé (keyLocks), que les lectures prennent en mode partagé,
+// et leur mise en place est journalisée pour être terminée après une panne (voir journal.go).
+
+// tmpPrefix préfixe les fichiers temporaires créés à côté de leur destination
+const tmpPrefix = ".tmp-"
+
+// tmpDir retourne le répertoire où est préparé le contenu des objets avant sa mise en place
+func (s *Storage) tmpDir() string {
+	return filepath.Join(s.BasePath, systemDir, "tmp")
+}
+
+// syncDir synchronise un répertoire sur disque, ce qui rend durables les créations et renommages qu'il contient
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// commitFile synchronise un fichier temporaire déjà écrit, le ferme puis le renomme atomiquement en path
+func commitFile(tmp *os.File, path string) error {
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// writeFileAtomic remplace le contenu d'un fichier par data : l'ancien contenu reste lisible jusqu'au renommage
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), tmpPrefix+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	return commitFile(tmp, path)
+}
+
+// RemoveStaleTempFiles supprime les fichiers temporaires laissés par des écritures interrompues (arrêt brutal
+// du serveur pendant un PUT) et retourne leur nombre. À appeler au démarrage, après RecoverWrites (qui utilise
+// le contenu préparé des écritures journalisées) et avant toute écriture.
+func (s *Storage) RemoveStaleTempFiles() (int, error) {
+	root := filepath.Join(s.BasePath, systemDir)
+	tmpDir := s.tmpDir()
+	removed := 0
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if filepath.Dir(path) != tmpDir && !strings.HasPrefix(entry.Name(), tmpPrefix) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// keyLocks associe un verrou à chaque clé d'objet en cours d'utilisation ; la valeur zéro est prête à l'emploi.
+// Les verrous inutilisés sont libérés : la table ne grossit pas avec le nombre d'objets.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+// keyLock est le verrou d'une clé et le nombre d'appelants qui le détiennent ou l'attendent
+type keyLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// acquire retourne le verrou d'une clé en le réservant ; il doit être rendu par release
+func (k *keyLocks) acquire(bucketName, objectName string) (*keyLock, string) {
+	id := bucketName + "/" + objectName
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyLock)
+	}
+	l, ok := k.locks[id]
+	if !ok {
+		l = &keyLock{}
+		k.locks[id] = l
+	}
+	l.refs++
+	return l, id
+}
+
+// release rend un verrou réservé par acquire et l'oublie s'il n'est plus utilisé
+func (k *keyLocks) release(l *keyLock, id string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(k.locks, id)
+	}
+}
+
+// lock verrouille une clé en écriture et retourne la fonction qui la déverrouille
+func (k *keyLocks) lock(bucketName, objectName string) func() {
+	l, id := k.acquire(bucketName, objectName)
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.release(l, id)
+	}
+}
+
+// rlock verrouille une clé en lecture et retourne la fonction qui la déverrouille
+func (k *keyLocks) rlock(bucketName, objectName string) func() {
+	l, id := k.acquire(bucketName, objectName)
+	l.RLock()
+	return func() {
+		l.RUnlock()
+		k.release(l, id)
+	}
+}
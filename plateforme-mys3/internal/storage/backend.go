@@ -0,0 +1,71 @@
+// internal/storage/backend.go
+package storage
+
+import (
+	"io"
+	"plateforme-mys3/internal/acl"
+	"time"
+)
+
+// Backend est l'interface de stockage dont dépendent les handlers et le middleware d'autorisation.
+// Storage (système de fichiers) et MemoryBackend (mémoire, pour les tests) l'implémentent ;
+// les deux retournent les mêmes erreurs (ErrNoSuchBucket, os.ErrNotExist pour une clé absente...).
+type Backend interface {
+	// Buckets
+	CreateBucket(bucketName, owner string) error
+	DeleteBucket(bucketName string) error
+	BucketExists(bucketName string) bool
+	ListBuckets() ([]BucketInfo, error)
+	BucketOwner(bucketName string) (string, error)
+
+	// Configuration des buckets
+	PutBucketPolicy(bucketName string, policy []byte) error
+	GetBucketPolicy(bucketName string) ([]byte, error)
+	DeleteBucketPolicy(bucketName string) error
+	PutBucketACL(bucketName string, a acl.ACL) error
+	GetBucketACL(bucketName string) (acl.ACL, error)
+	PutBucketVersioning(bucketName, status string) error
+	GetBucketVersioning(bucketName string) (string, error)
+	PutBucketEncryption(bucketName string, config BucketEncryption) error
+	GetBucketEncryption(bucketName string) (BucketEncryption, error)
+	DeleteBucketEncryption(bucketName string) error
+	PutBucketLifecycle(bucketName string, config LifecycleConfiguration) error
+	GetBucketLifecycle(bucketName string) (LifecycleConfiguration, error)
+	DeleteBucketLifecycle(bucketName string) error
+
+	// Objets et versions
+	PutObject(bucketName, objectName string, data io.Reader, metadata ObjectMetadata) (PutResult, error)
+	GetObjectMetadata(bucketName, objectName string) (ObjectMetadata, error)
+	GetObjectVersion(bucketName, objectName, versionID string) (io.ReadSeekCloser, ObjectMetadata, error)
+	OpenObjectVersion(bucketName, objectName, versionID string, customerKey []byte) (io.ReadSeekCloser, ObjectMetadata, error)
+	GetObjectVersionMetadata(bucketName, objectName, versionID string) (ObjectMetadata, error)
+	DeleteObject(bucketName, objectName, versionID string) (DeleteResult, error)
+	PutObjectACL(bucketName, objectName string, a acl.ACL) error
+	GetObjectACL(bucketName, objectName string) (acl.ACL, error)
+	RewrapDataKey(bucketName, objectName, versionID string) (bool, error)
+
+	// Listings paginés
+	ListObjects(bucketName string, opts ListObjectsOptions) (ListObjectsResult, error)
+	ListObjectVersions(bucketName string, opts ListObjectsOptions, versionIDMarker string) (ListVersionsResult, error)
+	WalkObjectVersions(bucketName, prefix string, fn func(versions []ObjectVersion) error) error
+
+	// Uploads multipart
+	CreateMultipartUpload(bucketName, objectName string, initiator acl.Owner, metadata ObjectMetadata) (string, error)
+	UploadPart(bucketName, objectName, uploadID string, partNumber int, data io.Reader, customerKey []byte) (PartInfo, error)
+	GetMultipartUpload(bucketName, objectName, uploadID string) (MultipartUpload, error)
+	ListParts(bucketName, objectName, uploadID string) ([]PartInfo, error)
+	CompleteMultipartUpload(bucketName, objectName, uploadID string, parts []CompletePart) (PutResult, error)
+	AbortMultipartUpload(bucketName, objectName, uploadID string) error
+	ListMultipartUploads(bucketName string) ([]MultipartUpload, error)
+}
+
+var (
+	_ Backend = (*Storage)(nil)
+	_ Backend = (*MemoryBackend)(nil)
+)
+
+// BucketInfo décrit un bucket listé
+type BucketInfo struct {
+	Name         string
+	CreationDate time.Time
+}
@@ -0,0 +1,447 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"plateforme-mys3/internal/acl"
+	"strings"
+	"testing"
+	"time"
+)
+
+// forEachBackend exécute un test sur le stockage fichiers et sur le stockage en mémoire
+func forEachBackend(t *testing.T, test func(t *testing.T, s Backend)) {
+	t.Run("filesystem", func(t *testing.T) { test(t, NewStorage(t.TempDir())) })
+	t.Run("memory", func(t *testing.T) { test(t, NewMemoryBackend()) })
+}
+
+// Test des opérations de base communes aux backends : buckets, objets, ACL et erreurs
+func TestBackendBasics(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", "alice"); err != nil {
+			t.Fatal(err)
+		}
+		buckets, err := s.ListBuckets()
+		if err != nil || len(buckets) != 1 || buckets[0].Name != "bucket" {
+			t.Fatalf("Unexpected buckets %+v (%v)", buckets, err)
+		}
+		if owner, err := s.BucketOwner("bucket"); err != nil || owner != "alice" {
+			t.Errorf("Expected owner alice, got %q (%v)", owner, err)
+		}
+		if err := s.CreateBucket("bucket", "alice"); !errors.Is(err, ErrBucketAlreadyOwnedByYou) {
+			t.Errorf("Expected ErrBucketAlreadyOwnedByYou, got %v", err)
+		}
+		if err := s.CreateBucket("bucket", "bob"); !errors.Is(err, ErrBucketAlreadyExists) {
+			t.Errorf("Expected ErrBucketAlreadyExists, got %v", err)
+		}
+
+		result, err := s.PutObject("bucket", "dir/key.txt", strings.NewReader("hello"), ObjectMetadata{ContentType: "text/plain"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := md5.Sum([]byte("hello"))
+		if result.ETag != hex.EncodeToString(sum[:]) || result.Size != 5 || result.VersionID != "" {
+			t.Errorf("Unexpected put result %+v", result)
+		}
+		file, metadata, err := s.GetObjectVersion("bucket", "dir/key.txt", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(file)
+		file.Close()
+		if string(data) != "hello" || metadata.ContentType != "text/plain" || metadata.ETag != result.ETag {
+			t.Errorf("Unexpected object %q %+v", data, metadata)
+		}
+
+		objectACL, err := s.GetObjectACL("bucket", "dir/key.txt")
+		if err != nil || objectACL.Owner.ID != "alice" {
+			t.Errorf("Expected a private ACL owned by alice, got %+v (%v)", objectACL, err)
+		}
+		if _, err := s.GetBucketPolicy("bucket"); !errors.Is(err, ErrNoSuchBucketPolicy) {
+			t.Errorf("Expected ErrNoSuchBucketPolicy, got %v", err)
+		}
+
+		if _, err := s.DeleteObject("bucket", "dir/key.txt", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetObjectMetadata("bucket", "dir/key.txt"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected os.ErrNotExist, got %v", err)
+		}
+		if _, err := s.PutObject("missing", "key", strings.NewReader(""), ObjectMetadata{}); !errors.Is(err, ErrNoSuchBucket) {
+			t.Errorf("Expected ErrNoSuchBucket, got %v", err)
+		}
+	})
+}
+
+// Test des sommes de contrôle supplémentaires calculées à l'écriture et enregistrées avec l'objet
+func TestBackendChecksum(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		// CRC32 de "hello" : 0x3610a686
+		result, err := s.PutObject("bucket", "key", strings.NewReader("hello"), ObjectMetadata{ChecksumAlgorithm: ChecksumCRC32})
+		if err != nil || result.Checksum != "NhCmhg==" {
+			t.Fatalf("Expected CRC32 checksum NhCmhg==, got %q (%v)", result.Checksum, err)
+		}
+		metadata, err := s.GetObjectMetadata("bucket", "key")
+		if err != nil || metadata.ChecksumAlgorithm != ChecksumCRC32 || metadata.Checksum != result.Checksum {
+			t.Errorf("Expected the checksum to be stored, got %+v (%v)", metadata, err)
+		}
+		if _, err := s.PutObject("bucket", "key", strings.NewReader("x"), ObjectMetadata{ChecksumAlgorithm: "MD4"}); !errors.Is(err, ErrInvalidChecksumAlgorithm) {
+			t.Errorf("Expected ErrInvalidChecksumAlgorithm, got %v", err)
+		}
+	})
+}
+
+// Test des uploads multipart communs aux backends : assemblage, ETag multipart et annulation
+func TestBackendMultipart(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		alice := acl.Owner{ID: "alice", DisplayName: "Alice"}
+		uploadID, err := s.CreateMultipartUpload("bucket", "big.bin", alice, ObjectMetadata{ContentType: "application/octet-stream"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := bytes.Repeat([]byte("a"), MinPartSize)
+		part1, err := s.UploadPart("bucket", "big.bin", uploadID, 1, bytes.NewReader(first), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part2, err := s.UploadPart("bucket", "big.bin", uploadID, 2, strings.NewReader("end"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UploadPart("bucket", "big.bin", uploadID, 0, strings.NewReader(""), nil); !errors.Is(err, ErrInvalidPartNumber) {
+			t.Errorf("Expected ErrInvalidPartNumber, got %v", err)
+		}
+		uploads, err := s.ListMultipartUploads("bucket")
+		if err != nil || len(uploads) != 1 || uploads[0].UploadID != uploadID || uploads[0].Initiator != alice {
+			t.Errorf("Unexpected uploads %+v (%v)", uploads, err)
+		}
+
+		// ETag de part erroné : refusé, l'upload reste utilisable
+		_, err = s.CompleteMultipartUpload("bucket", "big.bin", uploadID, []CompletePart{{1, part1.ETag}, {2, part1.ETag}})
+		if !errors.Is(err, ErrInvalidPart) {
+			t.Errorf("Expected ErrInvalidPart, got %v", err)
+		}
+		result, err := s.CompleteMultipartUpload("bucket", "big.bin", uploadID, []CompletePart{{1, part1.ETag}, {2, part2.ETag}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(result.ETag, "-2") || result.Size != int64(len(first)+3) {
+			t.Errorf("Unexpected multipart result %+v", result)
+		}
+		metadata, err := s.GetObjectMetadata("bucket", "big.bin")
+		if err != nil || metadata.ETag != result.ETag || len(metadata.PartSizes) != 2 {
+			t.Errorf("Unexpected metadata %+v (%v)", metadata, err)
+		}
+		if _, err := s.ListParts("bucket", "big.bin", uploadID); !errors.Is(err, ErrNoSuchUpload) {
+			t.Errorf("Expected the completed upload to be gone, got %v", err)
+		}
+
+		aborted, err := s.CreateMultipartUpload("bucket", "other", acl.Owner{}, ObjectMetadata{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AbortMultipartUpload("bucket", "other", aborted); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AbortMultipartUpload("bucket", "other", aborted); !errors.Is(err, ErrNoSuchUpload) {
+			t.Errorf("Expected ErrNoSuchUpload, got %v", err)
+		}
+	})
+}
+
+// Test de deux assemblages simultanés d'un même upload : un seul aboutit, l'objet n'est écrit qu'une fois
+func TestConcurrentCompleteMultipartUpload(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PutBucketVersioning("bucket", VersioningEnabled); err != nil {
+			t.Fatal(err)
+		}
+		for round := 0; round < 10; round++ {
+			uploadID, err := s.CreateMultipartUpload("bucket", "key", acl.Owner{}, ObjectMetadata{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			part, err := s.UploadPart("bucket", "key", uploadID, 1, strings.NewReader(fmt.Sprint("round ", round)), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			errs := make(chan error, 2)
+			for i := 0; i < 2; i++ {
+				go func() {
+					_, err := s.CompleteMultipartUpload("bucket", "key", uploadID, []CompletePart{{1, part.ETag}})
+					errs <- err
+				}()
+			}
+			succeeded := 0
+			for i := 0; i < 2; i++ {
+				switch err := <-errs; {
+				case err == nil:
+					succeeded++
+				case !errors.Is(err, ErrNoSuchUpload):
+					t.Errorf("Expected ErrNoSuchUpload for the losing complete, got %v", err)
+				}
+			}
+			if succeeded != 1 {
+				t.Fatalf("Expected exactly one complete to succeed, got %d", succeeded)
+			}
+		}
+		versions, err := s.ListObjectVersions("bucket", ListObjectsOptions{MaxKeys: 1000}, "")
+		if err != nil || len(versions.Versions) != 10 {
+			t.Errorf("Expected one version per upload, got %d (%v)", len(versions.Versions), err)
+		}
+	})
+}
+
+// Test du parcours des versions : pages successives et parcours par clé retournent les mêmes versions, dans l'ordre
+func TestWalkObjectVersions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.PutObject("bucket", "a/null", strings.NewReader("v0"), ObjectMetadata{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PutBucketVersioning("bucket", VersioningEnabled); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"a/null", "b", "c/d", "e"} {
+			for i := 0; i < 3; i++ {
+				if _, err := s.PutObject("bucket", key, strings.NewReader(fmt.Sprint(i)), ObjectMetadata{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if _, err := s.DeleteObject("bucket", "e", ""); err != nil {
+			t.Fatal(err)
+		}
+
+		var walked []string
+		err := s.WalkObjectVersions("bucket", "", func(versions []ObjectVersion) error {
+			for i, version := range versions {
+				if version.IsLatest != (i == 0) || version.Key != versions[0].Key {
+					t.Errorf("Unexpected version %+v at %d", version, i)
+				}
+				walked = append(walked, version.Key+"@"+version.Metadata.VersionID)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(walked) != 4+3+3+4 {
+			t.Errorf("Expected 14 versions, got %v", walked)
+		}
+
+		var paged []string
+		opts, versionIDMarker := ListObjectsOptions{MaxKeys: 2}, ""
+		for {
+			page, err := s.ListObjectVersions("bucket", opts, versionIDMarker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, version := range page.Versions {
+				paged = append(paged, version.Key+"@"+version.Metadata.VersionID)
+			}
+			if !page.IsTruncated {
+				break
+			}
+			opts.StartAfter, versionIDMarker = page.NextKeyMarker, page.NextVersionIDMarker
+		}
+		if strings.Join(paged, ",") != strings.Join(walked, ",") {
+			t.Errorf("Expected the pages to match the walk:\n%v\n%v", paged, walked)
+		}
+
+		// Avec un délimiteur, un préfixe commun compte pour une entrée de la page
+		page, err := s.ListObjectVersions("bucket", ListObjectsOptions{Delimiter: "/", MaxKeys: 2}, "")
+		if err != nil || len(page.CommonPrefixes) != 1 || len(page.Versions) != 1 || !page.IsTruncated {
+			t.Errorf("Unexpected delimited page %+v (%v)", page, err)
+		}
+		if err := s.WalkObjectVersions("missing", "", func([]ObjectVersion) error { return nil }); !errors.Is(err, ErrNoSuchBucket) {
+			t.Errorf("Expected ErrNoSuchBucket, got %v", err)
+		}
+	})
+}
+
+// Test de la suppression d'un bucket : refusée tant qu'il reste un objet, une version ou un marqueur
+func TestDeleteBucket(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.DeleteBucket("missing"); !errors.Is(err, ErrNoSuchBucket) {
+			t.Errorf("Expected ErrNoSuchBucket, got %v", err)
+		}
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.PutObject("bucket", "dir/key.txt", strings.NewReader("data"), ObjectMetadata{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteBucket("bucket"); !errors.Is(err, ErrBucketNotEmpty) {
+			t.Fatalf("Expected ErrBucketNotEmpty, got %v", err)
+		}
+		if _, err := s.GetObjectMetadata("bucket", "dir/key.txt"); err != nil {
+			t.Fatalf("Expected the object to survive a refused deletion: %v", err)
+		}
+
+		// Un marqueur de suppression compte comme du contenu
+		if err := s.PutBucketVersioning("bucket", VersioningEnabled); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DeleteObject("bucket", "dir/key.txt", ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteBucket("bucket"); !errors.Is(err, ErrBucketNotEmpty) {
+			t.Fatalf("Expected ErrBucketNotEmpty with a delete marker, got %v", err)
+		}
+
+		versions, err := s.ListObjectVersions("bucket", ListObjectsOptions{MaxKeys: 1000}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, version := range versions.Versions {
+			if _, err := s.DeleteObject("bucket", version.Key, version.Metadata.VersionID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.CreateMultipartUpload("bucket", "pending", acl.Owner{}, ObjectMetadata{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteBucket("bucket"); err != nil {
+			t.Fatalf("Expected an emptied bucket to be deleted, got %v", err)
+		}
+		if s.BucketExists("bucket") {
+			t.Errorf("Expected the bucket to be gone")
+		}
+
+		// Les uploads en cours sont annulés avec le bucket
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if uploads, err := s.ListMultipartUploads("bucket"); err != nil || len(uploads) != 0 {
+			t.Errorf("Expected no pending upload in the recreated bucket, got %+v (%v)", uploads, err)
+		}
+	})
+}
+
+// Test de la purge forcée d'un bucket par lots
+func TestPurgeBucket(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PutBucketVersioning("bucket", VersioningEnabled); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"a", "a", "b/c", "d"} {
+			if _, err := s.PutObject("bucket", key, strings.NewReader(key), ObjectMetadata{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.DeleteObject("bucket", "d", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.CreateMultipartUpload("bucket", "pending", acl.Owner{}, ObjectMetadata{}); err != nil {
+			t.Fatal(err)
+		}
+
+		var reports []PurgeProgress
+		if err := PurgeBucket(context.Background(), s, "bucket", 2, func(p PurgeProgress) { reports = append(reports, p) }); err != nil {
+			t.Fatal(err)
+		}
+		if s.BucketExists("bucket") {
+			t.Errorf("Expected the bucket to be deleted")
+		}
+		last := reports[len(reports)-1]
+		if len(reports) != 4 || last.Batches != 3 || last.DeletedVersions != 5 || last.AbortedUploads != 1 {
+			t.Errorf("Unexpected progress reports %+v", reports)
+		}
+		if err := PurgeBucket(context.Background(), s, "bucket", 2, nil); !errors.Is(err, ErrNoSuchBucket) {
+			t.Errorf("Expected ErrNoSuchBucket, got %v", err)
+		}
+	})
+}
+
+// Test de l'évaluation du cycle de vie : expiration filtrée par étiquettes, versions non courantes, uploads
+// abandonnés, règle désactivée et simulation
+func TestApplyLifecycle(t *testing.T) {
+	deadline := lifecycleDeadline(time.Date(2024, 3, 1, 15, 4, 0, 0, time.UTC), 2)
+	if !deadline.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the deadline to be rounded to the next midnight, got %s", deadline)
+	}
+
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		s.PutBucketVersioning("bucket", VersioningEnabled)
+		s.PutObject("bucket", "logs/a", strings.NewReader("v1"), ObjectMetadata{})
+		s.PutObject("bucket", "logs/a", strings.NewReader("v2"), ObjectMetadata{})
+		s.PutObject("bucket", "logs/tagged", strings.NewReader("tmp"), ObjectMetadata{Tags: map[string]string{"tmp": "yes"}})
+		s.PutObject("bucket", "keep/b", strings.NewReader("b"), ObjectMetadata{})
+		if _, err := s.CreateMultipartUpload("bucket", "logs/big", acl.Owner{}, ObjectMetadata{}); err != nil {
+			t.Fatal(err)
+		}
+		err := s.PutBucketLifecycle("bucket", LifecycleConfiguration{Rules: []LifecycleRule{
+			{ID: "tmp", Enabled: true, Tags: map[string]string{"tmp": "yes"}, ExpirationDays: 1},
+			{ID: "old-versions", Enabled: true, Prefix: "logs/", NoncurrentDays: 7},
+			{ID: "uploads", Enabled: true, AbortIncompleteDays: 1},
+			{ID: "disabled", Enabled: false, Prefix: "keep/", ExpirationDays: 1},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		countVersions := func() int {
+			page, err := s.ListObjectVersions("bucket", ListObjectsOptions{MaxKeys: 100}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return len(page.Versions)
+		}
+
+		// Rien n'est encore échu
+		if result, err := ApplyLifecycle(context.Background(), s, time.Now(), false); err != nil || result != (LifecycleResult{}) {
+			t.Fatalf("Expected no action yet, got %+v (%v)", result, err)
+		}
+
+		later := time.Now().AddDate(0, 0, 10)
+		expected := LifecycleResult{Expired: 1, NoncurrentExpired: 1, AbortedUploads: 1}
+		result, err := ApplyLifecycle(context.Background(), s, later, true)
+		if err != nil || result != expected {
+			t.Fatalf("Expected %+v in dry run, got %+v (%v)", expected, result, err)
+		}
+		if n := countVersions(); n != 4 {
+			t.Errorf("Expected the dry run to keep the 4 versions, got %d", n)
+		}
+
+		result, err = ApplyLifecycle(context.Background(), s, later, false)
+		if err != nil || result != expected {
+			t.Fatalf("Expected %+v, got %+v (%v)", expected, result, err)
+		}
+		if metadata, err := s.GetObjectVersionMetadata("bucket", "logs/tagged", ""); err != nil || !metadata.DeleteMarker {
+			t.Errorf("Expected logs/tagged to be expired by a delete marker, got %+v (%v)", metadata, err)
+		}
+		if page, _ := s.ListObjectVersions("bucket", ListObjectsOptions{Prefix: "logs/a", MaxKeys: 10}, ""); len(page.Versions) != 1 {
+			t.Errorf("Expected the noncurrent version of logs/a to be deleted, got %+v", page.Versions)
+		}
+		if _, err := s.GetObjectMetadata("bucket", "keep/b"); err != nil {
+			t.Errorf("Expected the disabled rule to keep keep/b: %v", err)
+		}
+		if uploads, err := s.ListMultipartUploads("bucket"); err != nil || len(uploads) != 0 {
+			t.Errorf("Expected the upload to be aborted, got %+v (%v)", uploads, err)
+		}
+	})
+}
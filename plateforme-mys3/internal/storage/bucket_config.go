@@ -0,0 +1,119 @@
+// internal/storage/bucket_config.go
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// bucketInfo regroupe les informations persistées à la création d'un bucket
+type bucketInfo struct {
+	Owner string `json:"owner"`
+}
+
+// bucketConfigDir retourne le répertoire des configurations d'un bucket (propriétaire, politique...).
+// Il est rangé sous le répertoire système pour ne jamais entrer en collision avec une clé d'objet.
+func (s *Storage) bucketConfigDir(bucketName string) string {
+	return filepath.Join(s.BasePath, systemDir, "buckets", escapeSegment(bucketName))
+}
+
+// putBucketConfig enregistre un document de configuration d'un bucket
+func (s *Storage) putBucketConfig(bucketName, name string, data []byte) error {
+	if !s.BucketExists(bucketName) {
+		return ErrNoSuchBucket
+	}
+	dir := s.bucketConfigDir(bucketName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, name), data)
+}
+
+// getBucketConfig lit un document de configuration d'un bucket (os.ErrNotExist s'il n'existe pas)
+func (s *Storage) getBucketConfig(bucketName, name string) ([]byte, error) {
+	if !s.BucketExists(bucketName) {
+		return nil, ErrNoSuchBucket
+	}
+	return os.ReadFile(filepath.Join(s.bucketConfigDir(bucketName), name))
+}
+
+// deleteBucketConfig supprime un document de configuration d'un bucket
+func (s *Storage) deleteBucketConfig(bucketName, name string) error {
+	if !s.BucketExists(bucketName) {
+		return ErrNoSuchBucket
+	}
+	err := os.Remove(filepath.Join(s.bucketConfigDir(bucketName), name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// BucketOwner retourne l'identifiant du propriétaire d'un bucket
+// (vide pour un bucket créé avant le suivi des propriétaires et pas encore migré, voir AssignLegacyOwners)
+func (s *Storage) BucketOwner(bucketName string) (string, error) {
+	if !s.BucketExists(bucketName) {
+		return "", ErrNoSuchBucket
+	}
+	var info bucketInfo
+	err := readJSON(filepath.Join(s.bucketConfigDir(bucketName), "bucket.json"), &info)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return info.Owner, err
+}
+
+// AssignLegacyOwners attribue owner aux buckets sans propriétaire enregistré (créés avant le suivi des
+// propriétaires, ou dont la création a été interrompue) et retourne le nombre de buckets migrés.
+// Un bucket sans propriétaire n'est accessible qu'au travers de sa politique et de ses ACL.
+func (s *Storage) AssignLegacyOwners(owner string) (int, error) {
+	if owner == "" {
+		return 0, errors.New("propriétaire des buckets hérités non renseigné")
+	}
+	buckets, err := s.ListBuckets()
+	if err != nil {
+		return 0, err
+	}
+	assigned := 0
+	for _, bucket := range buckets {
+		existing, err := s.BucketOwner(bucket.Name)
+		if err != nil {
+			return assigned, err
+		}
+		if existing != "" {
+			continue
+		}
+		info, err := json.Marshal(bucketInfo{Owner: owner})
+		if err != nil {
+			return assigned, err
+		}
+		if err := s.putBucketConfig(bucket.Name, "bucket.json", info); err != nil {
+			return assigned, err
+		}
+		log.Printf("Bucket %s sans propriétaire attribué à %s", bucket.Name, owner)
+		assigned++
+	}
+	return assigned, nil
+}
+
+// PutBucketPolicy enregistre la politique (document JSON déjà validé) d'un bucket
+func (s *Storage) PutBucketPolicy(bucketName string, policy []byte) error {
+	return s.putBucketConfig(bucketName, "policy.json", policy)
+}
+
+// GetBucketPolicy retourne la politique d'un bucket
+func (s *Storage) GetBucketPolicy(bucketName string) ([]byte, error) {
+	data, err := s.getBucketConfig(bucketName, "policy.json")
+	if os.IsNotExist(err) {
+		return nil, ErrNoSuchBucketPolicy
+	}
+	return data, err
+}
+
+// DeleteBucketPolicy supprime la politique d'un bucket
+func (s *Storage) DeleteBucketPolicy(bucketName string) error {
+	return s.deleteBucketConfig(bucketName, "policy.json")
+}
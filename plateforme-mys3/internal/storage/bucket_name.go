@@ -0,0 +1,57 @@
+// internal/storage/bucket_name.go
+package storage
+
+import (
+	"net"
+	"strings"
+)
+
+// Préfixes et suffixes réservés par S3 pour ses propres ressources
+var (
+	reservedBucketPrefixes = []string{"xn--", "sthree-", "amzn-s3-demo-"}
+	reservedBucketSuffixes = []string{"-s3alias", "--ol-s3", ".mrap", "--x-s3", "--table-s3"}
+)
+
+// validBucketName applique les règles de nommage des buckets S3 : 3 à 63 caractères parmi les minuscules,
+// chiffres, points et tirets, commençant et finissant par une lettre ou un chiffre, sans ".." ni forme
+// d'adresse IP, et sans préfixe ni suffixe réservé. Un nom valide est aussi un nom de répertoire sûr.
+func validBucketName(bucketName string) bool {
+	if len(bucketName) < 3 || len(bucketName) > 63 {
+		return false
+	}
+	for i := 0; i < len(bucketName); i++ {
+		c := bucketName[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '.' && c != '-' {
+			return false
+		}
+	}
+	if !isAlphanumeric(bucketName[0]) || !isAlphanumeric(bucketName[len(bucketName)-1]) {
+		return false
+	}
+	if strings.Contains(bucketName, "..") || net.ParseIP(bucketName) != nil {
+		return false
+	}
+	for _, prefix := range reservedBucketPrefixes {
+		if strings.HasPrefix(bucketName, prefix) {
+			return false
+		}
+	}
+	for _, suffix := range reservedBucketSuffixes {
+		if strings.HasSuffix(bucketName, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+// existingBucketError retourne l'erreur de création d'un bucket déjà existant, selon son propriétaire.
+func existingBucketError(existingOwner, owner string) error {
+	if existingOwner == owner {
+		return ErrBucketAlreadyOwnedByYou
+	}
+	return ErrBucketAlreadyExists
+}
@@ -0,0 +1,58 @@
+// internal/storage/checksum.go
+package storage
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"hash/crc32"
+)
+
+// Algorithmes de somme de contrôle supplémentaire (x-amz-checksum-*) enregistrés avec un objet
+const (
+	ChecksumCRC32  = "CRC32"
+	ChecksumCRC32C = "CRC32C"
+	ChecksumSHA1   = "SHA1"
+	ChecksumSHA256 = "SHA256"
+)
+
+// ChecksumAlgorithms liste les algorithmes de somme de contrôle pris en charge
+var ChecksumAlgorithms = []string{ChecksumCRC32, ChecksumCRC32C, ChecksumSHA1, ChecksumSHA256}
+
+// NewChecksumHash retourne un hash pour un algorithme de somme de contrôle (false s'il est inconnu).
+// Comme sur S3, les CRC sont encodés en big-endian : hash.Sum donne directement la valeur à encoder.
+func NewChecksumHash(algorithm string) (hash.Hash, bool) {
+	switch algorithm {
+	case ChecksumCRC32:
+		return crc32.NewIEEE(), true
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), true
+	case ChecksumSHA1:
+		return sha1.New(), true
+	case ChecksumSHA256:
+		return sha256.New(), true
+	default:
+		return nil, false
+	}
+}
+
+// checksumHash retourne le hash de la somme de contrôle à calculer pour un objet (nil si aucune n'est demandée)
+func checksumHash(metadata ObjectMetadata) (hash.Hash, error) {
+	if metadata.ChecksumAlgorithm == "" {
+		return nil, nil
+	}
+	h, ok := NewChecksumHash(metadata.ChecksumAlgorithm)
+	if !ok {
+		return nil, ErrInvalidChecksumAlgorithm
+	}
+	return h, nil
+}
+
+// encodeChecksum encode une somme de contrôle comme les en-têtes x-amz-checksum-* (base64)
+func encodeChecksum(h hash.Hash) string {
+	if h == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
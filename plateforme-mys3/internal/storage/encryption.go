@@ -0,0 +1,131 @@
+// internal/storage/encryption.go
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// BucketEncryption est la configuration de chiffrement par défaut d'un bucket : les objets écrits sans
+// en-tête de chiffrement y sont chiffrés avec SSEAlgorithm (SSE-S3)
+type BucketEncryption struct {
+	SSEAlgorithm string `json:"sseAlgorithm"`
+	// BucketKeyEnabled est conservé et renvoyé tel quel : S3 ne l'applique qu'aux clés KMS
+	BucketKeyEnabled bool `json:"bucketKeyEnabled,omitempty"`
+}
+
+// validate vérifie une configuration de chiffrement avant son enregistrement
+func (c BucketEncryption) validate(masterKey []byte) error {
+	if c.SSEAlgorithm != SSEAlgorithm {
+		return ErrInvalidEncryptionAlgorithm
+	}
+	// Sans clé maître, toutes les écritures du bucket échoueraient
+	if masterKey == nil {
+		return ErrSSENotConfigured
+	}
+	return nil
+}
+
+// PutBucketEncryption enregistre le chiffrement par défaut d'un bucket
+func (s *Storage) PutBucketEncryption(bucketName string, config BucketEncryption) error {
+	if err := config.validate(s.MasterKey); err != nil {
+		return err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return s.putBucketConfig(bucketName, "encryption.json", data)
+}
+
+// GetBucketEncryption retourne le chiffrement par défaut d'un bucket (ErrNoSuchEncryptionConfiguration s'il n'en a pas)
+func (s *Storage) GetBucketEncryption(bucketName string) (BucketEncryption, error) {
+	var config BucketEncryption
+	data, err := s.getBucketConfig(bucketName, "encryption.json")
+	if os.IsNotExist(err) {
+		return config, ErrNoSuchEncryptionConfiguration
+	}
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// DeleteBucketEncryption supprime le chiffrement par défaut d'un bucket ; les objets déjà chiffrés le restent
+func (s *Storage) DeleteBucketEncryption(bucketName string) error {
+	return s.deleteBucketConfig(bucketName, "encryption.json")
+}
+
+// RewrapDataKey réenveloppe avec MasterKey la clé de données d'une version SSE-S3 encore enveloppée par
+// PreviousMasterKey, et indique si elle l'a été. Les autres versions (en clair, SSE-C, déjà à jour) sont ignorées.
+func (s *Storage) RewrapDataKey(bucketName, objectName, versionID string) (bool, error) {
+	unlock := s.locks.lock(bucketName, objectName)
+	defer unlock()
+	metadata, isCurrent, err := s.findVersion(bucketName, objectName, versionID)
+	if err != nil {
+		return false, err
+	}
+	rewrapped, err := rewrapDataKey(&metadata, s.MasterKey, s.PreviousMasterKey)
+	if err != nil || !rewrapped {
+		return false, err
+	}
+	if isCurrent {
+		return true, s.putObjectMetadata(bucketName, objectName, metadata)
+	}
+	return true, writeJSON(s.versionMetadataPath(bucketName, objectName, versionIDOf(metadata)), metadata)
+}
+
+// RotationProgress décrit l'avancement d'une rotation de la clé maître
+type RotationProgress struct {
+	Bucket    string // dernier bucket parcouru
+	Buckets   int    // buckets parcourus
+	Versions  int    // versions examinées
+	Rewrapped int    // clés de données réenveloppées
+}
+
+// RotateDataKeys réenveloppe avec la clé maître du backend toutes les clés de données SSE-S3 encore enveloppées
+// par la clé maître précédente, version par version, dans tous les buckets. progress, s'il est fourni, est
+// appelé après chaque bucket. Une rotation interrompue peut être relancée : les versions déjà traitées sont ignorées.
+// Opération d'administration : elle n'est exposée par aucune route S3.
+func RotateDataKeys(ctx context.Context, b Backend, progress func(RotationProgress)) (RotationProgress, error) {
+	var p RotationProgress
+	buckets, err := b.ListBuckets()
+	if err != nil {
+		return p, err
+	}
+	for _, bucket := range buckets {
+		err := b.WalkObjectVersions(bucket.Name, "", func(versions []ObjectVersion) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			for _, version := range versions {
+				p.Versions++
+				info := version.Metadata.Encryption
+				if version.Metadata.DeleteMarker || info == nil || info.CustomerProvided() {
+					continue
+				}
+				rewrapped, err := b.RewrapDataKey(bucket.Name, version.Key, version.Metadata.VersionID)
+				// Une version supprimée depuis le listing n'a plus rien à réenvelopper
+				if err != nil && !os.IsNotExist(err) && !errors.Is(err, ErrNoSuchVersion) {
+					return err
+				}
+				if rewrapped {
+					p.Rewrapped++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return p, err
+		}
+		p.Bucket = bucket.Name
+		p.Buckets++
+		if progress != nil {
+			progress(p)
+		}
+	}
+	return p, nil
+}
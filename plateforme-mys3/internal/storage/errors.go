@@ -0,0 +1,35 @@
+// internal/storage/errors.go
+package storage
+
+import "errors"
+
+// Erreurs retournées par le stockage, à traduire en codes S3 par les handlers
+var (
+	ErrNoSuchBucket             = errors.New("storage: bucket inexistant")
+	ErrInvalidBucketName        = errors.New("storage: nom de bucket invalide")
+	ErrBucketAlreadyOwnedByYou  = errors.New("storage: bucket déjà créé par ce propriétaire")
+	ErrBucketAlreadyExists      = errors.New("storage: bucket déjà créé par un autre propriétaire")
+	ErrBucketNotEmpty           = errors.New("storage: bucket non vide")
+	ErrNoSuchBucketPolicy       = errors.New("storage: aucune politique pour ce bucket")
+	ErrNoSuchUpload             = errors.New("storage: upload multipart inexistant")
+	ErrInvalidPart              = errors.New("storage: part invalide")
+	ErrInvalidPartOrder         = errors.New("storage: parts non triées par numéro croissant")
+	ErrEntityTooSmall           = errors.New("storage: part trop petite")
+	ErrInvalidPartNumber        = errors.New("storage: numéro de part hors limites")
+	ErrMalformedUploadXML       = errors.New("storage: liste de parts vide")
+	ErrInvalidChecksumAlgorithm = errors.New("storage: algorithme de somme de contrôle inconnu")
+
+	ErrSSENotConfigured       = errors.New("storage: aucune clé maître pour le chiffrement SSE-S3")
+	ErrInvalidSSEKey          = errors.New("storage: clé de chiffrement de taille invalide")
+	ErrSSECustomerKeyRequired = errors.New("storage: objet chiffré avec une clé client (SSE-C), clé absente")
+	ErrSSECustomerKeyMismatch = errors.New("storage: clé client (SSE-C) différente de celle de l'objet")
+	ErrSSENotApplicable       = errors.New("storage: clé client (SSE-C) fournie pour un objet qui n'en utilise pas")
+	ErrEncryptedWithoutKey    = errors.New("storage: contenu chiffré sans métadonnées de chiffrement")
+
+	ErrNoSuchEncryptionConfiguration = errors.New("storage: aucun chiffrement par défaut pour ce bucket")
+	ErrInvalidEncryptionAlgorithm    = errors.New("storage: algorithme de chiffrement par défaut non pris en charge")
+	ErrNoSuchLifecycleConfiguration  = errors.New("storage: aucun cycle de vie pour ce bucket")
+
+	ErrNoSuchVersion           = errors.New("storage: version inexistante")
+	ErrInvalidVersioningStatus = errors.New("storage: état de versionnage invalide")
+)
@@ -0,0 +1,247 @@
+// internal/storage/journal.go
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"plateforme-mys3/internal/acl"
+	"sort"
+	"strings"
+)
+
+// Journal des écritures d'objets.
+//
+// Mettre en place un objet touche plusieurs fichiers : archivage de la version courante, renommage du contenu,
+// ACL et métadonnées. Avant la première de ces étapes, l'écriture est décrite dans une entrée du journal
+// (contenu préparé, métadonnées complètes, état du versionnage), synchronisée sur disque : c'est le point de
+// validation. Chaque étape peut être rejouée sans effet de bord ; après une panne, RecoverWrites rejoue les
+// entrées restantes au démarrage, dans l'ordre des écritures. La clé n'est donc jamais laissée sans version
+// courante, ni son contenu décrit par les métadonnées (et la clé de chiffrement) d'un autre contenu.
+//
+// Un marqueur de suppression passe par le même journal, sans contenu : archivage de la version courante,
+// retrait de la clé, puis enregistrement du marqueur, qui vaut validation de la suppression.
+
+// writeIntent est une entrée du journal : une écriture d'objet validée mais pas encore entièrement en place
+type writeIntent struct {
+	Bucket     string         `json:"bucket"`
+	Key        string         `json:"key"`
+	Data       string         `json:"data"`          // nom du fichier temporaire du contenu dans tmpDir, jusqu'à son renommage ; vide pour un marqueur de suppression
+	Versioning string         `json:"versioning"`    // état du versionnage du bucket au moment de l'écriture
+	Metadata   ObjectMetadata `json:"metadata"`      // métadonnées complètes de la nouvelle version
+	ACL        *acl.ACL       `json:"acl,omitempty"` // ACL de la nouvelle version ; nil pour l'ACL par défaut
+}
+
+// writeFailpoint, s'il est défini, est appelé avant chaque étape de la mise en place d'une écriture ;
+// une erreur interrompt l'écriture à cette étape (tests : simulation d'une panne)
+var writeFailpoint func(step string) error
+
+// journalDir retourne le répertoire des entrées du journal des écritures
+func (s *Storage) journalDir() string {
+	return filepath.Join(s.BasePath, systemDir, "journal")
+}
+
+// beginWrite enregistre une écriture dans le journal et retourne le chemin de son entrée.
+// Le nom de l'entrée est ordonné chronologiquement, comme un identifiant de version.
+func (s *Storage) beginWrite(intent writeIntent) (string, error) {
+	if err := os.MkdirAll(s.journalDir(), 0755); err != nil {
+		return "", err
+	}
+	id, err := newVersionID()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.journalDir(), id+".json")
+	return path, writeJSON(path, intent)
+}
+
+// completeWrite met en place une écriture du journal ; l'appelant détient le verrou de la clé.
+// Chaque étape reprend là où une exécution interrompue s'est arrêtée.
+func (s *Storage) completeWrite(intent writeIntent) error {
+	if intent.Metadata.DeleteMarker {
+		return s.completeDeleteMarker(intent)
+	}
+	bucketName, objectName := intent.Bucket, intent.Key
+	objectPath := s.ObjectPath(bucketName, objectName)
+	dataPath := filepath.Join(s.tmpDir(), intent.Data)
+
+	if _, err := os.Stat(dataPath); err == nil {
+		if err := failpoint("archive"); err != nil {
+			return err
+		}
+		if err := s.archiveForWrite(intent); err != nil {
+			return err
+		}
+
+		if err := failpoint("rename"); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(dataPath, objectPath); err != nil {
+			return err
+		}
+		if err := syncDir(filepath.Dir(objectPath)); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Le contenu est en place : ses métadonnées sont écrites en dernier, une fois l'ACL de la nouvelle version
+	// posée (ou celle de l'objet remplacé retirée). Des métadonnées déjà à jour signifient que l'écriture était
+	// terminée (ACL éventuellement modifiée depuis comprise) : rien n'est refait.
+	var current ObjectMetadata
+	err := readJSON(s.objectSidecarPath(bucketName, objectName, "meta"), &current)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil && sameVersion(current, intent.Metadata) {
+		return nil
+	}
+	if err := failpoint("acl"); err != nil {
+		return err
+	}
+	if intent.ACL != nil {
+		if err := s.putObjectACL(bucketName, objectName, *intent.ACL); err != nil {
+			return err
+		}
+	} else if err := s.removeSidecar(bucketName, objectName, "acl"); err != nil {
+		return err
+	}
+	if err := failpoint("metadata"); err != nil {
+		return err
+	}
+	return s.putObjectMetadata(bucketName, objectName, intent.Metadata)
+}
+
+// completeDeleteMarker met en place un marqueur de suppression du journal ; chaque étape peut être rejouée
+func (s *Storage) completeDeleteMarker(intent writeIntent) error {
+	if err := failpoint("archive"); err != nil {
+		return err
+	}
+	if err := s.archiveForWrite(intent); err != nil {
+		return err
+	}
+	if err := failpoint("remove"); err != nil {
+		return err
+	}
+	if err := s.removeCurrent(intent.Bucket, intent.Key); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := failpoint("metadata"); err != nil {
+		return err
+	}
+	return s.writeDeleteMarker(intent.Bucket, intent.Key, intent.Metadata)
+}
+
+// archiveForWrite archive la version courante que va remplacer une écriture, selon le versionnage du bucket.
+// La version courante reste en place : elle est remplacée par le renommage du nouveau contenu, ou retirée
+// ensuite pour un marqueur de suppression.
+func (s *Storage) archiveForWrite(intent writeIntent) error {
+	switch intent.Versioning {
+	case VersioningEnabled:
+		return s.linkCurrent(intent.Bucket, intent.Key)
+	case VersioningSuspended:
+		// La nouvelle version "null" remplace l'éventuelle version "null", courante ou archivée
+		if _, _, err := s.removeArchivedVersion(intent.Bucket, intent.Key, NullVersionID); err != nil {
+			return err
+		}
+		current, err := s.getObjectMetadata(intent.Bucket, intent.Key)
+		if os.IsNotExist(err) || (err == nil && versionIDOf(current) == NullVersionID) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return s.linkCurrent(intent.Bucket, intent.Key)
+	}
+	return nil
+}
+
+// sameVersion indique si deux métadonnées décrivent la même écriture
+func sameVersion(a, b ObjectMetadata) bool {
+	return a.ETag == b.ETag && a.VersionID == b.VersionID && a.LastModified.Equal(b.LastModified)
+}
+
+// failpoint appelle writeFailpoint s'il est défini
+func failpoint(step string) error {
+	if writeFailpoint == nil {
+		return nil
+	}
+	return writeFailpoint(step)
+}
+
+// RecoverWrites termine les écritures d'objets interrompues par un arrêt brutal du serveur (voir le journal)
+// et retourne leur nombre. À appeler au démarrage, avant RemoveStaleTempFiles et toute écriture.
+func (s *Storage) RecoverWrites() (int, error) {
+	entries, err := os.ReadDir(s.journalDir())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") && !strings.HasPrefix(entry.Name(), tmpPrefix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	recovered := 0
+	for _, name := range names {
+		path := filepath.Join(s.journalDir(), name)
+		var intent writeIntent
+		if err := readJSON(path, &intent); err != nil {
+			return recovered, fmt.Errorf("entrée du journal %s illisible: %w", name, err)
+		}
+		done, err := s.recoverWrite(intent)
+		if err != nil {
+			return recovered, fmt.Errorf("reprise de l'écriture de %s/%s: %w", intent.Bucket, intent.Key, err)
+		}
+		if err := os.Remove(path); err != nil {
+			return recovered, err
+		}
+		if done {
+			log.Printf("Écriture interrompue de %s/%s terminée", intent.Bucket, intent.Key)
+			recovered++
+		}
+	}
+	return recovered, nil
+}
+
+// recoverWrite rejoue une entrée du journal, sauf si la clé a été écrite ou supprimée depuis
+// (écriture en échec suivie d'une autre) : done indique si l'écriture a été mise en place
+func (s *Storage) recoverWrite(intent writeIntent) (done bool, err error) {
+	if !intent.Metadata.DeleteMarker && (intent.Data == "" || filepath.Base(intent.Data) != intent.Data) {
+		return false, errors.New("fichier de contenu invalide")
+	}
+	unlock := s.locks.lock(intent.Bucket, intent.Key)
+	defer unlock()
+
+	var current ObjectMetadata
+	err = readJSON(s.objectSidecarPath(intent.Bucket, intent.Key, "meta"), &current)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	newer := err == nil && current.LastModified.After(intent.Metadata.LastModified)
+	if !newer {
+		versions, err := s.archivedVersions(intent.Bucket, intent.Key)
+		if err != nil {
+			return false, err
+		}
+		newer = len(versions) > 0 && versions[0].LastModified.After(intent.Metadata.LastModified)
+	}
+	if newer {
+		if intent.Data != "" {
+			os.Remove(filepath.Join(s.tmpDir(), intent.Data))
+		}
+		return false, nil
+	}
+	return true, s.completeWrite(intent)
+}
@@ -0,0 +1,242 @@
+// internal/storage/keypath.go
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encodage des clés S3 en chemins relatifs sûrs.
+//
+// Une clé est découpée sur "/" et chaque segment devient un nom de fichier :
+//   - "%", "/", "\", les caractères de contrôle et un "." initial sont échappés en %XX : aucun nom ne vaut
+//     "." ou "..", ne commence par un point ni ne contient de séparateur ;
+//   - un segment vide (clé "a//b" ou "dir/") devient "%" ;
+//   - un segment suivi d'un "/" est un répertoire suffixé par "%2F" : l'objet "a" (fichier "a") et le préfixe
+//     "a/" (répertoire "a%2F") ne se confondent jamais ;
+//   - un segment trop long pour le système de fichiers est coupé en répertoires suffixés par "%+".
+//
+// L'échappement ne produit jamais "%2F", "%+" ni "%" seul : l'encodage est réversible (voir decodePath).
+// Les clés sans caractère spécial ni "/" ("photo.jpg") sont stockées telles quelles.
+const (
+	dirSuffix          = "%2F"
+	continuationSuffix = "%+"
+	emptySegment       = "%"
+
+	// maxSegmentLen laisse de la marge sous la limite de 255 octets par nom (suffixes, ".meta.json"...)
+	maxSegmentLen = 200
+)
+
+// escapeByte indique si un octet doit être échappé ; first vaut true pour le premier octet d'un nom
+func escapeByte(b byte, first bool) bool {
+	return b == '%' || b == '/' || b == '\\' || b < 0x20 || b == 0x7f || (first && b == '.')
+}
+
+// escapeSegment échappe un segment de clé (ou un nom de bucket) en nom de fichier
+func escapeSegment(segment string) string {
+	if segment == "" {
+		return emptySegment
+	}
+	var sb strings.Builder
+	for i := 0; i < len(segment); i++ {
+		if escapeByte(segment[i], i == 0) {
+			fmt.Fprintf(&sb, "%%%02X", segment[i])
+		} else {
+			sb.WriteByte(segment[i])
+		}
+	}
+	return sb.String()
+}
+
+// unescapeSegment décode un nom produit par escapeSegment ; ok vaut false pour tout autre nom
+func unescapeSegment(name string) (segment string, ok bool) {
+	if name == emptySegment {
+		return "", true
+	}
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '%' {
+			sb.WriteByte(name[i])
+			continue
+		}
+		if i+2 >= len(name) {
+			return "", false
+		}
+		b, err := strconv.ParseUint(name[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+		sb.WriteByte(byte(b))
+		i += 2
+	}
+	segment = sb.String()
+	// Seule la forme canonique d'un segment (sans "/") est acceptée : chaque segment n'a qu'un seul nom possible
+	return segment, segment != "" && !strings.Contains(segment, "/") && escapeSegment(segment) == name
+}
+
+// splitSegment coupe un segment en morceaux dont la forme échappée tient dans maxSegmentLen,
+// sans couper de caractère UTF-8
+func splitSegment(segment string) []string {
+	var chunks []string
+	start, size := 0, 0
+	for i := 0; i < len(segment); {
+		_, width := utf8.DecodeRuneInString(segment[i:])
+		escaped := 0
+		for j := i; j < i+width; j++ {
+			if escapeByte(segment[j], j == start) {
+				escaped += 3
+			} else {
+				escaped++
+			}
+		}
+		if size > 0 && size+escaped > maxSegmentLen {
+			chunks = append(chunks, segment[start:i])
+			start, size = i, 0
+			continue // recalcule l'échappement : le caractère commence maintenant un nom
+		}
+		size += escaped
+		i += width
+	}
+	return append(chunks, segment[start:])
+}
+
+// encodeKey retourne le chemin relatif (séparé par "/") où est rangée une clé
+func encodeKey(key string) string {
+	segments := strings.Split(key, "/")
+	var names []string
+	for i, segment := range segments {
+		chunks := splitSegment(segment)
+		for j, chunk := range chunks {
+			name := escapeSegment(chunk)
+			switch {
+			case j < len(chunks)-1:
+				name += continuationSuffix
+			case i < len(segments)-1:
+				name += dirSuffix
+			}
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// decodePath retourne la clé correspondant à un chemin relatif produit par encodeKey, ou, pour un répertoire,
+// le préfixe de clé qu'il contient. ok vaut false pour un chemin que l'encodage ne produit pas.
+func decodePath(rel string, isDir bool) (key string, ok bool) {
+	var sb strings.Builder
+	names := strings.Split(rel, "/")
+	for i, name := range names {
+		suffix := ""
+		if isDir || i < len(names)-1 {
+			switch {
+			case strings.HasSuffix(name, dirSuffix):
+				suffix = dirSuffix
+			case strings.HasSuffix(name, continuationSuffix):
+				suffix = continuationSuffix
+			default:
+				return "", false
+			}
+		}
+		segment, ok := unescapeSegment(strings.TrimSuffix(name, suffix))
+		if !ok {
+			return "", false
+		}
+		sb.WriteString(segment)
+		if suffix == dirSuffix {
+			sb.WriteByte('/')
+		}
+	}
+	return sb.String(), true
+}
+
+// MigrateLegacyKeys déplace les objets rangés avec l'ancien schéma (clé utilisée directement comme chemin)
+// vers leur chemin encodé, avec leurs métadonnées et leur ACL, et retourne le nombre d'objets déplacés.
+// Les clés simples ("photo.jpg") ont le même chemin dans les deux schémas et ne sont pas touchées.
+func (s *Storage) MigrateLegacyKeys() (int, error) {
+	buckets, err := s.ListBuckets()
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, bucket := range buckets {
+		root := s.BucketPath(bucket.Name)
+		var legacyKeys, legacyDirs []string
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || path == root {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if _, ok := decodePath(rel, entry.IsDir()); ok {
+				return nil
+			}
+			if entry.IsDir() {
+				legacyDirs = append(legacyDirs, path)
+			} else {
+				legacyKeys = append(legacyKeys, rel)
+			}
+			return nil
+		})
+		if err != nil {
+			return moved, err
+		}
+
+		for _, key := range legacyKeys {
+			done, err := s.migrateLegacyKey(bucket.Name, key)
+			if err != nil {
+				return moved, err
+			}
+			if done {
+				moved++
+			}
+		}
+		// Les répertoires de l'ancien schéma sont vides une fois leurs objets déplacés (les plus profonds d'abord)
+		for i := len(legacyDirs) - 1; i >= 0; i-- {
+			os.Remove(legacyDirs[i])
+		}
+	}
+	return moved, nil
+}
+
+// migrateLegacyKey déplace un objet de l'ancien schéma et ses fichiers annexes vers leurs chemins encodés ;
+// moved vaut false si un objet occupe déjà le nouvel emplacement
+func (s *Storage) migrateLegacyKey(bucketName, key string) (moved bool, err error) {
+	oldPath := filepath.Join(s.BucketPath(bucketName), filepath.FromSlash(key))
+	newPath := s.ObjectPath(bucketName, key)
+	if _, err := os.Stat(newPath); err == nil {
+		log.Printf("Objet %s/%s déjà présent au nouvel emplacement, ancien fichier conservé : %s", bucketName, key, oldPath)
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return false, err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return false, err
+	}
+
+	for _, kind := range []string{"meta", "acl"} {
+		oldSidecar := filepath.Join(s.bucketConfigDir(bucketName), "objects", filepath.FromSlash(key)) + "." + kind + ".json"
+		newSidecar := s.objectSidecarPath(bucketName, key, kind)
+		if oldSidecar == newSidecar {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(newSidecar), 0755); err != nil {
+			return false, err
+		}
+		if err := os.Rename(oldSidecar, newSidecar); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	log.Printf("Objet %s/%s migré vers %s", bucketName, key, newPath)
+	return true, nil
+}
@@ -0,0 +1,301 @@
+// internal/storage/lifecycle.go
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// lifecyclePageSize est le nombre d'objets ou de versions listés par page lors de l'évaluation du cycle de vie
+const lifecyclePageSize = 1000
+
+// LifecycleConfiguration est la configuration du cycle de vie d'un bucket (?lifecycle)
+type LifecycleConfiguration struct {
+	Rules []LifecycleRule `json:"rules"`
+}
+
+// LifecycleRule est une règle du cycle de vie : un filtre (préfixe et étiquettes) et des actions.
+// Les durées sont en jours ; 0 (ou une date nulle) signifie que l'action est absente.
+type LifecycleRule struct {
+	ID      string            `json:"id,omitempty"`
+	Enabled bool              `json:"enabled"`
+	Prefix  string            `json:"prefix,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"` // étiquettes que l'objet doit toutes porter
+
+	ExpirationDays      int       `json:"expirationDays,omitempty"`      // expiration de la version courante, depuis son écriture
+	ExpirationDate      time.Time `json:"expirationDate"`                // expiration de la version courante à une date fixe
+	NoncurrentDays      int       `json:"noncurrentDays,omitempty"`      // suppression des versions non courantes, depuis leur remplacement
+	AbortIncompleteDays int       `json:"abortIncompleteDays,omitempty"` // annulation des uploads multipart, depuis leur création
+}
+
+// matches indique si une règle active s'applique à une clé portant ces étiquettes
+func (r LifecycleRule) matches(key string, tags map[string]string) bool {
+	if !r.Enabled || !strings.HasPrefix(key, r.Prefix) {
+		return false
+	}
+	for name, value := range r.Tags {
+		if actual, ok := tags[name]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// expired indique si la version courante d'un objet écrit à lastModified a expiré selon la règle
+func (r LifecycleRule) expired(lastModified, now time.Time) bool {
+	if r.ExpirationDays > 0 && !now.Before(lifecycleDeadline(lastModified, r.ExpirationDays)) {
+		return true
+	}
+	return !r.ExpirationDate.IsZero() && !now.Before(r.ExpirationDate)
+}
+
+// lifecycleDeadline retourne l'échéance d'une durée de days jours comptée depuis t,
+// arrondie comme sur S3 au minuit UTC suivant
+func lifecycleDeadline(t time.Time, days int) time.Time {
+	deadline := t.UTC().AddDate(0, 0, days)
+	midnight := deadline.Truncate(24 * time.Hour)
+	if midnight.Before(deadline) {
+		midnight = midnight.Add(24 * time.Hour)
+	}
+	return midnight
+}
+
+// PutBucketLifecycle enregistre la configuration du cycle de vie (déjà validée) d'un bucket
+func (s *Storage) PutBucketLifecycle(bucketName string, config LifecycleConfiguration) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return s.putBucketConfig(bucketName, "lifecycle.json", data)
+}
+
+// GetBucketLifecycle retourne la configuration du cycle de vie d'un bucket
+// (ErrNoSuchLifecycleConfiguration s'il n'en a pas)
+func (s *Storage) GetBucketLifecycle(bucketName string) (LifecycleConfiguration, error) {
+	var config LifecycleConfiguration
+	data, err := s.getBucketConfig(bucketName, "lifecycle.json")
+	if os.IsNotExist(err) {
+		return config, ErrNoSuchLifecycleConfiguration
+	}
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// DeleteBucketLifecycle supprime la configuration du cycle de vie d'un bucket
+func (s *Storage) DeleteBucketLifecycle(bucketName string) error {
+	return s.deleteBucketConfig(bucketName, "lifecycle.json")
+}
+
+// LifecycleResult compte les actions d'une évaluation du cycle de vie (à effectuer en simulation)
+type LifecycleResult struct {
+	Expired           int // versions courantes expirées
+	NoncurrentExpired int // versions non courantes supprimées
+	AbortedUploads    int // uploads multipart annulés
+	Failed            int // actions en échec, réessayées à la prochaine évaluation
+}
+
+// lifecycleAction est une action décidée par une règle, appliquée une fois le bucket entièrement évalué
+type lifecycleAction struct {
+	rule      string
+	key       string
+	versionID string         // version non courante à supprimer
+	uploadID  string         // upload multipart à annuler
+	current   ObjectMetadata // version courante à expirer, vérifiée inchangée avant suppression
+}
+
+// ApplyLifecycle évalue les règles du cycle de vie de tous les buckets à l'instant now et supprime les objets
+// expirés, les versions non courantes échues et les uploads multipart abandonnés. Chaque action est journalisée ;
+// avec dryRun, elles le sont sans être effectuées.
+func ApplyLifecycle(ctx context.Context, b Backend, now time.Time, dryRun bool) (LifecycleResult, error) {
+	var result LifecycleResult
+	buckets, err := b.ListBuckets()
+	if err != nil {
+		return result, err
+	}
+	for _, bucket := range buckets {
+		config, err := b.GetBucketLifecycle(bucket.Name)
+		if errors.Is(err, ErrNoSuchLifecycleConfiguration) || errors.Is(err, ErrNoSuchBucket) {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		actions, err := evaluateLifecycle(ctx, b, bucket.Name, config, now)
+		if err != nil {
+			return result, err
+		}
+		for _, action := range actions {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			applyLifecycleAction(b, bucket.Name, action, dryRun, &result)
+		}
+	}
+	return result, nil
+}
+
+// evaluateLifecycle liste les actions dues dans un bucket. Le bucket est parcouru en entier avant toute
+// suppression : les listings et parcours ne sont pas perturbés par les actions.
+func evaluateLifecycle(ctx context.Context, b Backend, bucketName string, config LifecycleConfiguration, now time.Time) ([]lifecycleAction, error) {
+	var actions []lifecycleAction
+	var expiration, noncurrent, abort bool
+	for _, rule := range config.Rules {
+		expiration = expiration || (rule.Enabled && (rule.ExpirationDays > 0 || !rule.ExpirationDate.IsZero()))
+		noncurrent = noncurrent || (rule.Enabled && rule.NoncurrentDays > 0)
+		abort = abort || (rule.Enabled && rule.AbortIncompleteDays > 0)
+	}
+
+	// Versions courantes, d'après le listing des objets
+	opts := ListObjectsOptions{MaxKeys: lifecyclePageSize}
+	for expiration {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := b.ListObjects(bucketName, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Objects {
+			for _, rule := range config.Rules {
+				if rule.matches(object.Key, object.Metadata.Tags) && rule.expired(object.Metadata.LastModified, now) {
+					actions = append(actions, lifecycleAction{rule: rule.ID, key: object.Key, current: object.Metadata})
+					break
+				}
+			}
+		}
+		if !page.IsTruncated {
+			break
+		}
+		opts.StartAfter = page.NextMarker
+	}
+
+	// Versions non courantes : une version le devient quand la suivante, plus récente, est écrite
+	if noncurrent {
+		err := b.WalkObjectVersions(bucketName, "", func(versions []ObjectVersion) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			for i, version := range versions {
+				if version.IsLatest {
+					continue
+				}
+				noncurrentSince := versions[i-1].Metadata.LastModified
+				for _, rule := range config.Rules {
+					if rule.NoncurrentDays > 0 && rule.matches(version.Key, version.Metadata.Tags) &&
+						!now.Before(lifecycleDeadline(noncurrentSince, rule.NoncurrentDays)) {
+						actions = append(actions, lifecycleAction{rule: rule.ID, key: version.Key, versionID: version.Metadata.VersionID})
+						break
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Uploads multipart abandonnés ; les filtres par étiquettes ne s'y appliquent pas
+	if abort {
+		uploads, err := b.ListMultipartUploads(bucketName)
+		if err != nil {
+			return nil, err
+		}
+		for _, upload := range uploads {
+			for _, rule := range config.Rules {
+				if rule.AbortIncompleteDays > 0 && rule.matches(upload.Key, nil) &&
+					!now.Before(lifecycleDeadline(upload.Initiated, rule.AbortIncompleteDays)) {
+					actions = append(actions, lifecycleAction{rule: rule.ID, key: upload.Key, uploadID: upload.UploadID})
+					break
+				}
+			}
+		}
+	}
+	return actions, nil
+}
+
+// applyLifecycleAction effectue (ou simule) une action du cycle de vie et la comptabilise
+func applyLifecycleAction(b Backend, bucketName string, action lifecycleAction, dryRun bool, result *LifecycleResult) {
+	prefix := "Cycle de vie"
+	if dryRun {
+		prefix = "Cycle de vie (simulation)"
+	}
+	var err error
+	switch {
+	case action.uploadID != "":
+		log.Printf("%s : annulation de l'upload %s de %s/%s (règle %q)", prefix, action.uploadID, bucketName, action.key, action.rule)
+		if !dryRun {
+			err = b.AbortMultipartUpload(bucketName, action.key, action.uploadID)
+		}
+		if err == nil {
+			result.AbortedUploads++
+		}
+	case action.versionID != "":
+		log.Printf("%s : suppression de la version %s de %s/%s (règle %q)", prefix, action.versionID, bucketName, action.key, action.rule)
+		if !dryRun {
+			_, err = b.DeleteObject(bucketName, action.key, action.versionID)
+		}
+		if err == nil {
+			result.NoncurrentExpired++
+		}
+	default:
+		log.Printf("%s : expiration de %s/%s (règle %q)", prefix, bucketName, action.key, action.rule)
+		expired := true
+		if !dryRun {
+			expired, err = expireCurrent(b, bucketName, action)
+		}
+		if err == nil && expired {
+			result.Expired++
+		}
+	}
+	if err != nil {
+		result.Failed++
+		log.Printf("Erreur du cycle de vie sur %s/%s: %v", bucketName, action.key, err)
+	}
+}
+
+// expireCurrent supprime la version courante d'un objet (marqueur de suppression si le bucket est versionné),
+// sauf si elle a été remplacée ou supprimée depuis l'évaluation des règles ; expired indique si elle l'a été
+func expireCurrent(b Backend, bucketName string, action lifecycleAction) (expired bool, err error) {
+	current, err := b.GetObjectVersionMetadata(bucketName, action.key, "")
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if current.DeleteMarker || current.ETag != action.current.ETag || !current.LastModified.Equal(action.current.LastModified) {
+		log.Printf("Cycle de vie : %s/%s modifié depuis l'évaluation, expiration reportée", bucketName, action.key)
+		return false, nil
+	}
+	_, err = b.DeleteObject(bucketName, action.key, "")
+	return err == nil, err
+}
+
+// RunLifecycle évalue périodiquement le cycle de vie de tous les buckets jusqu'à l'annulation du contexte
+func RunLifecycle(ctx context.Context, b Backend, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			log.Println("Évaluation du cycle de vie des buckets")
+			result, err := ApplyLifecycle(ctx, b, now, dryRun)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Erreur lors de l'évaluation du cycle de vie: %v", err)
+			}
+			log.Printf("Cycle de vie : %d objet(s) expiré(s), %d version(s) non courante(s) supprimée(s), %d upload(s) annulé(s), %d échec(s)",
+				result.Expired, result.NoncurrentExpired, result.AbortedUploads, result.Failed)
+		}
+	}
+}
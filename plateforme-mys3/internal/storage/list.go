@@ -0,0 +1,256 @@
+// internal/storage/list.go
+package storage
+
+import (
+	"container/heap"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ListObjectsOptions décrit une page de listing d'objets
+type ListObjectsOptions struct {
+	Prefix     string // seules les clés commençant par ce préfixe sont listées
+	Delimiter  string // les clés contenant le délimiteur après le préfixe sont regroupées en préfixes communs
+	StartAfter string // seules les clés (et préfixes communs) strictement supérieures sont listées
+	MaxKeys    int    // nombre maximal de clés et de préfixes communs retournés
+}
+
+// ObjectInfo décrit un objet listé
+type ObjectInfo struct {
+	Key      string
+	Metadata ObjectMetadata
+}
+
+// ListObjectsResult est une page de listing d'objets
+type ListObjectsResult struct {
+	Objects        []ObjectInfo
+	CommonPrefixes []string
+	IsTruncated    bool
+	NextMarker     string // dernière clé ou dernier préfixe commun retourné, à reprendre pour la page suivante
+}
+
+// ListObjects liste les objets d'un bucket, sous-répertoires compris, par ordre lexicographique des clés.
+// Le parcours commence à StartAfter et s'arrête dès que la page est complète : lister un bucket page par
+// page ne relit pas les clés des pages précédentes.
+func (s *Storage) ListObjects(bucketName string, opts ListObjectsOptions) (ListObjectsResult, error) {
+	var result ListObjectsResult
+	if !s.BucketExists(bucketName) {
+		return result, ErrNoSuchBucket
+	}
+
+	// Un StartAfter qui est un préfixe commun (NextMarker d'une page précédente) couvre toutes ses clés
+	from := ""
+	if opts.StartAfter != "" {
+		from = opts.StartAfter + "\x00"
+		if commonPrefixOf(opts.StartAfter, opts) == opts.StartAfter {
+			if end, ok := prefixEnd(opts.StartAfter); ok {
+				from = end
+			}
+		}
+	}
+
+	// Les métadonnées sont lues pendant le parcours : une clé supprimée entre-temps ne compte pas dans la page.
+	// Le parcours s'arrête à la première clé de l'entrée (clé ou préfixe commun) qui suit la page, qui indique
+	// que la page est tronquée.
+	var keys []string
+	loaded := make(map[string]ObjectMetadata)
+	entries, last := 0, ""
+	err := s.walkObjects(bucketName, opts.Prefix, from, func(objectName string) error {
+		entry := commonPrefixOf(objectName, opts)
+		if entry == "" {
+			metadata, err := s.GetObjectMetadata(bucketName, objectName)
+			if os.IsNotExist(err) {
+				// Objet supprimé pendant le listing
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			loaded[objectName] = metadata
+			entry = objectName
+		} else if entry <= opts.StartAfter {
+			return nil
+		}
+		keys = append(keys, objectName)
+		if entry != last {
+			entries++
+			last = entry
+		}
+		if entries > opts.MaxKeys {
+			return errStopWalk
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return paginateObjects(keys, opts, func(key string) (ObjectMetadata, bool, error) {
+		metadata, found := loaded[key]
+		return metadata, found, nil
+	})
+}
+
+// prefixEnd retourne la plus petite chaîne supérieure à toutes celles qui commencent par prefix ;
+// ok vaut false s'il n'y en a pas (préfixe fait d'octets 0xff)
+func prefixEnd(prefix string) (string, bool) {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1]), true
+		}
+	}
+	return "", false
+}
+
+// paginateObjects construit une page de listing à partir de clés triées : StartAfter, regroupement
+// par délimiteur puis MaxKeys. load retourne les métadonnées d'une clé, found valant false si elle a disparu.
+func paginateObjects(keys []string, opts ListObjectsOptions, load func(key string) (metadata ObjectMetadata, found bool, err error)) (ListObjectsResult, error) {
+	var result ListObjectsResult
+	count := 0
+	for _, key := range keys {
+		if key <= opts.StartAfter {
+			continue
+		}
+
+		commonPrefix := commonPrefixOf(key, opts)
+		if commonPrefix != "" {
+			// Les clés d'un même préfixe commun se suivent : il n'est compté qu'une fois
+			if commonPrefix <= opts.StartAfter ||
+				(len(result.CommonPrefixes) > 0 && result.CommonPrefixes[len(result.CommonPrefixes)-1] == commonPrefix) {
+				continue
+			}
+		}
+
+		if count >= opts.MaxKeys {
+			result.IsTruncated = count > 0
+			break
+		}
+		count++
+
+		if commonPrefix != "" {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix)
+			result.NextMarker = commonPrefix
+			continue
+		}
+		metadata, found, err := load(key)
+		if err != nil {
+			return result, err
+		}
+		if !found {
+			count--
+			continue
+		}
+		result.Objects = append(result.Objects, ObjectInfo{Key: key, Metadata: metadata})
+		result.NextMarker = key
+	}
+	return result, nil
+}
+
+// commonPrefixOf retourne le préfixe commun sous lequel une clé est regroupée (vide sans délimiteur après le préfixe)
+func commonPrefixOf(key string, opts ListObjectsOptions) string {
+	if opts.Delimiter == "" {
+		return ""
+	}
+	rest := strings.TrimPrefix(key, opts.Prefix)
+	if i := strings.Index(rest, opts.Delimiter); i >= 0 {
+		return opts.Prefix + rest[:i+len(opts.Delimiter)]
+	}
+	return ""
+}
+
+// walkObjects appelle fn, par ordre lexicographique des clés, pour chaque objet d'un bucket dont la clé commence
+// par prefix et n'est pas inférieure à from (vide : pas de borne). fn peut retourner errStopWalk pour arrêter le
+// parcours. Les répertoires sont lus dans l'ordre des préfixes de clés qu'ils représentent, un objet n'étant
+// visité qu'une fois toutes les clés inférieures visitées ; ceux qui ne peuvent contenir aucune clé
+// correspondante (préfixe ou borne) ne sont pas lus.
+func (s *Storage) walkObjects(bucketName, prefix, from string, fn func(objectName string) error) error {
+	root := s.BucketPath(bucketName)
+	queue := &walkQueue{}
+	if err := queue.pushDir(root, "", prefix, from); err != nil {
+		return err
+	}
+	for queue.Len() > 0 {
+		entry := heap.Pop(queue).(walkEntry)
+		if entry.isDir {
+			if err := queue.pushDir(root, entry.path, prefix, from); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(entry.key); err != nil {
+			if err == errStopWalk {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// walkEntry est un objet ou un répertoire à visiter par walkObjects
+type walkEntry struct {
+	key   string // clé de l'objet, ou préfixe commun à toutes les clés rangées sous le répertoire
+	path  string // chemin relatif à la racine du bucket, séparé par des "/"
+	isDir bool
+}
+
+// walkQueue est la file de priorité de walkObjects, ordonnée par clé. Les clés rangées sous un répertoire
+// commençant par son préfixe, aucune entrée ajoutée en le lisant ne précède celles déjà visitées ; à préfixe
+// égal, l'objet passe avant le répertoire, dont les clés sont plus longues.
+type walkQueue []walkEntry
+
+func (q walkQueue) Len() int      { return len(q) }
+func (q walkQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q walkQueue) Less(i, j int) bool {
+	if q[i].key != q[j].key {
+		return q[i].key < q[j].key
+	}
+	return !q[i].isDir && q[j].isDir
+}
+func (q *walkQueue) Push(x interface{}) { *q = append(*q, x.(walkEntry)) }
+func (q *walkQueue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// pushDir ajoute à la file les entrées d'un répertoire (rel, vide pour la racine du bucket) qui peuvent
+// correspondre à prefix et à from
+func (q *walkQueue) pushDir(root, rel, prefix, from string) error {
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		if rel != "" && os.IsNotExist(err) {
+			// Supprimé pendant le parcours
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		path := entry.Name()
+		if rel != "" {
+			path = rel + "/" + path
+		}
+		key, ok := decodePath(path, entry.IsDir())
+		if !ok {
+			// Fichier étranger à l'encodage des clés (ancien schéma non migré...)
+			continue
+		}
+		if entry.IsDir() {
+			// Toutes les clés du répertoire commencent par key : il est ignoré si aucune ne peut correspondre
+			if !strings.HasPrefix(key, prefix) && !strings.HasPrefix(prefix, key) {
+				continue
+			}
+			if key < from && !strings.HasPrefix(from, key) {
+				continue
+			}
+		} else if !strings.HasPrefix(key, prefix) || key < from {
+			continue
+		}
+		heap.Push(q, walkEntry{key: key, path: path, isDir: entry.IsDir()})
+	}
+	return nil
+}
@@ -0,0 +1,878 @@
+// internal/storage/memory.go
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"plateforme-mys3/internal/acl"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryBackend est un Backend entièrement en mémoire, sans persistance.
+// Il sert aux tests rapides et reproduit la sémantique de Storage (versionnage, ACL par défaut, multipart...).
+type MemoryBackend struct {
+	// MasterKey et PreviousMasterKey sont les clés maîtres SSE-S3, comme pour Storage
+	MasterKey         []byte
+	PreviousMasterKey []byte
+
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	uploads map[string]*memoryUpload
+}
+
+// memoryBucket regroupe la configuration et les objets d'un bucket
+type memoryBucket struct {
+	owner      string
+	created    time.Time
+	policy     []byte
+	acl        *acl.ACL
+	versioning string
+	encryption *BucketEncryption
+	lifecycle  *LifecycleConfiguration
+	objects    map[string][]*memoryVersion // versions de chaque clé, la plus récente en tête
+}
+
+// memoryVersion est une version d'un objet, ou un marqueur de suppression (metadata.DeleteMarker)
+type memoryVersion struct {
+	data     []byte
+	metadata ObjectMetadata
+	acl      *acl.ACL
+}
+
+// memoryUpload est un upload multipart en cours et ses parts reçues
+type memoryUpload struct {
+	upload MultipartUpload
+	parts  map[int]memoryPart
+}
+
+type memoryPart struct {
+	info PartInfo
+	data []byte
+}
+
+// memoryReader ajoute une méthode Close sans effet à un bytes.Reader
+type memoryReader struct {
+	*bytes.Reader
+}
+
+func (memoryReader) Close() error {
+	return nil
+}
+
+// NewMemoryBackend crée un stockage en mémoire vide
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		buckets: make(map[string]*memoryBucket),
+		uploads: make(map[string]*memoryUpload),
+	}
+}
+
+// bucket retourne un bucket existant (à appeler verrou pris)
+func (m *MemoryBackend) bucket(bucketName string) (*memoryBucket, error) {
+	b, ok := m.buckets[bucketName]
+	if !ok {
+		return nil, ErrNoSuchBucket
+	}
+	return b, nil
+}
+
+// latest retourne la version courante d'une clé, nil si elle n'existe pas ou si c'est un marqueur de suppression
+func (b *memoryBucket) latest(objectName string) *memoryVersion {
+	versions := b.objects[objectName]
+	if len(versions) == 0 || versions[0].metadata.DeleteMarker {
+		return nil
+	}
+	return versions[0]
+}
+
+// dropNullVersion supprime la version "null" d'une clé, remplacée en versionnage suspendu
+func (b *memoryBucket) dropNullVersion(objectName string) {
+	var kept []*memoryVersion
+	for _, version := range b.objects[objectName] {
+		if versionIDOf(version.metadata) != NullVersionID {
+			kept = append(kept, version)
+		}
+	}
+	b.objects[objectName] = kept
+}
+
+// addVersion ajoute une version en tête des versions d'une clé, selon l'état du versionnage du bucket,
+// et retourne son identifiant (vide si le versionnage n'a jamais été activé)
+func (b *memoryBucket) addVersion(objectName string, version *memoryVersion) (string, error) {
+	var versionID string
+	switch b.versioning {
+	case VersioningEnabled:
+		id, err := newVersionID()
+		if err != nil {
+			return "", err
+		}
+		versionID = id
+	case VersioningSuspended:
+		b.dropNullVersion(objectName)
+		versionID = NullVersionID
+	default:
+		b.objects[objectName] = nil
+	}
+	version.metadata.VersionID = versionID
+	b.objects[objectName] = append([]*memoryVersion{version}, b.objects[objectName]...)
+	return versionID, nil
+}
+
+// CreateBucket crée un bucket ; un bucket existant est laissé tel quel (ErrBucketAlreadyOwnedByYou ou ErrBucketAlreadyExists)
+func (m *MemoryBackend) CreateBucket(bucketName, owner string) error {
+	if !validBucketName(bucketName) {
+		return ErrInvalidBucketName
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.buckets[bucketName]; ok {
+		return existingBucketError(existing.owner, owner)
+	}
+	m.buckets[bucketName] = &memoryBucket{
+		owner:   owner,
+		created: time.Now().UTC(),
+		objects: make(map[string][]*memoryVersion),
+	}
+	return nil
+}
+
+// DeleteBucket supprime un bucket vide, ses configurations et ses uploads multipart en cours
+func (m *MemoryBackend) DeleteBucket(bucketName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if len(b.objects) > 0 {
+		return ErrBucketNotEmpty
+	}
+	for uploadID, upload := range m.uploads {
+		if upload.upload.Bucket == bucketName {
+			delete(m.uploads, uploadID)
+		}
+	}
+	delete(m.buckets, bucketName)
+	return nil
+}
+
+// BucketExists indique si le bucket existe
+func (m *MemoryBackend) BucketExists(bucketName string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.buckets[bucketName]
+	return ok
+}
+
+// ListBuckets liste les buckets par ordre alphabétique
+func (m *MemoryBackend) ListBuckets() ([]BucketInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var buckets []BucketInfo
+	for name, b := range m.buckets {
+		buckets = append(buckets, BucketInfo{Name: name, CreationDate: b.created})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Name < buckets[j].Name })
+	return buckets, nil
+}
+
+// BucketOwner retourne l'identifiant du propriétaire d'un bucket
+func (m *MemoryBackend) BucketOwner(bucketName string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return "", err
+	}
+	return b.owner, nil
+}
+
+// PutBucketPolicy enregistre la politique (document JSON déjà validé) d'un bucket
+func (m *MemoryBackend) PutBucketPolicy(bucketName string, policy []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	b.policy = append([]byte(nil), policy...)
+	return nil
+}
+
+// GetBucketPolicy retourne la politique d'un bucket
+func (m *MemoryBackend) GetBucketPolicy(bucketName string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	if b.policy == nil {
+		return nil, ErrNoSuchBucketPolicy
+	}
+	return append([]byte(nil), b.policy...), nil
+}
+
+// DeleteBucketPolicy supprime la politique d'un bucket
+func (m *MemoryBackend) DeleteBucketPolicy(bucketName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	b.policy = nil
+	return nil
+}
+
+// PutBucketACL enregistre l'ACL d'un bucket
+func (m *MemoryBackend) PutBucketACL(bucketName string, a acl.ACL) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	b.acl = &a
+	return nil
+}
+
+// GetBucketACL retourne l'ACL d'un bucket (privée par défaut)
+func (m *MemoryBackend) GetBucketACL(bucketName string) (acl.ACL, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return acl.ACL{}, err
+	}
+	if b.acl != nil {
+		return *b.acl, nil
+	}
+	return acl.Private(acl.Owner{ID: b.owner, DisplayName: b.owner}), nil
+}
+
+// PutBucketVersioning active ou suspend le versionnage d'un bucket
+func (m *MemoryBackend) PutBucketVersioning(bucketName, status string) error {
+	if status != VersioningEnabled && status != VersioningSuspended {
+		return ErrInvalidVersioningStatus
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	b.versioning = status
+	return nil
+}
+
+// GetBucketVersioning retourne l'état du versionnage d'un bucket (vide s'il n'a jamais été activé)
+func (m *MemoryBackend) GetBucketVersioning(bucketName string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return "", err
+	}
+	return b.versioning, nil
+}
+
+// PutBucketEncryption enregistre le chiffrement par défaut d'un bucket
+func (m *MemoryBackend) PutBucketEncryption(bucketName string, config BucketEncryption) error {
+	if err := config.validate(m.MasterKey); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	b.encryption = &config
+	return nil
+}
+
+// GetBucketEncryption retourne le chiffrement par défaut d'un bucket (ErrNoSuchEncryptionConfiguration s'il n'en a pas)
+func (m *MemoryBackend) GetBucketEncryption(bucketName string) (BucketEncryption, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return BucketEncryption{}, err
+	}
+	if b.encryption == nil {
+		return BucketEncryption{}, ErrNoSuchEncryptionConfiguration
+	}
+	return *b.encryption, nil
+}
+
+// DeleteBucketEncryption supprime le chiffrement par défaut d'un bucket
+func (m *MemoryBackend) DeleteBucketEncryption(bucketName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	b.encryption = nil
+	return nil
+}
+
+// PutBucketLifecycle enregistre la configuration du cycle de vie d'un bucket
+func (m *MemoryBackend) PutBucketLifecycle(bucketName string, config LifecycleConfiguration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	b.lifecycle = &config
+	return nil
+}
+
+// GetBucketLifecycle retourne la configuration du cycle de vie d'un bucket (ErrNoSuchLifecycleConfiguration s'il n'en a pas)
+func (m *MemoryBackend) GetBucketLifecycle(bucketName string) (LifecycleConfiguration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return LifecycleConfiguration{}, err
+	}
+	if b.lifecycle == nil {
+		return LifecycleConfiguration{}, ErrNoSuchLifecycleConfiguration
+	}
+	return *b.lifecycle, nil
+}
+
+// DeleteBucketLifecycle supprime la configuration du cycle de vie d'un bucket
+func (m *MemoryBackend) DeleteBucketLifecycle(bucketName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	b.lifecycle = nil
+	return nil
+}
+
+// PutObject ajoute un objet dans un bucket ; le contenu est lu entièrement avant que l'objet ne soit visible
+func (m *MemoryBackend) PutObject(bucketName, objectName string, data io.Reader, metadata ObjectMetadata) (PutResult, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return PutResult{}, err
+	}
+	sum := md5.Sum(content)
+	return m.putObject(bucketName, objectName, content, metadata, hex.EncodeToString(sum[:]))
+}
+
+// putObject enregistre une nouvelle version d'un objet avec l'ETag fourni
+func (m *MemoryBackend) putObject(bucketName, objectName string, content []byte, metadata ObjectMetadata, etag string) (PutResult, error) {
+	checksum, err := checksumHash(metadata)
+	if err != nil {
+		return PutResult{}, err
+	}
+	if checksum != nil {
+		checksum.Write(content)
+	}
+	size := int64(len(content))
+	// Comme pour Storage, les parts d'un objet multipart SSE-C arrivent déjà chiffrées
+	var dataKey []byte
+	if metadata.Encryption != nil && len(metadata.Encryption.Segments) > 0 {
+		size = 0
+		for _, partSize := range metadata.PartSizes {
+			size += partSize
+		}
+	} else if dataKey, err = newDataKey(&metadata, m.MasterKey); err != nil {
+		return PutResult{}, err
+	}
+	if dataKey != nil {
+		var sealed bytes.Buffer
+		encrypter, err := newEncryptWriter(&sealed, dataKey)
+		if err != nil {
+			return PutResult{}, err
+		}
+		encrypter.Write(content)
+		if err := encrypter.Close(); err != nil {
+			return PutResult{}, err
+		}
+		content = sealed.Bytes()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return PutResult{}, err
+	}
+
+	metadata.ETag = etag
+	metadata.Size = size
+	metadata.LastModified = time.Now().UTC()
+	metadata.Checksum = encodeChecksum(checksum)
+	objectACL := metadata.ACL
+	metadata.ACL = nil
+	versionID, err := b.addVersion(objectName, &memoryVersion{data: content, metadata: metadata, acl: objectACL})
+	if err != nil {
+		return PutResult{}, err
+	}
+
+	sha := sha256.Sum256(content)
+	return PutResult{
+		ETag:      etag,
+		SHA256:    hex.EncodeToString(sha[:]),
+		Checksum:  metadata.Checksum,
+		Size:      metadata.Size,
+		VersionID: versionID,
+	}, nil
+}
+
+// GetObjectMetadata retourne les métadonnées d'un objet (os.ErrNotExist s'il n'existe pas)
+func (m *MemoryBackend) GetObjectMetadata(bucketName, objectName string) (ObjectMetadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return ObjectMetadata{}, os.ErrNotExist
+	}
+	version := b.latest(objectName)
+	if version == nil {
+		return ObjectMetadata{}, os.ErrNotExist
+	}
+	return version.metadata, nil
+}
+
+// findVersion retrouve une version d'un objet (la dernière, éventuellement un marqueur, si versionID est vide)
+func (m *MemoryBackend) findVersion(bucketName, objectName, versionID string) (*memoryVersion, error) {
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return nil, os.ErrNotExist
+	}
+	versions := b.objects[objectName]
+	if versionID == "" {
+		if len(versions) == 0 {
+			return nil, os.ErrNotExist
+		}
+		return versions[0], nil
+	}
+	if !isValidVersionID(versionID) {
+		return nil, ErrNoSuchVersion
+	}
+	for _, version := range versions {
+		if versionIDOf(version.metadata) == versionID {
+			return version, nil
+		}
+	}
+	return nil, ErrNoSuchVersion
+}
+
+// GetObjectVersion ouvre une version d'un objet (la dernière si versionID est vide) et retourne ses métadonnées.
+// Pour un marqueur de suppression, le lecteur est nil et metadata.DeleteMarker vaut true.
+func (m *MemoryBackend) GetObjectVersion(bucketName, objectName, versionID string) (io.ReadSeekCloser, ObjectMetadata, error) {
+	return m.OpenObjectVersion(bucketName, objectName, versionID, nil)
+}
+
+// OpenObjectVersion ouvre une version d'un objet éventuellement chiffré, comme Storage.OpenObjectVersion
+func (m *MemoryBackend) OpenObjectVersion(bucketName, objectName, versionID string, customerKey []byte) (io.ReadSeekCloser, ObjectMetadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	version, err := m.findVersion(bucketName, objectName, versionID)
+	if err != nil {
+		return nil, ObjectMetadata{}, err
+	}
+	if version.metadata.DeleteMarker {
+		return nil, version.metadata, nil
+	}
+	dataKey, err := openDataKey(version.metadata, m.MasterKey, m.PreviousMasterKey, customerKey)
+	if err != nil {
+		return nil, version.metadata, err
+	}
+	reader, err := decryptObject(memoryReader{bytes.NewReader(version.data)}, version.metadata, dataKey)
+	return reader, version.metadata, err
+}
+
+// RewrapDataKey réenveloppe avec MasterKey la clé de données d'une version SSE-S3, comme Storage.RewrapDataKey
+func (m *MemoryBackend) RewrapDataKey(bucketName, objectName, versionID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	version, err := m.findVersion(bucketName, objectName, versionID)
+	if err != nil {
+		return false, err
+	}
+	return rewrapDataKey(&version.metadata, m.MasterKey, m.PreviousMasterKey)
+}
+
+// GetObjectVersionMetadata retourne les métadonnées d'une version d'un objet (la dernière si versionID est vide)
+func (m *MemoryBackend) GetObjectVersionMetadata(bucketName, objectName, versionID string) (ObjectMetadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	version, err := m.findVersion(bucketName, objectName, versionID)
+	if err != nil {
+		return ObjectMetadata{}, err
+	}
+	return version.metadata, nil
+}
+
+// DeleteObject supprime un objet, ou une version précise, avec la même sémantique que Storage.DeleteObject
+func (m *MemoryBackend) DeleteObject(bucketName, objectName, versionID string) (DeleteResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return DeleteResult{}, err
+	}
+
+	if versionID != "" {
+		if !isValidVersionID(versionID) {
+			return DeleteResult{}, ErrNoSuchVersion
+		}
+		result := DeleteResult{VersionID: versionID}
+		versions := b.objects[objectName]
+		for i, version := range versions {
+			if versionIDOf(version.metadata) != versionID {
+				continue
+			}
+			result.DeleteMarker = version.metadata.DeleteMarker
+			b.objects[objectName] = append(versions[:i:i], versions[i+1:]...)
+			break
+		}
+		if len(b.objects[objectName]) == 0 {
+			delete(b.objects, objectName)
+		}
+		return result, nil
+	}
+
+	if b.versioning == "" {
+		delete(b.objects, objectName)
+		return DeleteResult{}, nil
+	}
+	marker := &memoryVersion{metadata: ObjectMetadata{
+		Key:          objectName,
+		DeleteMarker: true,
+		LastModified: time.Now().UTC(),
+	}}
+	markerID, err := b.addVersion(objectName, marker)
+	if err != nil {
+		return DeleteResult{}, err
+	}
+	return DeleteResult{VersionID: markerID, DeleteMarker: true}, nil
+}
+
+// PutObjectACL enregistre l'ACL d'un objet existant
+func (m *MemoryBackend) PutObjectACL(bucketName, objectName string, a acl.ACL) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	version := b.latest(objectName)
+	if version == nil {
+		return os.ErrNotExist
+	}
+	version.acl = &a
+	return nil
+}
+
+// GetObjectACL retourne l'ACL d'un objet (privée, au nom du propriétaire du bucket, par défaut)
+func (m *MemoryBackend) GetObjectACL(bucketName, objectName string) (acl.ACL, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return acl.ACL{}, err
+	}
+	if version := b.latest(objectName); version != nil && version.acl != nil {
+		return *version.acl, nil
+	}
+	return acl.Private(acl.Owner{ID: b.owner, DisplayName: b.owner}), nil
+}
+
+// ListObjects liste les objets d'un bucket par ordre lexicographique des clés
+func (m *MemoryBackend) ListObjects(bucketName string, opts ListObjectsOptions) (ListObjectsResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return ListObjectsResult{}, err
+	}
+
+	var keys []string
+	for key := range b.objects {
+		if strings.HasPrefix(key, opts.Prefix) && b.latest(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return paginateObjects(keys, opts, func(key string) (ObjectMetadata, bool, error) {
+		return b.latest(key).metadata, true, nil
+	})
+}
+
+// ListObjectVersions liste les versions et marqueurs de suppression des objets d'un bucket
+func (m *MemoryBackend) ListObjectVersions(bucketName string, opts ListObjectsOptions, versionIDMarker string) (ListVersionsResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		return ListVersionsResult{}, err
+	}
+
+	byKey := make(map[string][]ObjectMetadata)
+	for key, versions := range b.objects {
+		if !strings.HasPrefix(key, opts.Prefix) {
+			continue
+		}
+		for _, version := range versions {
+			metadata := version.metadata
+			metadata.Key = key
+			metadata.VersionID = versionIDOf(metadata)
+			byKey[key] = append(byKey[key], metadata)
+		}
+	}
+	return paginateVersions(byKey, opts, versionIDMarker), nil
+}
+
+// WalkObjectVersions appelle fn pour chaque clé d'un bucket commençant par prefix, comme Storage.WalkObjectVersions.
+// Les versions sont copiées avant l'appel : fn peut modifier le bucket.
+func (m *MemoryBackend) WalkObjectVersions(bucketName, prefix string, fn func(versions []ObjectVersion) error) error {
+	m.mu.Lock()
+	b, err := m.bucket(bucketName)
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	var keys []string
+	byKey := make(map[string][]ObjectVersion)
+	for key, versions := range b.objects {
+		if !strings.HasPrefix(key, prefix) || len(versions) == 0 {
+			continue
+		}
+		keys = append(keys, key)
+		for i, version := range versions {
+			metadata := version.metadata
+			metadata.Key = key
+			metadata.VersionID = versionIDOf(metadata)
+			byKey[key] = append(byKey[key], ObjectVersion{Key: key, Metadata: metadata, IsLatest: i == 0})
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(byKey[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getUpload retourne un upload en vérifiant qu'il correspond au bucket et à la clé (à appeler verrou pris)
+func (m *MemoryBackend) getUpload(bucketName, objectName, uploadID string) (*memoryUpload, error) {
+	upload, ok := m.uploads[uploadID]
+	if !ok || upload.upload.Bucket != bucketName || upload.upload.Key != objectName {
+		return nil, ErrNoSuchUpload
+	}
+	return upload, nil
+}
+
+// CreateMultipartUpload initialise un upload multipart et retourne son identifiant
+func (m *MemoryBackend) CreateMultipartUpload(bucketName, objectName string, initiator acl.Owner, metadata ObjectMetadata) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.bucket(bucketName); err != nil {
+		return "", err
+	}
+	// Comme pour Storage, un upload SSE-S3 est refusé dès l'initialisation sans clé maître ;
+	// les parts restent en mémoire et ne sont chiffrées qu'avec l'objet assemblé
+	if metadata.Encryption != nil && !metadata.Encryption.CustomerProvided() && m.MasterKey == nil {
+		return "", ErrSSENotConfigured
+	}
+	// Les parts d'un upload SSE-C sont en revanche chiffrées à leur réception, la clé du client n'étant pas
+	// renvoyée avec CompleteMultipartUpload
+	if metadata.Encryption != nil && metadata.Encryption.CustomerProvided() {
+		if _, err := newDataKey(&metadata, nil); err != nil {
+			return "", err
+		}
+	}
+	uploadID, err := newUploadID()
+	if err != nil {
+		return "", err
+	}
+	m.uploads[uploadID] = &memoryUpload{
+		upload: MultipartUpload{
+			UploadID:  uploadID,
+			Bucket:    bucketName,
+			Key:       objectName,
+			Initiated: time.Now().UTC(),
+			Initiator: initiator,
+			Metadata:  metadata,
+		},
+		parts: make(map[int]memoryPart),
+	}
+	return uploadID, nil
+}
+
+// GetMultipartUpload retourne un upload en cours (ErrNoSuchUpload s'il n'existe pas pour ce bucket et cette clé)
+func (m *MemoryBackend) GetMultipartUpload(bucketName, objectName, uploadID string) (MultipartUpload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, err := m.getUpload(bucketName, objectName, uploadID)
+	if err != nil {
+		return MultipartUpload{}, err
+	}
+	return upload.upload, nil
+}
+
+// UploadPart enregistre une part d'un upload multipart ; customerKey est la clé SSE-C envoyée avec la part
+func (m *MemoryBackend) UploadPart(bucketName, objectName, uploadID string, partNumber int, data io.Reader, customerKey []byte) (PartInfo, error) {
+	if partNumber < 1 || partNumber > MaxPartNumber {
+		return PartInfo{}, ErrInvalidPartNumber
+	}
+	m.mu.Lock()
+	upload, err := m.getUpload(bucketName, objectName, uploadID)
+	m.mu.Unlock()
+	if err != nil {
+		return PartInfo{}, err
+	}
+	dataID, err := newVersionID()
+	if err != nil {
+		return PartInfo{}, err
+	}
+	dataName := fmt.Sprintf("part-%05d-%s", partNumber, dataID)
+	partKey, err := customerPartKey(upload.upload, dataName, customerKey)
+	if err != nil {
+		return PartInfo{}, err
+	}
+
+	// Le corps est lu hors verrou ; l'upload peut avoir été annulé entre-temps
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return PartInfo{}, err
+	}
+	sum := md5.Sum(content)
+	part := PartInfo{
+		PartNumber:   partNumber,
+		ETag:         hex.EncodeToString(sum[:]),
+		Size:         int64(len(content)),
+		LastModified: time.Now().UTC(),
+	}
+	if partKey != nil {
+		var sealed bytes.Buffer
+		encrypter, err := newEncryptWriter(&sealed, partKey)
+		if err != nil {
+			return PartInfo{}, err
+		}
+		encrypter.Write(content)
+		if err := encrypter.Close(); err != nil {
+			return PartInfo{}, err
+		}
+		content = sealed.Bytes()
+		part.Data = dataName
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if upload, err = m.getUpload(bucketName, objectName, uploadID); err != nil {
+		return PartInfo{}, err
+	}
+	upload.parts[partNumber] = memoryPart{info: part, data: content}
+	return part, nil
+}
+
+// ListParts liste les parts reçues d'un upload, triées par numéro
+func (m *MemoryBackend) ListParts(bucketName, objectName, uploadID string) ([]PartInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, err := m.getUpload(bucketName, objectName, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return upload.partInfos(), nil
+}
+
+// partInfos retourne les parts d'un upload triées par numéro
+func (u *memoryUpload) partInfos() []PartInfo {
+	var parts []PartInfo
+	for _, part := range u.parts {
+		parts = append(parts, part.info)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts
+}
+
+// CompleteMultipartUpload assemble les parts demandées en un objet, dont l'ETag est l'ETag multipart
+func (m *MemoryBackend) CompleteMultipartUpload(bucketName, objectName, uploadID string, parts []CompletePart) (PutResult, error) {
+	m.mu.Lock()
+	upload, err := m.getUpload(bucketName, objectName, uploadID)
+	if err != nil {
+		m.mu.Unlock()
+		return PutResult{}, err
+	}
+	selected, etag, err := selectParts(upload.partInfos(), parts)
+	if err != nil {
+		m.mu.Unlock()
+		return PutResult{}, err
+	}
+	var content []byte
+	var partSizes []int64
+	var segments []string
+	for _, part := range selected {
+		content = append(content, upload.parts[part.PartNumber].data...)
+		partSizes = append(partSizes, part.Size)
+		segments = append(segments, part.Data)
+	}
+	metadata := upload.upload.Metadata
+	metadata.PartSizes = partSizes
+	// Les parts SSE-C sont assemblées chiffrées
+	if metadata.Encryption != nil && metadata.Encryption.CustomerProvided() {
+		info := *metadata.Encryption
+		info.Segments = segments
+		metadata.Encryption = &info
+	}
+	delete(m.uploads, uploadID)
+	m.mu.Unlock()
+
+	return m.putObject(bucketName, objectName, content, metadata, etag)
+}
+
+// AbortMultipartUpload annule un upload multipart et oublie ses parts
+func (m *MemoryBackend) AbortMultipartUpload(bucketName, objectName, uploadID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.getUpload(bucketName, objectName, uploadID); err != nil {
+		return err
+	}
+	delete(m.uploads, uploadID)
+	return nil
+}
+
+// ListMultipartUploads liste les uploads en cours d'un bucket, triés par clé puis par date
+func (m *MemoryBackend) ListMultipartUploads(bucketName string) ([]MultipartUpload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.bucket(bucketName); err != nil {
+		return nil, err
+	}
+	var uploads []MultipartUpload
+	for _, upload := range m.uploads {
+		if upload.upload.Bucket == bucketName {
+			uploads = append(uploads, upload.upload)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		if uploads[i].Key != uploads[j].Key {
+			return uploads[i].Key < uploads[j].Key
+		}
+		return uploads[i].Initiated.Before(uploads[j].Initiated)
+	})
+	return uploads, nil
+}
@@ -0,0 +1,152 @@
+// internal/storage/metadata.go
+package storage
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"plateforme-mys3/internal/acl"
+	"time"
+)
+
+// ObjectMetadata regroupe les métadonnées d'un objet enregistrées à l'écriture et renvoyées par GET/HEAD
+type ObjectMetadata struct {
+	ContentType        string            `json:"contentType,omitempty"`
+	ContentEncoding    string            `json:"contentEncoding,omitempty"`
+	ContentDisposition string            `json:"contentDisposition,omitempty"`
+	ContentLanguage    string            `json:"contentLanguage,omitempty"`
+	CacheControl       string            `json:"cacheControl,omitempty"`
+	Expires            string            `json:"expires,omitempty"`
+	UserMetadata       map[string]string `json:"userMetadata,omitempty"`      // x-amz-meta-*, clés en minuscules sans le préfixe
+	Tags               map[string]string `json:"tags,omitempty"`              // étiquettes (x-amz-tagging), utilisées par les filtres du cycle de vie
+	ChecksumAlgorithm  string            `json:"checksumAlgorithm,omitempty"` // somme de contrôle supplémentaire calculée à l'écriture (CRC32, SHA256...)
+	Checksum           string            `json:"checksum,omitempty"`          // valeur de cette somme, encodée en base64
+	Encryption         *EncryptionInfo   `json:"encryption,omitempty"`        // chiffrement au repos (SSE-S3 ou SSE-C), nil si l'objet est en clair
+	ETag               string            `json:"etag"`
+	Size               int64             `json:"size"`
+	LastModified       time.Time         `json:"lastModified"`
+	PartSizes          []int64           `json:"partSizes,omitempty"`    // tailles des parts d'un objet multipart, pour recalculer son ETag
+	VerifiedAt         time.Time         `json:"verifiedAt"`             // dernière vérification de l'ETag par le vérificateur
+	ETagMismatch       string            `json:"etagMismatch,omitempty"` // ETag recalculé lors de la dernière vérification, s'il diffère
+	VersionID          string            `json:"versionId,omitempty"`    // vide si l'objet a été écrit sans versionnage
+	DeleteMarker       bool              `json:"deleteMarker,omitempty"` // version archivée représentant une suppression
+	Key                string            `json:"key,omitempty"`          // clé de l'objet, enregistrée pour les versions archivées
+	ACL                *acl.ACL          `json:"-"`                      // ACL posée à l'écriture (x-amz-acl), enregistrée à part ; nil pour l'ACL par défaut
+}
+
+// objectSidecarPath retourne le chemin d'un fichier annexe d'un objet (métadonnées, ACL...),
+// rangé avec les configurations du bucket pour ne jamais apparaître comme une clé
+func (s *Storage) objectSidecarPath(bucketName, objectName, kind string) string {
+	return filepath.Join(s.bucketConfigDir(bucketName), "objects", filepath.FromSlash(encodeKey(objectName))) + "." + kind + ".json"
+}
+
+// putObjectMetadata enregistre les métadonnées d'un objet
+func (s *Storage) putObjectMetadata(bucketName, objectName string, metadata ObjectMetadata) error {
+	path := s.objectSidecarPath(bucketName, objectName, "meta")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeJSON(path, metadata)
+}
+
+// errMetadataMissing signale un objet sans fichier de métadonnées, écrit avant leur enregistrement
+var errMetadataMissing = errors.New("storage: métadonnées de l'objet absentes")
+
+// GetObjectMetadata retourne les métadonnées d'un objet (os.ErrNotExist s'il n'existe pas).
+// Pour un objet écrit avant l'enregistrement des métadonnées, l'ETag est recalculé une fois depuis le contenu
+// puis enregistré sous le verrou d'écriture de la clé : les lectures suivantes ne relisent plus le fichier.
+// Une écriture interrompue par une panne est terminée au démarrage avec ses propres métadonnées (voir RecoverWrites).
+func (s *Storage) GetObjectMetadata(bucketName, objectName string) (ObjectMetadata, error) {
+	unlock := s.locks.rlock(bucketName, objectName)
+	metadata, err := s.readObjectMetadata(bucketName, objectName)
+	unlock()
+	if err != errMetadataMissing {
+		return metadata, err
+	}
+
+	// Une écriture a pu enregistrer des métadonnées entre les deux verrous : elles sont relues avant tout calcul
+	unlock = s.locks.lock(bucketName, objectName)
+	defer unlock()
+	metadata, err = s.readObjectMetadata(bucketName, objectName)
+	if err != errMetadataMissing {
+		return metadata, err
+	}
+	if metadata, err = s.rebuildObjectMetadata(bucketName, objectName); err != nil {
+		return ObjectMetadata{}, err
+	}
+	if err := s.putObjectMetadata(bucketName, objectName, metadata); err != nil {
+		log.Printf("Erreur lors de l'enregistrement des métadonnées de %s/%s: %v", bucketName, objectName, err)
+	}
+	return metadata, nil
+}
+
+// getObjectMetadata est GetObjectMetadata pour un appelant qui détient déjà le verrou de la clé, en lecture ou
+// en écriture : les métadonnées d'un objet ancien sont recalculées sans être enregistrées
+func (s *Storage) getObjectMetadata(bucketName, objectName string) (ObjectMetadata, error) {
+	metadata, err := s.readObjectMetadata(bucketName, objectName)
+	if err == errMetadataMissing {
+		return s.rebuildObjectMetadata(bucketName, objectName)
+	}
+	return metadata, err
+}
+
+// readObjectMetadata lit les métadonnées enregistrées d'un objet : os.ErrNotExist si l'objet n'existe pas,
+// errMetadataMissing s'il existe sans fichier de métadonnées
+func (s *Storage) readObjectMetadata(bucketName, objectName string) (ObjectMetadata, error) {
+	if _, err := s.StatObject(bucketName, objectName); err != nil {
+		return ObjectMetadata{}, err
+	}
+	var metadata ObjectMetadata
+	err := readJSON(s.objectSidecarPath(bucketName, objectName, "meta"), &metadata)
+	if os.IsNotExist(err) {
+		return ObjectMetadata{}, errMetadataMissing
+	}
+	if err != nil {
+		return ObjectMetadata{}, err
+	}
+	return metadata, nil
+}
+
+// rebuildObjectMetadata recalcule depuis son contenu les métadonnées d'un objet écrit avant leur enregistrement
+func (s *Storage) rebuildObjectMetadata(bucketName, objectName string) (ObjectMetadata, error) {
+	info, err := s.StatObject(bucketName, objectName)
+	if err != nil {
+		return ObjectMetadata{}, err
+	}
+
+	// Un contenu chiffré ne peut pas être décrit sans sa clé de données enveloppée, perdue avec ses métadonnées :
+	// recalculer l'ETag du chiffré et le servir comme un contenu en clair serait pire qu'une erreur
+	file, err := s.GetObject(bucketName, objectName)
+	if err != nil {
+		return ObjectMetadata{}, err
+	}
+	encrypted, err := isEncrypted(file)
+	file.Close()
+	if err != nil {
+		return ObjectMetadata{}, err
+	}
+	if encrypted {
+		log.Printf("Métadonnées de l'objet chiffré %s/%s introuvables", bucketName, objectName)
+		return ObjectMetadata{}, ErrEncryptedWithoutKey
+	}
+
+	etag, err := s.contentETag(bucketName, objectName, nil)
+	if err != nil {
+		return ObjectMetadata{}, err
+	}
+	return ObjectMetadata{
+		ETag:         etag,
+		Size:         info.Size(),
+		LastModified: info.ModTime().UTC(),
+	}, nil
+}
+
+// removeSidecar supprime un fichier annexe d'un objet s'il existe
+func (s *Storage) removeSidecar(bucketName, objectName, kind string) error {
+	err := os.Remove(s.objectSidecarPath(bucketName, objectName, kind))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
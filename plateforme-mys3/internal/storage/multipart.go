@@ -0,0 +1,522 @@
+// internal/storage/multipart.go
+package storage
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"plateforme-mys3/internal/acl"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// MinPartSize est la taille minimale d'une part (hors dernière) imposée par S3
+	MinPartSize = 5 << 20
+	// MaxPartNumber est le numéro de part maximal accepté par S3
+	MaxPartNumber = 10000
+)
+
+// MultipartUpload décrit un upload multipart en cours
+type MultipartUpload struct {
+	UploadID  string         `json:"uploadId"`
+	Bucket    string         `json:"bucket"`
+	Key       string         `json:"key"`
+	Initiated time.Time      `json:"initiated"`
+	Initiator acl.Owner      `json:"initiator"`         // appelant qui a initialisé l'upload
+	Metadata  ObjectMetadata `json:"metadata"`          // métadonnées appliquées à l'objet final
+	PartKey   string         `json:"partKey,omitempty"` // SSE-S3 : clé des parts, enveloppée par la clé maître (base64)
+}
+
+// PartInfo décrit une part déjà reçue d'un upload multipart
+type PartInfo struct {
+	PartNumber   int       `json:"partNumber"`
+	ETag         string    `json:"etag"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	Data         string    `json:"data,omitempty"` // fichier de données de la part dans le répertoire de l'upload
+}
+
+// CompletePart référence une part dans une requête CompleteMultipartUpload
+type CompletePart struct {
+	PartNumber int
+	ETag       string
+}
+
+// multipartRoot retourne le répertoire où sont stockés les uploads en cours
+func (s *Storage) multipartRoot() string {
+	return filepath.Join(s.BasePath, systemDir, "multipart")
+}
+
+// uploadPath retourne le répertoire d'un upload multipart
+func (s *Storage) uploadPath(uploadID string) string {
+	return filepath.Join(s.multipartRoot(), uploadID)
+}
+
+// partPath retourne le chemin d'une part, sans extension : son descripteur est partPath + ".json"
+func (s *Storage) partPath(uploadID string, partNumber int) string {
+	return filepath.Join(s.uploadPath(uploadID), fmt.Sprintf("part-%05d", partNumber))
+}
+
+// partDataPath retourne le chemin du fichier de données d'une part décrite par son descripteur
+func (s *Storage) partDataPath(uploadID string, part PartInfo) string {
+	if part.Data == "" {
+		// Descripteur antérieur aux fichiers de données nommés : les données sont à côté, sans extension
+		return s.partPath(uploadID, part.PartNumber)
+	}
+	return filepath.Join(s.uploadPath(uploadID), filepath.Base(part.Data))
+}
+
+// CreateMultipartUpload initialise pour initiator un upload multipart et retourne son identifiant ;
+// les métadonnées fournies seront celles de l'objet assemblé
+func (s *Storage) CreateMultipartUpload(bucketName, objectName string, initiator acl.Owner, metadata ObjectMetadata) (string, error) {
+	if !s.BucketExists(bucketName) {
+		return "", ErrNoSuchBucket
+	}
+
+	uploadID, err := newUploadID()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(s.uploadPath(uploadID), 0755); err != nil {
+		return "", err
+	}
+
+	upload := MultipartUpload{
+		UploadID:  uploadID,
+		Bucket:    bucketName,
+		Key:       objectName,
+		Initiated: time.Now().UTC(),
+		Initiator: initiator,
+		Metadata:  metadata,
+	}
+	// Les parts d'un objet SSE-S3 sont chiffrées dès leur réception : l'objet assemblé l'est avec sa propre clé.
+	// Celles d'un objet SSE-C le sont avec la clé de données de l'objet, enveloppée par la clé du client : seul
+	// UploadPart reçoit cette clé, CompleteMultipartUpload assemble les parts sans les déchiffrer.
+	switch {
+	case metadata.Encryption == nil:
+	case metadata.Encryption.CustomerProvided():
+		if _, err = newDataKey(&upload.Metadata, nil); err != nil {
+			os.RemoveAll(s.uploadPath(uploadID))
+			return "", err
+		}
+	default:
+		if upload.PartKey, err = s.newPartKey(); err != nil {
+			os.RemoveAll(s.uploadPath(uploadID))
+			return "", err
+		}
+	}
+	if err := writeJSON(filepath.Join(s.uploadPath(uploadID), "upload.json"), upload); err != nil {
+		os.RemoveAll(s.uploadPath(uploadID))
+		return "", err
+	}
+	return uploadID, nil
+}
+
+// lockUpload verrouille un upload en écriture et retourne la fonction qui le déverrouille : la mise en place
+// d'une part, l'assemblage et l'annulation d'un même upload sont sérialisés. Le répertoire système ne pouvant
+// pas être un nom de bucket, ces verrous ne croisent jamais ceux des clés d'objets.
+func (s *Storage) lockUpload(uploadID string) func() {
+	return s.locks.lock(systemDir, uploadID)
+}
+
+// rlockUpload verrouille un upload en lecture et retourne la fonction qui le déverrouille
+func (s *Storage) rlockUpload(uploadID string) func() {
+	return s.locks.rlock(systemDir, uploadID)
+}
+
+// UploadPart enregistre une part d'un upload multipart en calculant son MD5 au fil de l'eau.
+// Le corps est reçu hors verrou, pour que les parts d'un upload puissent être envoyées en parallèle ; seule la
+// mise en place est faite sous le verrou de l'upload. Les données sont écrites sous un nom propre à cet envoi,
+// que le descripteur de la part référence : le remplacement du descripteur met en place données et description
+// d'un coup, même si le serveur s'arrête entre les deux écritures.
+// customerKey est la clé SSE-C envoyée avec la part (nil si aucune), exigée pour un upload SSE-C.
+func (s *Storage) UploadPart(bucketName, objectName, uploadID string, partNumber int, data io.Reader, customerKey []byte) (PartInfo, error) {
+	if partNumber < 1 || partNumber > MaxPartNumber {
+		return PartInfo{}, ErrInvalidPartNumber
+	}
+	if !s.BucketExists(bucketName) {
+		return PartInfo{}, ErrNoSuchBucket
+	}
+	upload, err := s.getUpload(bucketName, objectName, uploadID)
+	if err != nil {
+		return PartInfo{}, err
+	}
+	dataID, err := newVersionID()
+	if err != nil {
+		return PartInfo{}, err
+	}
+	dataName := fmt.Sprintf("part-%05d-%s", partNumber, dataID)
+	partKey, err := s.openPartKey(upload, dataName, customerKey)
+	if err != nil {
+		return PartInfo{}, err
+	}
+
+	partPath := s.partPath(uploadID, partNumber)
+	tmp, err := os.CreateTemp(s.uploadPath(uploadID), ".tmp-part-")
+	if err != nil {
+		return PartInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	var out io.Writer = tmp
+	var encrypter *encryptWriter
+	if partKey != nil {
+		if encrypter, err = newEncryptWriter(tmp, partKey); err != nil {
+			tmp.Close()
+			return PartInfo{}, err
+		}
+		out = encrypter
+	}
+	hash := md5.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), data)
+	if err == nil && encrypter != nil {
+		err = encrypter.Close()
+	}
+	if err != nil {
+		tmp.Close()
+		return PartInfo{}, err
+	}
+
+	// L'upload a pu être assemblé ou annulé pendant la réception
+	unlock := s.lockUpload(uploadID)
+	defer unlock()
+	if _, err := s.getUpload(bucketName, objectName, uploadID); err != nil {
+		tmp.Close()
+		return PartInfo{}, err
+	}
+
+	part := PartInfo{
+		PartNumber:   partNumber,
+		ETag:         hex.EncodeToString(hash.Sum(nil)),
+		Size:         size,
+		LastModified: time.Now().UTC(),
+		Data:         dataName,
+	}
+	if err := commitFile(tmp, s.partDataPath(uploadID, part)); err != nil {
+		return PartInfo{}, err
+	}
+
+	var previous PartInfo
+	hasPrevious := readJSON(partPath+".json", &previous) == nil
+	if err := writeJSON(partPath+".json", part); err != nil {
+		os.Remove(s.partDataPath(uploadID, part))
+		return PartInfo{}, err
+	}
+	// Les données de la part remplacée ne sont plus référencées
+	if hasPrevious && previous.Data != part.Data {
+		os.Remove(s.partDataPath(uploadID, previous))
+	}
+	return part, nil
+}
+
+// GetMultipartUpload retourne un upload en cours (ErrNoSuchUpload s'il n'existe pas pour ce bucket et cette clé)
+func (s *Storage) GetMultipartUpload(bucketName, objectName, uploadID string) (MultipartUpload, error) {
+	unlock := s.rlockUpload(uploadID)
+	defer unlock()
+	return s.getUpload(bucketName, objectName, uploadID)
+}
+
+// ListParts liste les parts reçues d'un upload, triées par numéro
+func (s *Storage) ListParts(bucketName, objectName, uploadID string) ([]PartInfo, error) {
+	unlock := s.rlockUpload(uploadID)
+	defer unlock()
+	return s.listParts(bucketName, objectName, uploadID)
+}
+
+// listParts liste les parts d'un upload ; l'appelant détient le verrou de l'upload
+func (s *Storage) listParts(bucketName, objectName, uploadID string) ([]PartInfo, error) {
+	if _, err := s.getUpload(bucketName, objectName, uploadID); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(s.uploadPath(uploadID))
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []PartInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "part-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		var part PartInfo
+		if err := readJSON(filepath.Join(s.uploadPath(uploadID), name), &part); err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// CompleteMultipartUpload assemble les parts demandées en un objet, dont l'ETag est l'ETag multipart
+// Le verrou de l'upload est détenu jusqu'à sa suppression : un seul assemblage aboutit.
+func (s *Storage) CompleteMultipartUpload(bucketName, objectName, uploadID string, parts []CompletePart) (PutResult, error) {
+	unlock := s.lockUpload(uploadID)
+	defer unlock()
+	upload, err := s.getUpload(bucketName, objectName, uploadID)
+	if err != nil {
+		return PutResult{}, err
+	}
+	stored, err := s.listParts(bucketName, objectName, uploadID)
+	if err != nil {
+		return PutResult{}, err
+	}
+	selected, etag, err := selectParts(stored, parts)
+	if err != nil {
+		return PutResult{}, err
+	}
+
+	// Les parts SSE-C, illisibles sans la clé du client, sont assemblées chiffrées
+	sealed := upload.Metadata.Encryption != nil && upload.Metadata.Encryption.CustomerProvided()
+	var readers []io.Reader
+	var partSizes []int64
+	var segments []string
+	var files []io.Closer
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, part := range selected {
+		var partKey []byte
+		if sealed {
+			if part.Data == "" {
+				return PutResult{}, ErrInvalidPart
+			}
+		} else if partKey, err = s.openPartKey(upload, part.Data, nil); err != nil {
+			return PutResult{}, err
+		}
+		f, err := os.Open(s.partDataPath(uploadID, part))
+		if err != nil {
+			return PutResult{}, err
+		}
+		files = append(files, f)
+		// Des données qui ne correspondent pas à leur descripteur ne sont pas assemblées
+		size := part.Size
+		if sealed || partKey != nil {
+			size = encryptedSize(part.Size)
+		}
+		if info, err := f.Stat(); err != nil || info.Size() != size {
+			return PutResult{}, ErrInvalidPart
+		}
+		partSizes = append(partSizes, part.Size)
+		if sealed {
+			readers = append(readers, f)
+			segments = append(segments, part.Data)
+			continue
+		}
+		// Les parts SSE-S3 sont déchiffrées au fil de l'assemblage
+		plain, err := decryptObject(f, ObjectMetadata{Size: part.Size}, partKey)
+		if err != nil {
+			return PutResult{}, err
+		}
+		readers = append(readers, plain)
+	}
+
+	metadata := upload.Metadata
+	metadata.PartSizes = partSizes
+	if sealed {
+		info := *metadata.Encryption
+		info.Segments = segments
+		metadata.Encryption = &info
+	}
+	result, err := s.putObject(bucketName, objectName, io.MultiReader(readers...), metadata, etag)
+	if err != nil {
+		return PutResult{}, err
+	}
+
+	if err := os.RemoveAll(s.uploadPath(uploadID)); err != nil {
+		return PutResult{}, err
+	}
+	return result, nil
+}
+
+// newPartKey génère la clé des parts d'un upload SSE-S3 et retourne sa version enveloppée par la clé maître
+func (s *Storage) newPartKey() (string, error) {
+	if s.MasterKey == nil {
+		return "", ErrSSENotConfigured
+	}
+	key := make([]byte, SSEKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return wrapKey(s.MasterKey, key)
+}
+
+// openPartKey retrouve la clé de chiffrement du fichier de données dataName d'un upload, dérivée de la clé des
+// parts : chaque envoi d'une part a ainsi sa propre clé. Retourne nil si les parts de l'upload sont en clair.
+func (s *Storage) openPartKey(upload MultipartUpload, dataName string, customerKey []byte) ([]byte, error) {
+	if key, err := customerPartKey(upload, dataName, customerKey); key != nil || err != nil {
+		return key, err
+	}
+	if upload.PartKey == "" {
+		return nil, nil
+	}
+	if dataName == "" {
+		return nil, ErrInvalidPart
+	}
+	key, _, err := unwrapMasterKey(upload.PartKey, s.MasterKey, s.PreviousMasterKey)
+	if err != nil {
+		return nil, err
+	}
+	return deriveKey(key, dataName), nil
+}
+
+// customerPartKey retrouve la clé de chiffrement du fichier de données dataName d'un upload SSE-C, dérivée de la
+// clé de données de l'objet que déverrouille la clé du client envoyée avec la part. Retourne nil pour un upload
+// qui n'est pas SSE-C, après avoir refusé une clé du client qui ne le concerne pas.
+func customerPartKey(upload MultipartUpload, dataName string, customerKey []byte) ([]byte, error) {
+	info := upload.Metadata.Encryption
+	if info == nil || !info.CustomerProvided() {
+		return nil, VerifyCustomerKey(upload.Metadata, customerKey)
+	}
+	dataKey, err := openDataKey(upload.Metadata, nil, nil, customerKey)
+	if err != nil {
+		return nil, err
+	}
+	return deriveKey(dataKey, dataName), nil
+}
+
+// selectParts vérifie les parts demandées par CompleteMultipartUpload (ordre, existence, ETag, taille minimale
+// hors dernière part) par rapport aux parts reçues, et retourne les parts retenues et l'ETag multipart
+func selectParts(stored []PartInfo, parts []CompletePart) ([]PartInfo, string, error) {
+	if len(parts) == 0 {
+		return nil, "", ErrMalformedUploadXML
+	}
+	byNumber := make(map[int]PartInfo, len(stored))
+	for _, part := range stored {
+		byNumber[part.PartNumber] = part
+	}
+
+	etagHash := md5.New()
+	selected := make([]PartInfo, 0, len(parts))
+	for i, requested := range parts {
+		if i > 0 && requested.PartNumber <= parts[i-1].PartNumber {
+			return nil, "", ErrInvalidPartOrder
+		}
+		part, ok := byNumber[requested.PartNumber]
+		if !ok || strings.Trim(requested.ETag, "\"") != part.ETag {
+			return nil, "", ErrInvalidPart
+		}
+		if i < len(parts)-1 && part.Size < MinPartSize {
+			return nil, "", ErrEntityTooSmall
+		}
+		sum, err := hex.DecodeString(part.ETag)
+		if err != nil {
+			return nil, "", ErrInvalidPart
+		}
+		etagHash.Write(sum)
+		selected = append(selected, part)
+	}
+	return selected, fmt.Sprintf("%s-%d", hex.EncodeToString(etagHash.Sum(nil)), len(parts)), nil
+}
+
+// AbortMultipartUpload annule un upload multipart et supprime ses parts
+func (s *Storage) AbortMultipartUpload(bucketName, objectName, uploadID string) error {
+	unlock := s.lockUpload(uploadID)
+	defer unlock()
+	if _, err := s.getUpload(bucketName, objectName, uploadID); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.uploadPath(uploadID))
+}
+
+// ListMultipartUploads liste les uploads en cours d'un bucket, triés par clé puis par date
+func (s *Storage) ListMultipartUploads(bucketName string) ([]MultipartUpload, error) {
+	if !s.BucketExists(bucketName) {
+		return nil, ErrNoSuchBucket
+	}
+
+	entries, err := os.ReadDir(s.multipartRoot())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var uploads []MultipartUpload
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		var upload MultipartUpload
+		if err := readJSON(filepath.Join(s.uploadPath(entry.Name()), "upload.json"), &upload); err != nil {
+			continue
+		}
+		if upload.Bucket == bucketName {
+			uploads = append(uploads, upload)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		if uploads[i].Key != uploads[j].Key {
+			return uploads[i].Key < uploads[j].Key
+		}
+		return uploads[i].Initiated.Before(uploads[j].Initiated)
+	})
+	return uploads, nil
+}
+
+// getUpload charge un upload et vérifie qu'il correspond bien au bucket et à la clé
+func (s *Storage) getUpload(bucketName, objectName, uploadID string) (MultipartUpload, error) {
+	var upload MultipartUpload
+	if !isValidUploadID(uploadID) {
+		return upload, ErrNoSuchUpload
+	}
+	if err := readJSON(filepath.Join(s.uploadPath(uploadID), "upload.json"), &upload); err != nil {
+		if os.IsNotExist(err) {
+			return upload, ErrNoSuchUpload
+		}
+		return upload, err
+	}
+	if upload.Bucket != bucketName || upload.Key != objectName {
+		return upload, ErrNoSuchUpload
+	}
+	return upload, nil
+}
+
+// newUploadID génère un identifiant d'upload aléatoire
+func newUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// isValidUploadID vérifie qu'un identifiant d'upload ne contient que des caractères hexadécimaux
+func isValidUploadID(uploadID string) bool {
+	if len(uploadID) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(uploadID)
+	return err == nil
+}
+
+// writeJSON sérialise une valeur dans un fichier JSON, remplacé atomiquement (voir writeFileAtomic)
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// readJSON désérialise un fichier JSON dans une valeur
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,79 @@
+// internal/storage/purge.go
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultPurgeBatchSize est le nombre de versions supprimées par lot lors d'une purge
+const DefaultPurgeBatchSize = 1000
+
+// PurgeProgress décrit l'avancement d'une purge de bucket
+type PurgeProgress struct {
+	Batches         int // lots de versions traités
+	DeletedVersions int // versions et marqueurs de suppression supprimés
+	AbortedUploads  int // uploads multipart annulés
+}
+
+// PurgeBucket supprime tout le contenu d'un bucket (versions, marqueurs de suppression, uploads multipart
+// en cours) par lots de batchSize versions, puis le bucket lui-même. progress, s'il est fourni, est appelé
+// après chaque lot et une dernière fois après l'annulation des uploads.
+// Opération d'administration : elle n'est exposée par aucune route S3.
+func PurgeBucket(ctx context.Context, b Backend, bucketName string, batchSize int, progress func(PurgeProgress)) error {
+	if batchSize <= 0 {
+		batchSize = DefaultPurgeBatchSize
+	}
+	if !b.BucketExists(bucketName) {
+		return ErrNoSuchBucket
+	}
+	report := func(p PurgeProgress) {
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	var p PurgeProgress
+	var previous ObjectVersion
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Les versions supprimées disparaissent du listing : chaque lot repart du début
+		page, err := b.ListObjectVersions(bucketName, ListObjectsOptions{MaxKeys: batchSize}, "")
+		if err != nil {
+			return err
+		}
+		if len(page.Versions) == 0 {
+			break
+		}
+		first := page.Versions[0]
+		if p.Batches > 0 && first.Key == previous.Key && first.Metadata.VersionID == previous.Metadata.VersionID {
+			return fmt.Errorf("storage: purge du bucket %s bloquée sur %s (version %s)", bucketName, first.Key, first.Metadata.VersionID)
+		}
+		previous = first
+
+		for _, version := range page.Versions {
+			if _, err := b.DeleteObject(bucketName, version.Key, version.Metadata.VersionID); err != nil {
+				return err
+			}
+			p.DeletedVersions++
+		}
+		p.Batches++
+		report(p)
+	}
+
+	uploads, err := b.ListMultipartUploads(bucketName)
+	if err != nil {
+		return err
+	}
+	for _, upload := range uploads {
+		if err := b.AbortMultipartUpload(bucketName, upload.Key, upload.UploadID); err != nil && err != ErrNoSuchUpload {
+			return err
+		}
+		p.AbortedUploads++
+	}
+	report(p)
+
+	return b.DeleteBucket(bucketName)
+}
@@ -0,0 +1,498 @@
+// internal/storage/sse.go
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Chiffrement côté serveur (SSE) des objets au repos, en AES-256-GCM.
+//
+// Chaque objet chiffré a sa propre clé de données, aléatoire, elle-même chiffrée (« enveloppée ») par une clé de
+// chiffrement de clés : la clé maître du serveur (SSE-S3) ou la clé fournie par le client à chaque requête (SSE-C).
+// Seule la clé enveloppée est enregistrée, dans les métadonnées de l'objet ; la clé du client ne l'est jamais.
+// Changer de clé maître ne demande donc pas de rechiffrer les contenus : il suffit de réenvelopper les clés de
+// données (RotateDataKeys), la clé précédente restant utilisable en lecture jusqu'à la fin de la rotation.
+//
+// Le contenu est découpé en blocs de sseChunkSize octets scellés séparément : une lecture partielle (Range)
+// ne déchiffre que les blocs concernés. Le nonce d'un bloc est dérivé de son numéro et d'un indicateur de dernier
+// bloc, ce qui empêche de réordonner ou de tronquer les blocs sans que la lecture échoue ; la clé de données
+// étant propre à l'objet, un même nonce n'est jamais réutilisé avec la même clé.
+//
+// Un contenu chiffré commence par un en-tête fixe (sseHeader) : un fichier dont les métadonnées manquent est
+// reconnu comme chiffré, et n'est jamais décrit ni servi comme s'il était en clair.
+
+const (
+	// SSEAlgorithm est le seul algorithme accepté par x-amz-server-side-encryption et SSE-C
+	SSEAlgorithm = "AES256"
+	// SSEKeySize est la taille des clés AES-256 : clé maître, clé du client et clés de données
+	SSEKeySize = 32
+
+	// sseChunkSize est la taille des blocs de contenu chiffrés séparément
+	sseChunkSize = 64 << 10
+	// sseOverhead est le nombre d'octets ajoutés à chaque bloc par GCM (tag d'authentification)
+	sseOverhead = 16
+)
+
+// sseHeader précède les blocs d'un contenu chiffré
+var sseHeader = []byte("MYS3SSE\x01")
+
+// sseKeyAAD lie une clé enveloppée à son usage
+var sseKeyAAD = []byte("mys3-sse-data-key")
+
+// EncryptionInfo décrit le chiffrement d'un objet. Pour écrire un objet chiffré, il suffit de renseigner
+// ObjectMetadata.Encryption (avec CustomerKey et CustomerKeyMD5 pour SSE-C) : le stockage génère la clé de données.
+type EncryptionInfo struct {
+	CustomerKeyMD5 string `json:"customerKeyMD5,omitempty"` // SSE-C : MD5 (base64) de la clé du client ; vide pour SSE-S3
+	WrappedKey     string `json:"wrappedKey,omitempty"`     // clé de données chiffrée par la clé maître ou la clé du client (base64)
+	CustomerKey    []byte `json:"-"`                        // SSE-C : clé du client, fournie à l'écriture et jamais enregistrée
+	// Segments nomme, dans l'ordre, les parts d'un objet multipart SSE-C : chacune est chiffrée à réception avec
+	// la clé dérivée de son nom, puis les contenus chiffrés sont mis bout à bout (voir segmentReader)
+	Segments []string `json:"segments,omitempty"`
+}
+
+// CustomerProvided indique si l'objet est chiffré avec une clé fournie par le client (SSE-C)
+func (e *EncryptionInfo) CustomerProvided() bool {
+	return e.CustomerKeyMD5 != ""
+}
+
+// CustomerKeyMD5 retourne le MD5 (base64) d'une clé SSE-C, tel qu'attendu dans x-amz-server-side-encryption-customer-key-MD5
+func CustomerKeyMD5(key []byte) string {
+	sum := md5.Sum(key)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// VerifyCustomerKey vérifie que la clé SSE-C fournie pour lire un objet (nil si aucune) convient à son chiffrement
+func VerifyCustomerKey(metadata ObjectMetadata, customerKey []byte) error {
+	info := metadata.Encryption
+	switch {
+	case info == nil || !info.CustomerProvided():
+		if customerKey != nil {
+			return ErrSSENotApplicable
+		}
+		return nil
+	case customerKey == nil:
+		return ErrSSECustomerKeyRequired
+	case CustomerKeyMD5(customerKey) != info.CustomerKeyMD5:
+		return ErrSSECustomerKeyMismatch
+	}
+	return nil
+}
+
+// newAEAD construit le chiffrement AES-256-GCM d'une clé
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != SSEKeySize {
+		return nil, ErrInvalidSSEKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// wrapKey enveloppe une clé de données avec une clé de chiffrement de clés : nonce aléatoire suivi du chiffré, en base64
+func wrapKey(kek, dataKey []byte) (string, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, dataKey, sseKeyAAD)), nil
+}
+
+// unwrapKey retrouve une clé de données enveloppée par wrapKey
+func unwrapKey(kek []byte, wrapped string) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil || len(data) < aead.NonceSize() {
+		return nil, errors.New("storage: clé de données enveloppée invalide")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], sseKeyAAD)
+}
+
+// newDataKey prépare le chiffrement d'un objet à écrire : génère sa clé de données et enregistre dans
+// metadata.Encryption sa version enveloppée. Retourne nil si l'objet n'est pas à chiffrer.
+func newDataKey(metadata *ObjectMetadata, masterKey []byte) ([]byte, error) {
+	info := metadata.Encryption
+	if info == nil {
+		return nil, nil
+	}
+	kek := masterKey
+	if info.CustomerProvided() {
+		kek = info.CustomerKey
+	} else if masterKey == nil {
+		return nil, ErrSSENotConfigured
+	}
+
+	dataKey := make([]byte, SSEKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := wrapKey(kek, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	// La clé du client ne doit survivre à la requête dans aucun backend
+	metadata.Encryption = &EncryptionInfo{CustomerKeyMD5: info.CustomerKeyMD5, WrappedKey: wrapped}
+	return dataKey, nil
+}
+
+// deriveKey dérive d'une clé de données une clé propre à name (HMAC-SHA256) : les nonces d'un contenu ne
+// dépendant que du numéro de bloc, chaque contenu chiffré doit avoir sa propre clé
+func deriveKey(key []byte, name string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	return mac.Sum(nil)
+}
+
+// encryptedSize retourne la taille chiffrée d'un contenu de size octets : en-tête, puis un tag par bloc,
+// le dernier bloc (éventuellement vide) compris
+func encryptedSize(size int64) int64 {
+	blocks := (size + sseChunkSize - 1) / sseChunkSize
+	if blocks == 0 {
+		blocks = 1
+	}
+	return int64(len(sseHeader)) + size + blocks*sseOverhead
+}
+
+// openDataKey retrouve la clé de données d'un objet à lire, après vérification de la clé du client.
+// Retourne nil si l'objet n'est pas chiffré.
+func openDataKey(metadata ObjectMetadata, masterKey, previousKey, customerKey []byte) ([]byte, error) {
+	if err := VerifyCustomerKey(metadata, customerKey); err != nil {
+		return nil, err
+	}
+	info := metadata.Encryption
+	if info == nil {
+		return nil, nil
+	}
+	if !info.CustomerProvided() {
+		dataKey, _, err := unwrapMasterKey(info.WrappedKey, masterKey, previousKey)
+		return dataKey, err
+	}
+	dataKey, err := unwrapKey(customerKey, info.WrappedKey)
+	if err != nil {
+		return nil, ErrSSECustomerKeyMismatch
+	}
+	return dataKey, nil
+}
+
+// unwrapMasterKey retrouve une clé de données SSE-S3 avec la clé maître ou, à défaut, avec la clé maître
+// précédente (previousKey, nil hors rotation) ; previous indique que c'est la clé précédente qui l'enveloppe
+func unwrapMasterKey(wrapped string, masterKey, previousKey []byte) (dataKey []byte, previous bool, err error) {
+	if masterKey == nil {
+		return nil, false, ErrSSENotConfigured
+	}
+	dataKey, err = unwrapKey(masterKey, wrapped)
+	if err == nil {
+		return dataKey, false, nil
+	}
+	if previousKey != nil {
+		if dataKey, previousErr := unwrapKey(previousKey, wrapped); previousErr == nil {
+			return dataKey, true, nil
+		}
+	}
+	return nil, false, fmt.Errorf("storage: clé de données illisible avec la clé maître: %w", err)
+}
+
+// rewrapDataKey réenveloppe avec la clé maître la clé de données d'un objet SSE-S3 encore enveloppée par la clé
+// précédente, et indique si metadata.Encryption a été modifié. Le contenu chiffré de l'objet ne change pas.
+func rewrapDataKey(metadata *ObjectMetadata, masterKey, previousKey []byte) (bool, error) {
+	info := metadata.Encryption
+	if metadata.DeleteMarker || info == nil || info.CustomerProvided() {
+		return false, nil
+	}
+	dataKey, previous, err := unwrapMasterKey(info.WrappedKey, masterKey, previousKey)
+	if err != nil || !previous {
+		return false, err
+	}
+	wrapped, err := wrapKey(masterKey, dataKey)
+	if err != nil {
+		return false, err
+	}
+	// Les versions listées partagent EncryptionInfo avec l'objet enregistré : il est remplacé, pas modifié
+	metadata.Encryption = &EncryptionInfo{WrappedKey: wrapped, Segments: info.Segments}
+	return true, nil
+}
+
+// isEncrypted indique si un contenu commence par l'en-tête des contenus chiffrés ; la position est rétablie
+func isEncrypted(file io.ReadSeeker) (bool, error) {
+	header := make([]byte, len(sseHeader))
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	return n == len(sseHeader) && bytes.Equal(header, sseHeader), nil
+}
+
+// decryptObject retourne le contenu en clair d'un objet ouvert : file lui-même s'il n'est pas chiffré
+func decryptObject(file io.ReadSeekCloser, metadata ObjectMetadata, dataKey []byte) (io.ReadSeekCloser, error) {
+	if dataKey == nil {
+		return file, nil
+	}
+	// Un contenu en clair peut commencer par l'en-tête : il ne sert qu'aux contenus dont on attend un chiffré
+	// ou dont les métadonnées manquent (voir getObjectMetadata)
+	encrypted, err := isEncrypted(file)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return nil, errors.New("storage: en-tête de contenu chiffré absent")
+	}
+	if metadata.Encryption != nil && len(metadata.Encryption.Segments) > 0 {
+		return newSegmentReader(file, metadata, dataKey)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{src: file, aead: aead, size: metadata.Size, loaded: -1}, nil
+}
+
+// sseNonce retourne le nonce d'un bloc : son numéro, puis l'indicateur de dernier bloc
+func sseNonce(index int64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, uint64(index))
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// encryptWriter chiffre un flux bloc par bloc. Un bloc plein n'est scellé qu'à l'arrivée de l'octet suivant,
+// pour savoir s'il est le dernier : Close scelle le dernier bloc (vide si le flux l'est).
+type encryptWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	buf   []byte
+	index int64
+}
+
+// newEncryptWriter construit un encryptWriter écrivant le contenu chiffré avec dataKey dans w, en-tête compris
+func newEncryptWriter(w io.Writer, dataKey []byte) (*encryptWriter, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(sseHeader); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, buf: make([]byte, 0, sseChunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if len(e.buf) == sseChunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):sseChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close scelle le dernier bloc ; il ne ferme pas le flux sous-jacent
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+// seal chiffre le bloc en attente et l'écrit
+func (e *encryptWriter) seal(final bool) error {
+	sealed := e.aead.Seal(nil, sseNonce(e.index, final), e.buf, nil)
+	if _, err := e.w.Write(sealed); err != nil {
+		return err
+	}
+	e.index++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// decryptReader lit en clair un objet chiffré par encryptWriter ; Seek ne déchiffre que le bloc de la position visée
+type decryptReader struct {
+	src    io.ReadSeekCloser
+	aead   cipher.AEAD
+	size   int64 // taille du contenu en clair
+	offset int64
+	loaded int64 // numéro du bloc déchiffré dans chunk, -1 si aucun
+	chunk  []byte
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	if d.offset >= d.size {
+		return 0, io.EOF
+	}
+	index := d.offset / sseChunkSize
+	if index != d.loaded {
+		if err := d.load(index); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.chunk[d.offset-index*sseChunkSize:])
+	d.offset += int64(n)
+	return n, nil
+}
+
+// load lit et déchiffre un bloc ; un bloc altéré, déplacé ou tronqué est refusé par GCM
+func (d *decryptReader) load(index int64) error {
+	plainLen := d.size - index*sseChunkSize
+	if plainLen > sseChunkSize {
+		plainLen = sseChunkSize
+	}
+	if _, err := d.src.Seek(int64(len(sseHeader))+index*(sseChunkSize+sseOverhead), io.SeekStart); err != nil {
+		return err
+	}
+	sealed := make([]byte, plainLen+sseOverhead)
+	if _, err := io.ReadFull(d.src, sealed); err != nil {
+		return fmt.Errorf("storage: bloc chiffré %d incomplet: %w", index, err)
+	}
+	final := index == (d.size-1)/sseChunkSize
+	chunk, err := d.aead.Open(sealed[:0], sseNonce(index, final), sealed, nil)
+	if err != nil {
+		return fmt.Errorf("storage: bloc chiffré %d invalide: %w", index, err)
+	}
+	d.chunk, d.loaded = chunk, index
+	return nil
+}
+
+func (d *decryptReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += d.offset
+	case io.SeekEnd:
+		offset += d.size
+	default:
+		return 0, errors.New("storage: origine de positionnement invalide")
+	}
+	if offset < 0 {
+		return 0, errors.New("storage: position négative")
+	}
+	d.offset = offset
+	return offset, nil
+}
+
+func (d *decryptReader) Close() error {
+	return d.src.Close()
+}
+
+// segmentReader lit en clair un objet multipart SSE-C, fait de parts chiffrées séparément et mises bout à bout :
+// chaque part est lue par son propre decryptReader
+type segmentReader struct {
+	file     io.ReadSeekCloser
+	segments []*decryptReader
+	starts   []int64 // position en clair du début de chaque part
+	size     int64
+	offset   int64
+}
+
+// newSegmentReader construit le segmentReader d'un objet dont metadata.Encryption.Segments nomme les parts
+// et metadata.PartSizes donne leurs tailles en clair
+func newSegmentReader(file io.ReadSeekCloser, metadata ObjectMetadata, dataKey []byte) (*segmentReader, error) {
+	names := metadata.Encryption.Segments
+	if len(names) != len(metadata.PartSizes) {
+		return nil, errors.New("storage: parts chiffrées de l'objet incohérentes")
+	}
+	r := &segmentReader{file: file}
+	var base int64
+	for i, name := range names {
+		aead, err := newAEAD(deriveKey(dataKey, name))
+		if err != nil {
+			return nil, err
+		}
+		size := metadata.PartSizes[i]
+		src := &sectionSeeker{src: file, base: base}
+		r.segments = append(r.segments, &decryptReader{src: src, aead: aead, size: size, loaded: -1})
+		r.starts = append(r.starts, r.size)
+		r.size += size
+		base += encryptedSize(size)
+	}
+	return r, nil
+}
+
+func (r *segmentReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	// Dernière part commençant avant la position : jamais une part vide, la position étant dans l'objet
+	i := sort.Search(len(r.starts), func(i int) bool { return r.starts[i] > r.offset }) - 1
+	segment := r.segments[i]
+	if _, err := segment.Seek(r.offset-r.starts[i], io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := segment.Read(p)
+	r.offset += int64(n)
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *segmentReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("storage: origine de positionnement invalide")
+	}
+	if offset < 0 {
+		return 0, errors.New("storage: position négative")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *segmentReader) Close() error {
+	return r.file.Close()
+}
+
+// sectionSeeker présente à un decryptReader la part d'un contenu commençant à base ; il ne ferme pas src,
+// partagé par toutes les parts
+type sectionSeeker struct {
+	src  io.ReadSeeker
+	base int64
+}
+
+func (s *sectionSeeker) Read(p []byte) (int, error) {
+	return s.src.Read(p)
+}
+
+// Seek n'accepte que des positions absolues, les seules utilisées par decryptReader
+func (s *sectionSeeker) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.New("storage: origine de positionnement invalide")
+	}
+	position, err := s.src.Seek(s.base+offset, io.SeekStart)
+	return position - s.base, err
+}
+
+func (s *sectionSeeker) Close() error {
+	return nil
+}
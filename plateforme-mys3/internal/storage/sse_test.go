@@ -0,0 +1,399 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"plateforme-mys3/internal/acl"
+	"testing"
+)
+
+// forEachEncryptedBackend exécute un test sur les deux backends, configurés avec une clé maître SSE-S3
+func forEachEncryptedBackend(t *testing.T, masterKey []byte, test func(t *testing.T, s Backend)) {
+	t.Run("filesystem", func(t *testing.T) {
+		s := NewStorage(t.TempDir())
+		s.MasterKey = masterKey
+		test(t, s)
+	})
+	t.Run("memory", func(t *testing.T) {
+		s := NewMemoryBackend()
+		s.MasterKey = masterKey
+		test(t, s)
+	})
+}
+
+// Test du chiffrement SSE-S3 : relecture complète et partielle à cheval sur plusieurs blocs, ETag du clair
+func TestSSES3(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{7}, SSEKeySize)
+	content := bytes.Repeat([]byte("0123456789abcdef"), 3*sseChunkSize/16+100)
+	sum := md5.Sum(content)
+
+	forEachEncryptedBackend(t, masterKey, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		result, err := s.PutObject("bucket", "key", bytes.NewReader(content), ObjectMetadata{Encryption: &EncryptionInfo{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.ETag != hex.EncodeToString(sum[:]) {
+			t.Errorf("Expected the ETag of the plaintext, got %s", result.ETag)
+		}
+		metadata, err := s.GetObjectMetadata("bucket", "key")
+		if err != nil || metadata.Size != int64(len(content)) || metadata.Encryption == nil || metadata.Encryption.WrappedKey == "" {
+			t.Fatalf("Expected a wrapped data key and the plaintext size, got %+v (%v)", metadata, err)
+		}
+
+		file, _, err := s.GetObjectVersion("bucket", "key", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil || !bytes.Equal(data, content) {
+			t.Fatalf("Expected the decrypted content (%d bytes), got %d bytes (%v)", len(content), len(data), err)
+		}
+
+		// Lecture partielle autour d'une frontière de blocs
+		offset := int64(sseChunkSize - 10)
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		part := make([]byte, 20)
+		if _, err := io.ReadFull(file, part); err != nil || !bytes.Equal(part, content[offset:offset+20]) {
+			t.Errorf("Expected %q at offset %d, got %q (%v)", content[offset:offset+20], offset, part, err)
+		}
+
+		if fs, ok := s.(*Storage); ok {
+			stored, err := os.ReadFile(fs.ObjectPath("bucket", "key"))
+			if err != nil || bytes.Contains(stored, content[:64]) || !bytes.HasPrefix(stored, sseHeader) {
+				t.Errorf("Expected the content to be encrypted on disk, header included (%v)", err)
+			}
+
+			// Métadonnées perdues : le contenu chiffré n'est ni décrit ni servi comme un contenu en clair
+			if err := os.Remove(fs.objectSidecarPath("bucket", "key", "meta")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fs.GetObjectMetadata("bucket", "key"); !errors.Is(err, ErrEncryptedWithoutKey) {
+				t.Errorf("Expected ErrEncryptedWithoutKey, got %v", err)
+			}
+			if _, _, err := fs.GetObjectVersion("bucket", "key", ""); !errors.Is(err, ErrEncryptedWithoutKey) {
+				t.Errorf("Expected ErrEncryptedWithoutKey on read, got %v", err)
+			}
+		}
+	})
+
+	// Sans clé maître, SSE-S3 est refusé
+	forEachEncryptedBackend(t, nil, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.PutObject("bucket", "key", bytes.NewReader(content), ObjectMetadata{Encryption: &EncryptionInfo{}}); !errors.Is(err, ErrSSENotConfigured) {
+			t.Errorf("Expected ErrSSENotConfigured, got %v", err)
+		}
+	})
+}
+
+// Test du chiffrement SSE-C : la clé du client est exigée, vérifiée et jamais enregistrée
+func TestSSEC(t *testing.T) {
+	customerKey := bytes.Repeat([]byte{42}, SSEKeySize)
+	otherKey := bytes.Repeat([]byte{43}, SSEKeySize)
+
+	forEachEncryptedBackend(t, nil, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		encryption := &EncryptionInfo{CustomerKey: customerKey, CustomerKeyMD5: CustomerKeyMD5(customerKey)}
+		if _, err := s.PutObject("bucket", "key", bytes.NewReader([]byte("secret")), ObjectMetadata{Encryption: encryption}); err != nil {
+			t.Fatal(err)
+		}
+		metadata, err := s.GetObjectMetadata("bucket", "key")
+		if err != nil || metadata.Encryption == nil || metadata.Encryption.CustomerKey != nil || !metadata.Encryption.CustomerProvided() {
+			t.Fatalf("Expected SSE-C metadata without the customer key, got %+v (%v)", metadata.Encryption, err)
+		}
+
+		if _, _, err := s.OpenObjectVersion("bucket", "key", "", nil); !errors.Is(err, ErrSSECustomerKeyRequired) {
+			t.Errorf("Expected ErrSSECustomerKeyRequired, got %v", err)
+		}
+		if _, _, err := s.OpenObjectVersion("bucket", "key", "", otherKey); !errors.Is(err, ErrSSECustomerKeyMismatch) {
+			t.Errorf("Expected ErrSSECustomerKeyMismatch, got %v", err)
+		}
+		file, _, err := s.OpenObjectVersion("bucket", "key", "", customerKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil || string(data) != "secret" {
+			t.Errorf("Expected secret, got %q (%v)", data, err)
+		}
+
+		// Une clé fournie pour un objet en clair est refusée
+		if _, err := s.PutObject("bucket", "plain", bytes.NewReader([]byte("plain")), ObjectMetadata{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.OpenObjectVersion("bucket", "plain", "", customerKey); !errors.Is(err, ErrSSENotApplicable) {
+			t.Errorf("Expected ErrSSENotApplicable, got %v", err)
+		}
+	})
+}
+
+// Test d'un upload multipart SSE-S3 : parts chiffrées sur disque, objet assemblé relu en clair
+func TestSSEMultipart(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{7}, SSEKeySize)
+	first := bytes.Repeat([]byte("0123456789abcdef"), MinPartSize/16)
+	last := []byte("fin du fichier en clair")
+
+	forEachEncryptedBackend(t, masterKey, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		uploadID, err := s.CreateMultipartUpload("bucket", "big.bin", acl.Owner{}, ObjectMetadata{Encryption: &EncryptionInfo{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var parts []CompletePart
+		for i, data := range [][]byte{first, last} {
+			part, err := s.UploadPart("bucket", "big.bin", uploadID, i+1, bytes.NewReader(data), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := md5.Sum(data)
+			if part.ETag != hex.EncodeToString(sum[:]) || part.Size != int64(len(data)) {
+				t.Errorf("Expected the ETag and size of the plaintext part, got %+v", part)
+			}
+			parts = append(parts, CompletePart{part.PartNumber, part.ETag})
+		}
+
+		if fs, ok := s.(*Storage); ok {
+			stored, err := s.ListParts("bucket", "big.bin", uploadID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, part := range stored {
+				data, err := os.ReadFile(fs.partDataPath(uploadID, part))
+				if err != nil || !bytes.HasPrefix(data, sseHeader) || bytes.Contains(data, last) || bytes.Contains(data, first[:64]) {
+					t.Errorf("Expected part %d to be encrypted on disk (%v)", part.PartNumber, err)
+				}
+			}
+		}
+
+		if _, err := s.CompleteMultipartUpload("bucket", "big.bin", uploadID, parts); err != nil {
+			t.Fatal(err)
+		}
+		file, _, err := s.GetObjectVersion("bucket", "big.bin", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil || !bytes.Equal(data, append(append([]byte(nil), first...), last...)) {
+			t.Errorf("Expected the assembled plaintext (%d bytes), got %d bytes (%v)", len(first)+len(last), len(data), err)
+		}
+	})
+
+	// Sans clé maître, l'upload SSE-S3 est refusé dès son initialisation
+	forEachEncryptedBackend(t, nil, func(t *testing.T, s Backend) {
+		s.CreateBucket("bucket", "")
+		if _, err := s.CreateMultipartUpload("bucket", "big.bin", acl.Owner{}, ObjectMetadata{Encryption: &EncryptionInfo{}}); !errors.Is(err, ErrSSENotConfigured) {
+			t.Errorf("Expected ErrSSENotConfigured, got %v", err)
+		}
+	})
+}
+
+// Test d'un upload multipart SSE-C : chaque part est chiffrée avec la clé envoyée avec elle, l'objet assemblé
+// se relit avec cette clé, y compris sur une plage à cheval sur deux parts
+func TestSSECustomerMultipart(t *testing.T) {
+	customerKey := bytes.Repeat([]byte{3}, SSEKeySize)
+	otherKey := bytes.Repeat([]byte{4}, SSEKeySize)
+	first := bytes.Repeat([]byte("0123456789abcdef"), MinPartSize/16+1)
+	last := []byte("fin du fichier en clair")
+	whole := append(append([]byte(nil), first...), last...)
+
+	forEachEncryptedBackend(t, nil, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		metadata := ObjectMetadata{Encryption: &EncryptionInfo{CustomerKey: customerKey, CustomerKeyMD5: CustomerKeyMD5(customerKey)}}
+		uploadID, err := s.CreateMultipartUpload("bucket", "big.bin", acl.Owner{}, metadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UploadPart("bucket", "big.bin", uploadID, 1, bytes.NewReader(first), nil); !errors.Is(err, ErrSSECustomerKeyRequired) {
+			t.Errorf("Expected ErrSSECustomerKeyRequired, got %v", err)
+		}
+		if _, err := s.UploadPart("bucket", "big.bin", uploadID, 1, bytes.NewReader(first), otherKey); !errors.Is(err, ErrSSECustomerKeyMismatch) {
+			t.Errorf("Expected ErrSSECustomerKeyMismatch, got %v", err)
+		}
+		var parts []CompletePart
+		for i, data := range [][]byte{first, last} {
+			part, err := s.UploadPart("bucket", "big.bin", uploadID, i+1, bytes.NewReader(data), customerKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := md5.Sum(data)
+			if part.ETag != hex.EncodeToString(sum[:]) || part.Size != int64(len(data)) {
+				t.Errorf("Expected the ETag and size of the plaintext part, got %+v", part)
+			}
+			parts = append(parts, CompletePart{part.PartNumber, part.ETag})
+		}
+		if _, err := s.CompleteMultipartUpload("bucket", "big.bin", uploadID, parts); err != nil {
+			t.Fatal(err)
+		}
+
+		if fs, ok := s.(*Storage); ok {
+			data, err := os.ReadFile(fs.ObjectPath("bucket", "big.bin"))
+			if err != nil || !bytes.HasPrefix(data, sseHeader) || bytes.Contains(data, last) || bytes.Contains(data, first[:64]) {
+				t.Errorf("Expected the object to be encrypted on disk (%v)", err)
+			}
+		}
+		if _, _, err := s.OpenObjectVersion("bucket", "big.bin", "", nil); !errors.Is(err, ErrSSECustomerKeyRequired) {
+			t.Errorf("Expected ErrSSECustomerKeyRequired, got %v", err)
+		}
+		if _, _, err := s.OpenObjectVersion("bucket", "big.bin", "", otherKey); !errors.Is(err, ErrSSECustomerKeyMismatch) {
+			t.Errorf("Expected ErrSSECustomerKeyMismatch, got %v", err)
+		}
+		file, stored, err := s.OpenObjectVersion("bucket", "big.bin", "", customerKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if stored.Size != int64(len(whole)) {
+			t.Errorf("Expected size %d, got %d", len(whole), stored.Size)
+		}
+		data, err := io.ReadAll(file)
+		if err != nil || !bytes.Equal(data, whole) {
+			t.Errorf("Expected the assembled plaintext (%d bytes), got %d bytes (%v)", len(whole), len(data), err)
+		}
+		if _, err := file.Seek(int64(len(first))-10, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		span := make([]byte, 20)
+		if _, err := io.ReadFull(file, span); err != nil || !bytes.Equal(span, whole[len(first)-10:len(first)+10]) {
+			t.Errorf("Expected a range spanning both parts, got %q (%v)", span, err)
+		}
+
+		// Une clé du client envoyée pour un upload en clair est refusée
+		plainID, err := s.CreateMultipartUpload("bucket", "plain.bin", acl.Owner{}, ObjectMetadata{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UploadPart("bucket", "plain.bin", plainID, 1, bytes.NewReader(last), customerKey); !errors.Is(err, ErrSSENotApplicable) {
+			t.Errorf("Expected ErrSSENotApplicable, got %v", err)
+		}
+	})
+}
+
+// Test de la configuration de chiffrement par défaut d'un bucket
+func TestBucketEncryption(t *testing.T) {
+	forEachEncryptedBackend(t, bytes.Repeat([]byte{7}, SSEKeySize), func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetBucketEncryption("bucket"); !errors.Is(err, ErrNoSuchEncryptionConfiguration) {
+			t.Errorf("Expected ErrNoSuchEncryptionConfiguration, got %v", err)
+		}
+		if err := s.PutBucketEncryption("bucket", BucketEncryption{SSEAlgorithm: "aws:kms"}); !errors.Is(err, ErrInvalidEncryptionAlgorithm) {
+			t.Errorf("Expected ErrInvalidEncryptionAlgorithm, got %v", err)
+		}
+		if err := s.PutBucketEncryption("missing", BucketEncryption{SSEAlgorithm: SSEAlgorithm}); !errors.Is(err, ErrNoSuchBucket) {
+			t.Errorf("Expected ErrNoSuchBucket, got %v", err)
+		}
+		config := BucketEncryption{SSEAlgorithm: SSEAlgorithm, BucketKeyEnabled: true}
+		if err := s.PutBucketEncryption("bucket", config); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.GetBucketEncryption("bucket"); err != nil || got != config {
+			t.Errorf("Expected %+v, got %+v (%v)", config, got, err)
+		}
+		if err := s.DeleteBucketEncryption("bucket"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetBucketEncryption("bucket"); !errors.Is(err, ErrNoSuchEncryptionConfiguration) {
+			t.Errorf("Expected the configuration to be deleted, got %v", err)
+		}
+	})
+
+	// Sans clé maître, le chiffrement par défaut est refusé
+	forEachEncryptedBackend(t, nil, func(t *testing.T, s Backend) {
+		s.CreateBucket("bucket", "")
+		if err := s.PutBucketEncryption("bucket", BucketEncryption{SSEAlgorithm: SSEAlgorithm}); !errors.Is(err, ErrSSENotConfigured) {
+			t.Errorf("Expected ErrSSENotConfigured, got %v", err)
+		}
+	})
+}
+
+// Test de la rotation de la clé maître : les clés de données de toutes les versions SSE-S3 sont réenveloppées
+func TestRotateDataKeys(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{7}, SSEKeySize)
+	newKey := bytes.Repeat([]byte{8}, SSEKeySize)
+	customerKey := bytes.Repeat([]byte{9}, SSEKeySize)
+
+	test := func(t *testing.T, s Backend, setKeys func(master, previous []byte)) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PutBucketVersioning("bucket", VersioningEnabled); err != nil {
+			t.Fatal(err)
+		}
+		for _, content := range []string{"v1", "v2"} {
+			if _, err := s.PutObject("bucket", "key", bytes.NewReader([]byte(content)), ObjectMetadata{Encryption: &EncryptionInfo{}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s.PutObject("bucket", "plain", bytes.NewReader([]byte("plain")), ObjectMetadata{})
+		customer := &EncryptionInfo{CustomerKey: customerKey, CustomerKeyMD5: CustomerKeyMD5(customerKey)}
+		s.PutObject("bucket", "customer", bytes.NewReader([]byte("customer")), ObjectMetadata{Encryption: customer})
+
+		// Nouvelle clé maître : les objets restent lisibles grâce à la clé précédente
+		setKeys(newKey, oldKey)
+		readAll := func(versionID string) string {
+			file, _, err := s.GetObjectVersion("bucket", "key", versionID)
+			if err != nil {
+				t.Fatalf("Unexpected error reading version %q: %v", versionID, err)
+			}
+			defer file.Close()
+			data, _ := io.ReadAll(file)
+			return string(data)
+		}
+		if got := readAll(""); got != "v2" {
+			t.Errorf("Expected v2 during the rotation, got %q", got)
+		}
+
+		p, err := RotateDataKeys(context.Background(), s, nil)
+		if err != nil || p.Rewrapped != 2 || p.Buckets != 1 {
+			t.Fatalf("Expected 2 rewrapped keys in 1 bucket, got %+v (%v)", p, err)
+		}
+		// Sans la clé précédente, toutes les versions sont lisibles avec la nouvelle clé seule
+		setKeys(newKey, nil)
+		versions, err := s.ListObjectVersions("bucket", ListObjectsOptions{Prefix: "key", MaxKeys: 10}, "")
+		if err != nil || len(versions.Versions) != 2 {
+			t.Fatalf("Expected 2 versions, got %+v (%v)", versions, err)
+		}
+		for i, version := range versions.Versions {
+			if got, expected := readAll(version.Metadata.VersionID), []string{"v2", "v1"}[i]; got != expected {
+				t.Errorf("Expected %s after the rotation, got %q", expected, got)
+			}
+		}
+
+		// Une rotation relancée n'a plus rien à faire
+		if p, err := RotateDataKeys(context.Background(), s, nil); err != nil || p.Rewrapped != 0 {
+			t.Errorf("Expected nothing to rewrap, got %+v (%v)", p, err)
+		}
+	}
+
+	t.Run("filesystem", func(t *testing.T) {
+		s := NewStorage(t.TempDir())
+		s.MasterKey = oldKey
+		test(t, s, func(master, previous []byte) { s.MasterKey, s.PreviousMasterKey = master, previous })
+	})
+	t.Run("memory", func(t *testing.T) {
+		s := NewMemoryBackend()
+		s.MasterKey = oldKey
+		test(t, s, func(master, previous []byte) { s.MasterKey, s.PreviousMasterKey = master, previous })
+	})
+}
@@ -2,15 +2,34 @@
 package storage
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
+// systemDir est le répertoire interne (uploads multipart, fichiers temporaires...) placé à la racine du stockage.
+// Son nom commence par un point, ce qui est interdit pour un bucket : aucune collision possible.
+const systemDir = ".mys3"
+
 // Storage représente le stockage des buckets
 type Storage struct {
 	BasePath string
+	// MasterKey est la clé maître (32 octets) qui enveloppe les clés de données des objets SSE-S3 ;
+	// nil si le chiffrement SSE-S3 n'est pas configuré
+	MasterKey []byte
+	// PreviousMasterKey est l'ancienne clé maître pendant une rotation (voir RotateDataKeys) : les objets dont
+	// la clé de données n'a pas encore été réenveloppée restent lisibles. nil hors rotation.
+	PreviousMasterKey []byte
+	locks             keyLocks // verrous par clé sérialisant les écritures d'un même objet
 }
 
 // NewStorage initialise le stockage avec le chemin de base spécifié
@@ -18,31 +37,49 @@ func NewStorage(basePath string) *Storage {
 	return &Storage{BasePath: basePath}
 }
 
-// BucketPath retourne le chemin complet d'un bucket
+// BucketPath retourne le chemin complet d'un bucket. Le nom est échappé : un nom comme ".." ou "a/b"
+// ne sort jamais du répertoire de base (et ne correspond à aucun bucket, CreateBucket les refusant).
 func (s *Storage) BucketPath(bucketName string) string {
-	return filepath.Join(s.BasePath, bucketName)
+	return filepath.Join(s.BasePath, escapeSegment(bucketName))
 }
 
-// ObjectPath retourne le chemin complet d'un objet dans un bucket
+// ObjectPath retourne le chemin complet d'un objet dans un bucket, la clé étant encodée par encodeKey
 func (s *Storage) ObjectPath(bucketName, objectName string) string {
-	return filepath.Join(s.BasePath, bucketName, objectName)
+	return filepath.Join(s.BucketPath(bucketName), filepath.FromSlash(encodeKey(objectName)))
 }
 
-// CreateBucket crée un nouveau bucket en créant un dossier
-func (s *Storage) CreateBucket(bucketName string) error {
+// CreateBucket crée un nouveau bucket en créant un dossier, et enregistre son propriétaire.
+// Un nom invalide retourne ErrInvalidBucketName ; un bucket existant, ErrBucketAlreadyOwnedByYou ou ErrBucketAlreadyExists.
+func (s *Storage) CreateBucket(bucketName, owner string) error {
+	if !validBucketName(bucketName) {
+		return ErrInvalidBucketName
+	}
 	bucketPath := s.BucketPath(bucketName)
 	log.Printf("Tentative de création du bucket à l'emplacement : %s", bucketPath)
 
-	// Vérifier si le bucket existe déjà
-	if _, err := os.Stat(bucketPath); !os.IsNotExist(err) {
-		log.Printf("Le bucket %s existe déjà ou une erreur est survenue : %v", bucketName, err)
+	// Créer le dossier du bucket : os.Mkdir échoue si le bucket existe déjà, même en cas de création concurrente
+	if err := os.MkdirAll(s.BasePath, 0755); err != nil {
 		return err
 	}
+	if err := os.Mkdir(bucketPath, 0755); err != nil {
+		if !os.IsExist(err) {
+			log.Printf("Erreur lors de la création du dossier du bucket %s : %v", bucketName, err)
+			return err
+		}
+		existingOwner, err := s.BucketOwner(bucketName)
+		if err != nil {
+			return err
+		}
+		log.Printf("Le bucket %s existe déjà", bucketName)
+		return existingBucketError(existingOwner, owner)
+	}
 
-	// Créer le dossier du bucket
-	err := os.MkdirAll(bucketPath, 0755)
+	info, err := json.Marshal(bucketInfo{Owner: owner})
 	if err != nil {
-		log.Printf("Erreur lors de la création du dossier du bucket %s : %v", bucketName, err)
+		return err
+	}
+	if err := s.putBucketConfig(bucketName, "bucket.json", info); err != nil {
+		log.Printf("Erreur lors de l'enregistrement du propriétaire du bucket %s : %v", bucketName, err)
 		return err
 	}
 
@@ -50,47 +87,265 @@ func (s *Storage) CreateBucket(bucketName string) error {
 	return nil
 }
 
-// DeleteBucket supprime un bucket en supprimant son dossier
+// DeleteBucket supprime un bucket vide, ses configurations et ses uploads multipart en cours.
+// Un bucket contenant encore un objet, une version ou un marqueur de suppression n'est pas touché
+// (ErrBucketNotEmpty) : aucun fichier de données n'est jamais supprimé ici, voir PurgeBucket.
 func (s *Storage) DeleteBucket(bucketName string) error {
-	return os.RemoveAll(s.BucketPath(bucketName))
+	if !s.BucketExists(bucketName) {
+		return ErrNoSuchBucket
+	}
+	bucketPath := s.BucketPath(bucketName)
+	for _, dir := range []string{bucketPath, filepath.Join(s.bucketConfigDir(bucketName), "versions")} {
+		hasFiles, err := containsFiles(dir)
+		if err != nil {
+			return err
+		}
+		if hasFiles {
+			return ErrBucketNotEmpty
+		}
+	}
+
+	uploads, err := s.ListMultipartUploads(bucketName)
+	if err != nil {
+		return err
+	}
+	for _, upload := range uploads {
+		if err := s.AbortMultipartUpload(bucketName, upload.Key, upload.UploadID); err != nil && err != ErrNoSuchUpload {
+			return err
+		}
+	}
+
+	// Seuls des répertoires vides restent (préfixes de clés supprimées) : os.Remove échoue sur un répertoire
+	// où un objet vient d'être écrit, qui est alors conservé
+	if err := removeEmptyDirs(bucketPath); err != nil {
+		if entries, readErr := os.ReadDir(bucketPath); readErr == nil && len(entries) > 0 {
+			return ErrBucketNotEmpty
+		}
+		return err
+	}
+	log.Printf("Bucket %s supprimé", bucketName)
+	return os.RemoveAll(s.bucketConfigDir(bucketName))
+}
+
+// errStopWalk interrompt un parcours de répertoires
+var errStopWalk = errors.New("storage: parcours interrompu")
+
+// containsFiles indique si un répertoire (absent : false) contient au moins un fichier, sous-répertoires compris
+func containsFiles(dir string) (bool, error) {
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return errStopWalk
+		}
+		return nil
+	})
+	if err == errStopWalk {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// removeEmptyDirs supprime un répertoire et ses sous-répertoires, qui doivent être vides (les plus profonds d'abord)
+func removeEmptyDirs(root string) error {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Remove(dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BucketExists indique si le bucket existe
+func (s *Storage) BucketExists(bucketName string) bool {
+	info, err := os.Stat(s.BucketPath(bucketName))
+	return err == nil && info.IsDir()
 }
 
 // ListBuckets liste tous les buckets existants
-func (s *Storage) ListBuckets() ([]os.FileInfo, error) {
+func (s *Storage) ListBuckets() ([]BucketInfo, error) {
 	dirEntries, err := os.ReadDir(s.BasePath)
 	if err != nil {
 		return nil, err
 	}
 
-	var fileInfos []os.FileInfo
+	var buckets []BucketInfo
 	for _, entry := range dirEntries {
-		if entry.IsDir() {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
 			info, err := entry.Info()
 			if err != nil {
 				continue
 			}
-			fileInfos = append(fileInfos, info)
+			buckets = append(buckets, BucketInfo{Name: info.Name(), CreationDate: info.ModTime()})
 		}
 	}
-	return fileInfos, nil
+	return buckets, nil
 }
 
-// PutObject ajoute un objet dans un bucket
-func (s *Storage) PutObject(bucketName, objectName string, data io.Reader) error {
-	objectPath := s.ObjectPath(bucketName, objectName)
-	dir := filepath.Dir(objectPath)
-	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-		return err
+// PutResult décrit un objet écrit par PutObject
+type PutResult struct {
+	ETag      string // MD5 hexadécimal du contenu
+	SHA256    string // SHA-256 hexadécimal du contenu
+	Checksum  string // somme de contrôle metadata.ChecksumAlgorithm du contenu (base64), si demandée
+	Size      int64
+	VersionID string // vide si le versionnage n'a jamais été activé sur le bucket
+}
+
+// PutObject ajoute un objet dans un bucket avec ses métadonnées (ETag, taille et date sont calculés ici) et,
+// si metadata.ACL est renseigné, son ACL, mise en place avec l'objet.
+// Le contenu est copié en flux dans un fichier temporaire pendant que MD5 et SHA-256 sont calculés ;
+// l'objet n'est mis en place qu'une fois le flux lu sans erreur et synchronisé sur disque, sinon le fichier
+// temporaire est supprimé. Des PUT concurrents sur une même clé sont appliqués l'un après l'autre : le dernier
+// mis en place l'emporte, et un lecteur voit toujours un objet complet avec ses propres métadonnées.
+func (s *Storage) PutObject(bucketName, objectName string, data io.Reader, metadata ObjectMetadata) (PutResult, error) {
+	return s.putObject(bucketName, objectName, data, metadata, "")
+}
+
+// putObject écrit un objet ; etag remplace le MD5 du contenu s'il est fourni (ETag d'un upload multipart)
+func (s *Storage) putObject(bucketName, objectName string, data io.Reader, metadata ObjectMetadata, etag string) (PutResult, error) {
+	// Un bucket absent est signalé avant de recevoir le contenu ; sa suppression pendant la réception
+	// est détectée à la mise en place
+	if !s.BucketExists(bucketName) {
+		return PutResult{}, ErrNoSuchBucket
+	}
+	checksum, err := checksumHash(metadata)
+	if err != nil {
+		return PutResult{}, err
 	}
+	// Les parts d'un objet multipart SSE-C arrivent déjà chiffrées (voir EncryptionInfo.Segments) : elles sont
+	// écrites telles quelles, et la taille de l'objet est celle de leurs contenus en clair
+	sealed := metadata.Encryption != nil && len(metadata.Encryption.Segments) > 0
+	var dataKey []byte
+	if !sealed {
+		if dataKey, err = newDataKey(&metadata, s.MasterKey); err != nil {
+			return PutResult{}, err
+		}
+	}
+	// Le répertoire temporaire est sous BasePath : le renommage final reste sur le même système de fichiers
+	if err := os.MkdirAll(s.tmpDir(), 0755); err != nil {
+		return PutResult{}, err
+	}
+	tmp, err := os.CreateTemp(s.tmpDir(), "put-")
+	if err != nil {
+		return PutResult{}, err
+	}
+	keepTmp := false
+	defer func() {
+		if !keepTmp {
+			os.Remove(tmp.Name())
+		}
+	}()
 
-	file, err := os.Create(objectPath)
+	// Empreintes et taille portent sur le contenu en clair, seul le fichier reçoit le contenu chiffré
+	var out io.Writer = tmp
+	var encrypter *encryptWriter
+	if dataKey != nil {
+		if encrypter, err = newEncryptWriter(tmp, dataKey); err != nil {
+			tmp.Close()
+			return PutResult{}, err
+		}
+		out = encrypter
+	}
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	writers := []io.Writer{out, md5Hash, sha256Hash}
+	if checksum != nil {
+		writers = append(writers, checksum)
+	}
+	size, err := io.Copy(io.MultiWriter(writers...), data)
+	if err == nil && encrypter != nil {
+		err = encrypter.Close()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return err
+		return PutResult{}, err
 	}
-	defer file.Close()
 
-	_, err = io.Copy(file, data)
-	return err
+	// Le flux est lu hors verrou : seule la mise en place, rapide, est sérialisée
+	unlock := s.locks.lock(bucketName, objectName)
+	defer unlock()
+
+	status, err := s.GetBucketVersioning(bucketName)
+	if err != nil {
+		return PutResult{}, err
+	}
+	versionID := ""
+	switch status {
+	case VersioningEnabled:
+		if versionID, err = newVersionID(); err != nil {
+			return PutResult{}, err
+		}
+	case VersioningSuspended:
+		versionID = NullVersionID
+	}
+
+	if etag == "" {
+		etag = hex.EncodeToString(md5Hash.Sum(nil))
+	}
+	if sealed {
+		size = 0
+		for _, partSize := range metadata.PartSizes {
+			size += partSize
+		}
+	}
+	metadata.ETag = etag
+	metadata.Size = size
+	metadata.LastModified = time.Now().UTC()
+	metadata.VersionID = versionID
+	metadata.Checksum = encodeChecksum(checksum)
+
+	// Une fois l'écriture inscrite au journal, elle est menée à terme : en cas d'échec d'une étape, le contenu
+	// préparé et l'entrée sont conservés pour que RecoverWrites la termine au prochain démarrage
+	intent := writeIntent{
+		Bucket:     bucketName,
+		Key:        objectName,
+		Data:       filepath.Base(tmp.Name()),
+		Versioning: status,
+		Metadata:   metadata,
+		ACL:        metadata.ACL,
+	}
+	intentPath, err := s.beginWrite(intent)
+	if err != nil {
+		return PutResult{}, err
+	}
+	if err := s.completeWrite(intent); err != nil {
+		keepTmp = true
+		log.Printf("Écriture de %s/%s interrompue, reprise au prochain démarrage : %v", bucketName, objectName, err)
+		return PutResult{}, err
+	}
+	if err := os.Remove(intentPath); err != nil {
+		log.Printf("Erreur lors de la suppression de l'entrée du journal %s: %v", intentPath, err)
+	}
+
+	return PutResult{
+		ETag:      etag,
+		SHA256:    hex.EncodeToString(sha256Hash.Sum(nil)),
+		Checksum:  metadata.Checksum,
+		Size:      size,
+		VersionID: versionID,
+	}, nil
 }
 
 // GetObject récupère un objet depuis un bucket
@@ -98,27 +353,14 @@ func (s *Storage) GetObject(bucketName, objectName string) (*os.File, error) {
 	return os.Open(s.ObjectPath(bucketName, objectName))
 }
 
-// DeleteObject supprime un objet depuis un bucket
-func (s *Storage) DeleteObject(bucketName, objectName string) error {
-	return os.Remove(s.ObjectPath(bucketName, objectName))
-}
-
-// ListObjects liste tous les objets dans un bucket
-func (s *Storage) ListObjects(bucketName string) ([]os.FileInfo, error) {
-	dirEntries, err := os.ReadDir(s.BucketPath(bucketName))
+// StatObject retourne les informations d'un objet sans l'ouvrir
+func (s *Storage) StatObject(bucketName, objectName string) (os.FileInfo, error) {
+	info, err := os.Stat(s.ObjectPath(bucketName, objectName))
 	if err != nil {
 		return nil, err
 	}
-
-	var fileInfos []os.FileInfo
-	for _, entry := range dirEntries {
-		if !entry.IsDir() {
-			info, err := entry.Info()
-			if err != nil {
-				continue
-			}
-			fileInfos = append(fileInfos, info)
-		}
+	if info.IsDir() {
+		return nil, os.ErrNotExist
 	}
-	return fileInfos, nil
+	return info, nil
 }
@@ -0,0 +1,942 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"plateforme-mys3/internal/acl"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Test de l'écriture en flux d'un objet et des empreintes calculées
+func TestPutObject(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.PutObject("bucket", "dir/key.txt", strings.NewReader("hello"), ObjectMetadata{})
+	if err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	md5Sum := md5.Sum([]byte("hello"))
+	shaSum := sha256.Sum256([]byte("hello"))
+	if result.ETag != hex.EncodeToString(md5Sum[:]) || result.SHA256 != hex.EncodeToString(shaSum[:]) || result.Size != 5 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	data, err := os.ReadFile(s.ObjectPath("bucket", "dir/key.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Expected stored content %q, got %q (%v)", "hello", data, err)
+	}
+}
+
+// failingReader renvoie une erreur après avoir produit quelques octets
+type failingReader struct{ done bool }
+
+var errBrokenBody = errors.New("corps interrompu")
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.done {
+		return 0, errBrokenBody
+	}
+	f.done = true
+	return copy(p, "partiel"), nil
+}
+
+// Test de l'abandon d'une écriture dont le flux échoue
+func TestPutObjectAbortsOnReadError(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.PutObject("bucket", "key", io.MultiReader(&failingReader{}), ObjectMetadata{}); !errors.Is(err, errBrokenBody) {
+		t.Fatalf("Expected errBrokenBody, got %v", err)
+	}
+	if _, err := s.StatObject("bucket", "key"); !os.IsNotExist(err) {
+		t.Errorf("Expected no object after a failed write, got %v", err)
+	}
+}
+
+// Test d'une écriture dans un bucket absent : refusée sans lire le contenu
+func TestPutObjectMissingBucket(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if _, err := s.PutObject("missing", "key", &failingReader{}, ObjectMetadata{}); !errors.Is(err, ErrNoSuchBucket) {
+		t.Errorf("Expected ErrNoSuchBucket before reading the body, got %v", err)
+	}
+	if _, err := s.UploadPart("missing", "key", strings.Repeat("0", 32), 1, &failingReader{}, nil); !errors.Is(err, ErrNoSuchBucket) {
+		t.Errorf("Expected ErrNoSuchBucket for a part, got %v", err)
+	}
+	if _, err := os.Stat(s.tmpDir()); !os.IsNotExist(err) {
+		t.Errorf("Expected no temporary file to be created, got %v", err)
+	}
+}
+
+// Test des écritures concurrentes sur une même clé : chaque lecture voit un objet complet, décrit par ses
+// propres métadonnées, et le dernier PUT l'emporte, sur chaque backend
+func TestConcurrentPuts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.PutObject("bucket", "key", strings.NewReader("initial"), ObjectMetadata{}); err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 64)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				content := bytes.Repeat([]byte{byte('a' + i)}, 64<<10+i)
+				for j := 0; j < 5; j++ {
+					if _, err := s.PutObject("bucket", "key", bytes.NewReader(content), ObjectMetadata{}); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(i)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					file, metadata, err := s.GetObjectVersion("bucket", "key", "")
+					if err != nil {
+						errs <- err
+						return
+					}
+					data, err := io.ReadAll(file)
+					file.Close()
+					sum := md5.Sum(data)
+					if err != nil || int64(len(data)) != metadata.Size || hex.EncodeToString(sum[:]) != metadata.ETag {
+						errs <- fmt.Errorf("read %d bytes (%v) not matching metadata %+v", len(data), err, metadata)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+
+		if _, err := s.PutObject("bucket", "key", strings.NewReader("last"), ObjectMetadata{}); err != nil {
+			t.Fatal(err)
+		}
+		file, _, err := s.GetObjectVersion("bucket", "key", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if data, _ := io.ReadAll(file); string(data) != "last" {
+			t.Errorf("Expected the last write to win, got %q", data)
+		}
+	})
+}
+
+// Test du nettoyage au démarrage des fichiers temporaires d'écritures interrompues
+func TestRemoveStaleTempFiles(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutObject("bucket", "key", strings.NewReader("data"), ObjectMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fichiers laissés par un arrêt brutal : contenu d'un PUT et document JSON en cours d'écriture
+	os.WriteFile(filepath.Join(s.tmpDir(), "put-123"), []byte("partial"), 0600)
+	metaPath := s.objectSidecarPath("bucket", "key", "meta")
+	os.WriteFile(filepath.Join(filepath.Dir(metaPath), tmpPrefix+"key.meta.json-456"), []byte("{"), 0600)
+
+	removed, err := s.RemoveStaleTempFiles()
+	if err != nil || removed != 2 {
+		t.Fatalf("Expected 2 stale files removed, got %d (%v)", removed, err)
+	}
+	if _, err := os.Stat(metaPath); err != nil {
+		t.Errorf("Expected object metadata to be kept: %v", err)
+	}
+	if _, err := s.GetObjectMetadata("bucket", "key"); err != nil {
+		t.Errorf("GetObjectMetadata: %v", err)
+	}
+}
+
+// Test de la reprise d'une écriture interrompue à chaque étape de sa mise en place, avec et sans versionnage
+func TestRecoverWrites(t *testing.T) {
+	owner := acl.Owner{ID: "alice", DisplayName: "alice"}
+	publicRead, err := acl.Canned("public-read", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { writeFailpoint = nil }()
+
+	for _, versioning := range []string{"", VersioningEnabled} {
+		for _, step := range []string{"archive", "rename", "acl", "metadata"} {
+			t.Run(versioning+"/"+step, func(t *testing.T) {
+				dir := t.TempDir()
+				s := NewStorage(dir)
+				if err := s.CreateBucket("bucket", "alice"); err != nil {
+					t.Fatal(err)
+				}
+				if versioning != "" {
+					if err := s.PutBucketVersioning("bucket", versioning); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if _, err := s.PutObject("bucket", "key", strings.NewReader("old"), ObjectMetadata{ContentType: "text/old"}); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.PutObjectACL("bucket", "key", publicRead); err != nil {
+					t.Fatal(err)
+				}
+
+				// Panne simulée avant l'étape : l'écriture échoue, son entrée du journal reste
+				writeFailpoint = func(current string) error {
+					if current == step {
+						return errors.New("panne simulée")
+					}
+					return nil
+				}
+				_, err := s.PutObject("bucket", "key", strings.NewReader("new"), ObjectMetadata{ContentType: "text/new"})
+				writeFailpoint = nil
+				if err == nil {
+					t.Fatal("Expected the write to fail")
+				}
+				if step == "archive" || step == "rename" {
+					// Rien n'a encore été remplacé : l'ancien objet est intact, métadonnées et ACL comprises
+					metadata, err := s.GetObjectMetadata("bucket", "key")
+					if err != nil || metadata.ContentType != "text/old" {
+						t.Errorf("Expected the old object before recovery, got %+v (%v)", metadata, err)
+					}
+				}
+
+				// Redémarrage : l'écriture est terminée, puis les fichiers temporaires nettoyés
+				s = NewStorage(dir)
+				recovered, err := s.RecoverWrites()
+				if err != nil || recovered != 1 {
+					t.Fatalf("Expected 1 recovered write, got %d (%v)", recovered, err)
+				}
+				if _, err := s.RemoveStaleTempFiles(); err != nil {
+					t.Fatal(err)
+				}
+				file, metadata, err := s.GetObjectVersion("bucket", "key", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(file)
+				file.Close()
+				if string(data) != "new" || metadata.ContentType != "text/new" {
+					t.Errorf("Expected the new object, got %q %+v", data, metadata)
+				}
+				if objectACL, err := s.GetObjectACL("bucket", "key"); err != nil || len(objectACL.Grants) != 1 {
+					t.Errorf("Expected the new object to start with a private ACL, got %+v (%v)", objectACL, err)
+				}
+
+				if versioning == VersioningEnabled {
+					versions, err := s.ListObjectVersions("bucket", ListObjectsOptions{MaxKeys: 10}, "")
+					if err != nil || len(versions.Versions) != 2 {
+						t.Fatalf("Expected 2 versions, got %+v (%v)", versions, err)
+					}
+					if old := versions.Versions[1].Metadata; old.ContentType != "text/old" {
+						t.Errorf("Expected the archived version to keep its metadata, got %+v", old)
+					}
+				}
+				if entries, _ := os.ReadDir(s.journalDir()); len(entries) != 0 {
+					t.Errorf("Expected an empty journal, got %d entries", len(entries))
+				}
+			})
+		}
+	}
+
+	// L'ACL demandée avec l'écriture est posée par la même entrée du journal, jamais après coup
+	authenticatedRead, err := acl.Canned("authenticated-read", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []string{"rename", "acl", "metadata"} {
+		dir := t.TempDir()
+		s := NewStorage(dir)
+		if err := s.CreateBucket("bucket", "alice"); err != nil {
+			t.Fatal(err)
+		}
+		writeFailpoint = func(current string) error {
+			if current == step {
+				return errors.New("panne simulée")
+			}
+			return nil
+		}
+		_, err := s.PutObject("bucket", "key", strings.NewReader("new"), ObjectMetadata{ACL: &authenticatedRead})
+		writeFailpoint = nil
+		if err == nil {
+			t.Fatal("Expected the write to fail")
+		}
+		s = NewStorage(dir)
+		if recovered, err := s.RecoverWrites(); err != nil || recovered != 1 {
+			t.Fatalf("%s: expected 1 recovered write, got %d (%v)", step, recovered, err)
+		}
+		if objectACL, err := s.GetObjectACL("bucket", "key"); err != nil || len(objectACL.Grants) != 2 {
+			t.Errorf("%s: expected the requested ACL after recovery, got %+v (%v)", step, objectACL, err)
+		}
+	}
+
+	// Une écriture en échec suivie d'une autre n'est pas rejouée par-dessus la plus récente
+	dir := t.TempDir()
+	s := NewStorage(dir)
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	writeFailpoint = func(step string) error { return errors.New("panne simulée") }
+	if _, err := s.PutObject("bucket", "key", strings.NewReader("failed"), ObjectMetadata{}); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	writeFailpoint = nil
+	if _, err := s.PutObject("bucket", "key", strings.NewReader("latest"), ObjectMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+	s = NewStorage(dir)
+	if recovered, err := s.RecoverWrites(); err != nil || recovered != 0 {
+		t.Fatalf("Expected the stale write to be discarded, got %d (%v)", recovered, err)
+	}
+	if data, err := os.ReadFile(s.ObjectPath("bucket", "key")); err != nil || string(data) != "latest" {
+		t.Errorf("Expected the latest write to be kept, got %q (%v)", data, err)
+	}
+}
+
+// Test de la reprise d'une suppression versionnée interrompue : le marqueur passe par le journal des écritures
+func TestRecoverDeleteMarker(t *testing.T) {
+	defer func() { writeFailpoint = nil }()
+
+	for versioning, expectedVersions := range map[string]int{VersioningEnabled: 2, VersioningSuspended: 1} {
+		for _, step := range []string{"archive", "remove", "metadata"} {
+			t.Run(versioning+"/"+step, func(t *testing.T) {
+				dir := t.TempDir()
+				s := NewStorage(dir)
+				if err := s.CreateBucket("bucket", ""); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.PutBucketVersioning("bucket", versioning); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := s.PutObject("bucket", "key", strings.NewReader("old"), ObjectMetadata{ContentType: "text/old"}); err != nil {
+					t.Fatal(err)
+				}
+
+				writeFailpoint = func(current string) error {
+					if current == step {
+						return errors.New("panne simulée")
+					}
+					return nil
+				}
+				_, err := s.DeleteObject("bucket", "key", "")
+				writeFailpoint = nil
+				if err == nil {
+					t.Fatal("Expected the delete to fail")
+				}
+				if step != "metadata" {
+					// La clé n'a pas encore été retirée : l'objet est intact
+					if metadata, err := s.GetObjectMetadata("bucket", "key"); err != nil || metadata.ContentType != "text/old" {
+						t.Errorf("Expected the old object before recovery, got %+v (%v)", metadata, err)
+					}
+				}
+
+				s = NewStorage(dir)
+				if recovered, err := s.RecoverWrites(); err != nil || recovered != 1 {
+					t.Fatalf("Expected 1 recovered write, got %d (%v)", recovered, err)
+				}
+				if _, err := s.GetObjectMetadata("bucket", "key"); !os.IsNotExist(err) {
+					t.Errorf("Expected the key to be deleted, got %v", err)
+				}
+				if _, err := os.Stat(s.objectSidecarPath("bucket", "key", "meta")); !os.IsNotExist(err) {
+					t.Errorf("Expected no metadata left for the deleted key, got %v", err)
+				}
+				versions, err := s.ListObjectVersions("bucket", ListObjectsOptions{MaxKeys: 10}, "")
+				if err != nil || len(versions.Versions) != expectedVersions {
+					t.Fatalf("Expected %d versions, got %+v (%v)", expectedVersions, versions, err)
+				}
+				if latest := versions.Versions[0]; !latest.IsLatest || !latest.Metadata.DeleteMarker {
+					t.Errorf("Expected the delete marker to be the latest version, got %+v", latest)
+				}
+				if entries, _ := os.ReadDir(s.journalDir()); len(entries) != 0 {
+					t.Errorf("Expected an empty journal, got %d entries", len(entries))
+				}
+			})
+		}
+	}
+}
+
+// Test du remplacement d'une part : données et descripteur restent cohérents, même après une écriture interrompue
+func TestUploadPartReplace(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	uploadID, err := s.CreateMultipartUpload("bucket", "key", acl.Owner{}, ObjectMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UploadPart("bucket", "key", uploadID, 1, strings.NewReader("first"), nil); err != nil {
+		t.Fatal(err)
+	}
+	part, err := s.UploadPart("bucket", "key", uploadID, 1, strings.NewReader("second"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(s.uploadPath(uploadID), "part-00001-*"))
+	if len(matches) != 1 {
+		t.Errorf("Expected the replaced part data to be removed, got %v", matches)
+	}
+
+	// Arrêt entre l'écriture des données et celle du descripteur : les données orphelines sont ignorées
+	orphan := filepath.Join(s.uploadPath(uploadID), "part-00001-orphan")
+	if err := os.WriteFile(orphan, []byte("third!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parts, err := s.ListParts("bucket", "key", uploadID)
+	if err != nil || len(parts) != 1 || parts[0].ETag != part.ETag {
+		t.Fatalf("Expected the committed part only, got %+v (%v)", parts, err)
+	}
+
+	// Données ne correspondant pas au descripteur : l'assemblage est refusé
+	if err := os.WriteFile(s.partDataPath(uploadID, part), []byte("sec"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CompleteMultipartUpload("bucket", "key", uploadID, []CompletePart{{1, part.ETag}}); !errors.Is(err, ErrInvalidPart) {
+		t.Errorf("Expected ErrInvalidPart, got %v", err)
+	}
+	if err := os.WriteFile(s.partDataPath(uploadID, part), []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CompleteMultipartUpload("bucket", "key", uploadID, []CompletePart{{1, part.ETag}}); err != nil {
+		t.Fatal(err)
+	}
+	file, err := s.GetObject("bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if data, err := io.ReadAll(file); err != nil || string(data) != "second" {
+		t.Errorf("Expected the last uploaded part, got %q (%v)", data, err)
+	}
+}
+
+// Test de l'enregistrement des métadonnées à l'écriture et de leur suppression avec l'objet
+func TestObjectMetadata(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata := ObjectMetadata{
+		ContentType:  "text/plain",
+		CacheControl: "max-age=60",
+		UserMetadata: map[string]string{"author": "alice"},
+	}
+	result, err := s.PutObject("bucket", "key.txt", strings.NewReader("hello"), metadata)
+	if err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	stored, err := s.GetObjectMetadata("bucket", "key.txt")
+	if err != nil {
+		t.Fatalf("GetObjectMetadata: %v", err)
+	}
+	if stored.ContentType != "text/plain" || stored.CacheControl != "max-age=60" || stored.UserMetadata["author"] != "alice" {
+		t.Errorf("Unexpected metadata %+v", stored)
+	}
+	if stored.ETag != result.ETag || stored.Size != 5 || stored.LastModified.IsZero() {
+		t.Errorf("Expected ETag %s and size 5, got %+v", result.ETag, stored)
+	}
+
+	// Un objet sans fichier de métadonnées (écrit avant leur enregistrement) reste lisible
+	if err := os.WriteFile(s.ObjectPath("bucket", "legacy.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := s.GetObjectMetadata("bucket", "legacy.txt")
+	if err != nil || legacy.ETag != result.ETag || legacy.Size != 5 {
+		t.Errorf("Unexpected legacy metadata %+v (%v)", legacy, err)
+	}
+	if _, err := os.Stat(s.objectSidecarPath("bucket", "legacy.txt", "meta")); err != nil {
+		t.Errorf("Expected the recomputed metadata to be saved, got %v", err)
+	}
+
+	// Sous le seul verrou de lecture, les métadonnées recalculées ne sont pas enregistrées
+	if err := os.WriteFile(s.ObjectPath("bucket", "legacy2.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unlock := s.locks.rlock("bucket", "legacy2.txt")
+	legacy, err = s.getObjectMetadata("bucket", "legacy2.txt")
+	unlock()
+	if err != nil || legacy.ETag != result.ETag {
+		t.Errorf("Unexpected legacy metadata %+v (%v)", legacy, err)
+	}
+	if _, err := os.Stat(s.objectSidecarPath("bucket", "legacy2.txt", "meta")); !os.IsNotExist(err) {
+		t.Errorf("Expected no metadata to be saved under the read lock, got %v", err)
+	}
+
+	if _, err := s.DeleteObject("bucket", "key.txt", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.objectSidecarPath("bucket", "key.txt", "meta")); !os.IsNotExist(err) {
+		t.Errorf("Expected metadata to be removed with the object, got %v", err)
+	}
+	if _, err := s.GetObjectMetadata("bucket", "key.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist, got %v", err)
+	}
+}
+
+// Test du vérificateur d'ETags : objets simples et multipart intacts, objet modifié sur disque signalé
+func TestVerifyETags(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutObject("bucket", "dir/intact.txt", strings.NewReader("intact"), ObjectMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutObject("bucket", "altered.txt", strings.NewReader("original"), ObjectMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+
+	uploadID, err := s.CreateMultipartUpload("bucket", "multipart.bin", acl.Owner{}, ObjectMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []CompletePart
+	for i, data := range [][]byte{bytes.Repeat([]byte("a"), MinPartSize), []byte("end")} {
+		part, err := s.UploadPart("bucket", "multipart.bin", uploadID, i+1, bytes.NewReader(data), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	if _, err := s.CompleteMultipartUpload("bucket", "multipart.bin", uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Altération du contenu sans passer par le stockage
+	if err := os.WriteFile(s.ObjectPath("bucket", "altered.txt"), []byte("modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	corrupted, err := s.VerifyETags(context.Background())
+	if err != nil {
+		t.Fatalf("VerifyETags: %v", err)
+	}
+	if len(corrupted) != 1 || corrupted[0].Key != "altered.txt" {
+		t.Fatalf("Expected only altered.txt to be reported, got %+v", corrupted)
+	}
+
+	metadata, err := s.GetObjectMetadata("bucket", "altered.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.ETagMismatch != corrupted[0].Actual || metadata.VerifiedAt.IsZero() {
+		t.Errorf("Expected the mismatch to be recorded, got %+v", metadata)
+	}
+}
+
+// Test du listing récursif : ordre lexicographique, préfixe, délimiteur et pagination, sur chaque backend
+func TestListObjects(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"b", "a/b", "a-b", "a/c/d", "photos/2024/x.jpg", "photos/2025/y.jpg", "photos/z.jpg"} {
+			if _, err := s.PutObject("bucket", key, strings.NewReader(key), ObjectMetadata{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		keysOf := func(result ListObjectsResult) string {
+			var keys []string
+			for _, object := range result.Objects {
+				keys = append(keys, object.Key)
+			}
+			return strings.Join(keys, ",")
+		}
+
+		all, err := s.ListObjects("bucket", ListObjectsOptions{MaxKeys: 1000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keysOf(all); got != "a-b,a/b,a/c/d,b,photos/2024/x.jpg,photos/2025/y.jpg,photos/z.jpg" || all.IsTruncated {
+			t.Errorf("Unexpected full listing %q (truncated %v)", got, all.IsTruncated)
+		}
+
+		grouped, err := s.ListObjects("bucket", ListObjectsOptions{Prefix: "photos/", Delimiter: "/", MaxKeys: 1000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keysOf(grouped) != "photos/z.jpg" || strings.Join(grouped.CommonPrefixes, ",") != "photos/2024/,photos/2025/" {
+			t.Errorf("Unexpected grouped listing %q / %v", keysOf(grouped), grouped.CommonPrefixes)
+		}
+
+		// Pagination par deux entrées avec délimiteur : les préfixes communs comptent comme une entrée
+		var pages []string
+		opts := ListObjectsOptions{Delimiter: "/", MaxKeys: 2}
+		for {
+			page, err := s.ListObjects("bucket", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages = append(pages, keysOf(page)+"|"+strings.Join(page.CommonPrefixes, ","))
+			if !page.IsTruncated {
+				break
+			}
+			opts.StartAfter = page.NextMarker
+		}
+		if got := strings.Join(pages, " "); got != "a-b|a/ b|photos/" {
+			t.Errorf("Unexpected pages %q", got)
+		}
+
+		if _, err := s.ListObjects("missing", ListObjectsOptions{MaxKeys: 1000}); !errors.Is(err, ErrNoSuchBucket) {
+			t.Errorf("Expected ErrNoSuchBucket, got %v", err)
+		}
+	})
+}
+
+// Test du parcours ordonné des objets : ordre exact des clés (segments longs compris), reprise à une borne
+// sans visiter les clés précédentes, et pagination clé par clé identique au listing complet
+func TestWalkObjects(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("c", maxSegmentLen)
+	keys := []string{"a-b", "a/b", "a/c/d", long, long + "!x", long + "/k", long + "zz", "k0", "k1", "k2", "k3", "photos/x.jpg"}
+	for _, key := range keys {
+		if _, err := s.PutObject("bucket", key, strings.NewReader(key), ObjectMetadata{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var visited []string
+	if err := s.walkObjects("bucket", "", "", func(objectName string) error {
+		visited = append(visited, objectName)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(visited, ",") != strings.Join(keys, ",") {
+		t.Errorf("Expected the keys in order, got %v", visited)
+	}
+
+	visited = nil
+	if err := s.walkObjects("bucket", "", "k1", func(objectName string) error {
+		visited = append(visited, objectName)
+		if len(visited) == 2 {
+			return errStopWalk
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(visited, ",") != "k1,k2" {
+		t.Errorf("Expected the walk to start at k1 and stop after k2, got %v", visited)
+	}
+
+	var paged []string
+	opts := ListObjectsOptions{MaxKeys: 1}
+	for {
+		page, err := s.ListObjects("bucket", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, object := range page.Objects {
+			paged = append(paged, object.Key)
+		}
+		if !page.IsTruncated {
+			break
+		}
+		opts.StartAfter = page.NextMarker
+	}
+	if strings.Join(paged, ",") != strings.Join(keys, ",") {
+		t.Errorf("Expected the pages to cover every key once, got %v", paged)
+	}
+}
+
+// Test des versions d'objets, des marqueurs de suppression et du versionnage suspendu, sur chaque backend
+func TestVersioning(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Backend) {
+		if err := s.CreateBucket("bucket", ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PutBucketVersioning("bucket", "Paused"); !errors.Is(err, ErrInvalidVersioningStatus) {
+			t.Errorf("Expected ErrInvalidVersioningStatus, got %v", err)
+		}
+		if err := s.PutBucketVersioning("bucket", VersioningEnabled); err != nil {
+			t.Fatal(err)
+		}
+
+		readVersion := func(versionID string) string {
+			file, metadata, err := s.GetObjectVersion("bucket", "key.txt", versionID)
+			if err != nil {
+				t.Fatalf("GetObjectVersion(%q): %v", versionID, err)
+			}
+			if metadata.DeleteMarker {
+				return "<marker>"
+			}
+			defer file.Close()
+			data, _ := io.ReadAll(file)
+			return string(data)
+		}
+
+		v1, err := s.PutObject("bucket", "key.txt", strings.NewReader("one"), ObjectMetadata{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v2, err := s.PutObject("bucket", "key.txt", strings.NewReader("two"), ObjectMetadata{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v1.VersionID == "" || v1.VersionID == v2.VersionID {
+			t.Fatalf("Expected distinct version IDs, got %q and %q", v1.VersionID, v2.VersionID)
+		}
+		if readVersion("") != "two" || readVersion(v1.VersionID) != "one" {
+			t.Errorf("Unexpected contents: latest %q, first version %q", readVersion(""), readVersion(v1.VersionID))
+		}
+
+		// Sans versionId, la suppression crée un marqueur et l'objet disparaît
+		deleted, err := s.DeleteObject("bucket", "key.txt", "")
+		if err != nil || !deleted.DeleteMarker {
+			t.Fatalf("Expected a delete marker, got %+v (%v)", deleted, err)
+		}
+		if readVersion("") != "<marker>" || readVersion(v2.VersionID) != "two" {
+			t.Errorf("Expected the latest version to be a delete marker")
+		}
+		listing, err := s.ListObjects("bucket", ListObjectsOptions{MaxKeys: 1000})
+		if err != nil || len(listing.Objects) != 0 {
+			t.Errorf("Expected deleted object to be hidden from listings, got %+v (%v)", listing.Objects, err)
+		}
+		if _, _, err := s.GetObjectVersion("bucket", "key.txt", "0123"); !errors.Is(err, ErrNoSuchVersion) {
+			t.Errorf("Expected ErrNoSuchVersion, got %v", err)
+		}
+
+		versions, err := s.ListObjectVersions("bucket", ListObjectsOptions{MaxKeys: 1000}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, version := range versions.Versions {
+			got = append(got, fmt.Sprintf("%s:%v:%v", version.Metadata.VersionID, version.Metadata.DeleteMarker, version.IsLatest))
+		}
+		want := []string{deleted.VersionID + ":true:true", v2.VersionID + ":false:false", v1.VersionID + ":false:false"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("Unexpected versions %v, want %v", got, want)
+		}
+
+		// Supprimer le marqueur restaure la version précédente
+		if _, err := s.DeleteObject("bucket", "key.txt", deleted.VersionID); err != nil {
+			t.Fatal(err)
+		}
+		if readVersion("") != "two" {
+			t.Errorf("Expected the previous version to be restored")
+		}
+
+		// Versionnage suspendu : les écritures successives remplacent la version "null"
+		if err := s.PutBucketVersioning("bucket", VersioningSuspended); err != nil {
+			t.Fatal(err)
+		}
+		for _, body := range []string{"three", "four"} {
+			result, err := s.PutObject("bucket", "key.txt", strings.NewReader(body), ObjectMetadata{})
+			if err != nil || result.VersionID != NullVersionID {
+				t.Fatalf("Expected null version, got %+v (%v)", result, err)
+			}
+		}
+		versions, err = s.ListObjectVersions("bucket", ListObjectsOptions{MaxKeys: 1000}, "")
+		if err != nil || len(versions.Versions) != 3 {
+			t.Fatalf("Expected 3 versions, got %+v (%v)", versions.Versions, err)
+		}
+		if readVersion(NullVersionID) != "four" || readVersion(v2.VersionID) != "two" {
+			t.Errorf("Unexpected contents after suspension")
+		}
+
+		// Pagination par version
+		page, err := s.ListObjectVersions("bucket", ListObjectsOptions{MaxKeys: 2}, "")
+		if err != nil || !page.IsTruncated || len(page.Versions) != 2 {
+			t.Fatalf("Expected a truncated page of 2 versions, got %+v (%v)", page, err)
+		}
+		next, err := s.ListObjectVersions("bucket", ListObjectsOptions{StartAfter: page.NextKeyMarker, MaxKeys: 2}, page.NextVersionIDMarker)
+		if err != nil || next.IsTruncated || len(next.Versions) != 1 || next.Versions[0].Metadata.VersionID != v1.VersionID {
+			t.Errorf("Unexpected second page %+v (%v)", next, err)
+		}
+	})
+}
+
+// Test de l'encodage des clés : réversible, sans nom dangereux ni trop long
+func TestKeyEncoding(t *testing.T) {
+	long := strings.Repeat("é", 300) + strings.Repeat("x", 500)
+	keys := []string{"a", "a/b", "a/", "a//b", "/a", "..", "../../etc/passwd", "./a/./b", ".hidden", "a%2F", "%", "%+", "back\\slash", "ctrl\x01", long, long + "/" + long}
+	for _, key := range keys {
+		encoded := encodeKey(key)
+		for _, name := range strings.Split(encoded, "/") {
+			if name == "" || name == "." || name == ".." || strings.HasPrefix(name, ".") || len(name) > maxSegmentLen+len(dirSuffix) || strings.ContainsAny(name, "\\\x00") {
+				t.Errorf("Unsafe name %q in encoding of %q", name, key)
+			}
+		}
+		if decoded, ok := decodePath(encoded, false); !ok || decoded != key {
+			t.Errorf("decodePath(encodeKey(%q)) = %q, %v", key, decoded, ok)
+		}
+	}
+	if encodeKey("photo.jpg") != "photo.jpg" {
+		t.Errorf("Expected simple keys to be stored as is, got %q", encodeKey("photo.jpg"))
+	}
+	if encodeKey("a") == strings.Split(encodeKey("a/b"), "/")[0] {
+		t.Errorf("Expected the object a and the prefix a/ to use different names")
+	}
+	for _, name := range []string{"a%2F", ".x", "a%zz", "%2e"} {
+		if _, ok := decodePath(name, false); ok {
+			t.Errorf("Expected %q to be rejected as an object name", name)
+		}
+	}
+}
+
+// Test des clés piégées sur le système de fichiers : rien n'est écrit hors du bucket, aucune collision
+func TestUnsafeKeys(t *testing.T) {
+	base := t.TempDir()
+	s := NewStorage(filepath.Join(base, "data"))
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"..", ".mys3", "a/b", ""} {
+		if err := s.CreateBucket(name, ""); !errors.Is(err, ErrInvalidBucketName) {
+			t.Errorf("CreateBucket(%q): expected ErrInvalidBucketName, got %v", name, err)
+		}
+	}
+
+	keys := []string{"../../escape", "a", "a/b", "a/", "a//b", "..", strings.Repeat("k", 1000)}
+	for _, key := range keys {
+		if _, err := s.PutObject("bucket", key, strings.NewReader(key), ObjectMetadata{}); err != nil {
+			t.Fatalf("PutObject(%q): %v", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(base, "escape")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written outside the storage root")
+	}
+	for _, key := range keys {
+		file, _, err := s.GetObjectVersion("bucket", key, "")
+		if err != nil {
+			t.Fatalf("GetObjectVersion(%q): %v", key, err)
+		}
+		data, _ := io.ReadAll(file)
+		file.Close()
+		if string(data) != key {
+			t.Errorf("Key %q: unexpected content %q", key, data)
+		}
+	}
+
+	listing, err := s.ListObjects("bucket", ListObjectsOptions{MaxKeys: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for _, object := range listing.Objects {
+		listed = append(listed, object.Key)
+	}
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	if strings.Join(listed, ",") != strings.Join(sorted, ",") {
+		t.Errorf("Unexpected listing %q", listed)
+	}
+}
+
+// Test de la migration des objets rangés avec l'ancien schéma (clé utilisée directement comme chemin)
+func TestMigrateLegacyKeys(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if err := s.CreateBucket("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	legacy := map[string]string{"photos/2024/x.jpg": "x", ".hidden": "h", "flat.txt": "f"}
+	for key, content := range legacy {
+		path := filepath.Join(s.BucketPath("bucket"), filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, err := s.MigrateLegacyKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Errorf("Expected 2 migrated objects, got %d", moved)
+	}
+	for key, content := range legacy {
+		file, _, err := s.GetObjectVersion("bucket", key, "")
+		if err != nil {
+			t.Fatalf("GetObjectVersion(%q): %v", key, err)
+		}
+		data, _ := io.ReadAll(file)
+		file.Close()
+		if string(data) != content {
+			t.Errorf("Key %q: unexpected content %q", key, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(s.BucketPath("bucket"), "photos")); !os.IsNotExist(err) {
+		t.Errorf("Expected the legacy directory to be removed")
+	}
+}
+
+// Test de l'attribution d'un propriétaire aux buckets qui n'en ont pas
+func TestAssignLegacyOwners(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	if err := s.CreateBucket("owned", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	// Bucket antérieur au suivi des propriétaires : un simple répertoire
+	if err := os.Mkdir(s.BucketPath("legacy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if owner, err := s.BucketOwner("legacy"); err != nil || owner != "" {
+		t.Fatalf("Expected no owner before the migration, got %q (%v)", owner, err)
+	}
+
+	assigned, err := s.AssignLegacyOwners("admin")
+	if err != nil || assigned != 1 {
+		t.Fatalf("Expected 1 assigned bucket, got %d (%v)", assigned, err)
+	}
+	for bucket, expected := range map[string]string{"owned": "alice", "legacy": "admin"} {
+		if owner, err := s.BucketOwner(bucket); err != nil || owner != expected {
+			t.Errorf("Bucket %s: expected owner %q, got %q (%v)", bucket, expected, owner, err)
+		}
+	}
+	if assigned, err := s.AssignLegacyOwners("admin"); err != nil || assigned != 0 {
+		t.Errorf("Expected nothing left to assign, got %d (%v)", assigned, err)
+	}
+	if _, err := s.AssignLegacyOwners(""); err == nil {
+		t.Error("Expected an empty owner to be refused")
+	}
+}
+
+// Test des règles de nommage des buckets S3
+func TestValidBucketName(t *testing.T) {
+	valid := []string{"abc", "my-bucket", "my.bucket.2024", "0bucket", strings.Repeat("a", 63)}
+	invalid := []string{"ab", strings.Repeat("a", 64), "MyBucket", "my_bucket", "-bucket", "bucket-", "my..bucket",
+		"192.168.1.1", "xn--bucket", "sthree-bucket", "bucket-s3alias", "bucket--ol-s3", "..", "a/b"}
+	for _, name := range valid {
+		if !validBucketName(name) {
+			t.Errorf("Expected %q to be a valid bucket name", name)
+		}
+	}
+	for _, name := range invalid {
+		if validBucketName(name) {
+			t.Errorf("Expected %q to be an invalid bucket name", name)
+		}
+	}
+}
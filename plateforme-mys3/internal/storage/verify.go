@@ -0,0 +1,145 @@
+// internal/storage/verify.go
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// CorruptObject signale un objet dont le contenu ne correspond plus à l'ETag enregistré
+type CorruptObject struct {
+	Bucket string
+	Key    string
+	ETag   string // ETag enregistré à l'écriture
+	Actual string // ETag recalculé depuis le contenu
+}
+
+// contentETag recalcule l'ETag d'un objet depuis son contenu : MD5 du contenu,
+// ou MD5 des MD5 de chaque part suffixé du nombre de parts pour un objet multipart
+func (s *Storage) contentETag(bucketName, objectName string, partSizes []int64) (string, error) {
+	file, err := s.GetObject(bucketName, objectName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if len(partSizes) == 0 {
+		hash := md5.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	etagHash := md5.New()
+	for _, size := range partSizes {
+		hash := md5.New()
+		if _, err := io.CopyN(hash, file, size); err != nil && err != io.EOF {
+			return "", err
+		}
+		etagHash.Write(hash.Sum(nil))
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(etagHash.Sum(nil)), len(partSizes)), nil
+}
+
+// VerifyObjectETag recalcule l'ETag d'un objet, le compare à l'ETag enregistré et consigne le résultat
+// dans ses métadonnées. Retourne nil si l'objet est intact.
+func (s *Storage) VerifyObjectETag(bucketName, objectName string) (*CorruptObject, error) {
+	metadata, err := s.GetObjectMetadata(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	// L'ETag d'un objet chiffré porte sur le contenu en clair, illisible sans clé (SSE-C) ;
+	// son intégrité est de toute façon contrôlée à chaque lecture par les tags GCM
+	if metadata.Encryption != nil {
+		return nil, nil
+	}
+	actual, err := s.contentETag(bucketName, objectName, metadata.PartSizes)
+	if err != nil {
+		return nil, err
+	}
+
+	// L'objet a pu être remplacé pendant le calcul : on ne consigne rien dans ce cas
+	unlock := s.locks.lock(bucketName, objectName)
+	defer unlock()
+	current, err := s.getObjectMetadata(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	if current.ETag != metadata.ETag || !current.LastModified.Equal(metadata.LastModified) {
+		return nil, nil
+	}
+
+	current.VerifiedAt = time.Now().UTC()
+	current.ETagMismatch = ""
+	if actual != current.ETag {
+		current.ETagMismatch = actual
+	}
+	if err := s.putObjectMetadata(bucketName, objectName, current); err != nil {
+		return nil, err
+	}
+	if current.ETagMismatch == "" {
+		return nil, nil
+	}
+	return &CorruptObject{Bucket: bucketName, Key: objectName, ETag: current.ETag, Actual: actual}, nil
+}
+
+// VerifyETags vérifie l'ETag de tous les objets de tous les buckets et retourne les objets corrompus
+func (s *Storage) VerifyETags(ctx context.Context) ([]CorruptObject, error) {
+	buckets, err := s.ListBuckets()
+	if err != nil {
+		return nil, err
+	}
+
+	var corrupted []CorruptObject
+	for _, bucket := range buckets {
+		err := s.walkObjects(bucket.Name, "", "", func(objectName string) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			corrupt, err := s.VerifyObjectETag(bucket.Name, objectName)
+			if err != nil {
+				if os.IsNotExist(err) {
+					// Objet supprimé pendant le parcours
+					return nil
+				}
+				return err
+			}
+			if corrupt != nil {
+				corrupted = append(corrupted, *corrupt)
+			}
+			return nil
+		})
+		if err != nil {
+			return corrupted, err
+		}
+	}
+	return corrupted, nil
+}
+
+// RunETagVerifier vérifie périodiquement les ETags de tous les objets jusqu'à l'annulation du contexte
+func (s *Storage) RunETagVerifier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Println("Vérification des ETags des objets")
+			corrupted, err := s.VerifyETags(ctx)
+			for _, object := range corrupted {
+				log.Printf("ETag incohérent pour %s/%s : enregistré %s, recalculé %s", object.Bucket, object.Key, object.ETag, object.Actual)
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Erreur lors de la vérification des ETags: %v", err)
+			}
+		}
+	}
+}
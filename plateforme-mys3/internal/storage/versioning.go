@@ -0,0 +1,664 @@
+// internal/storage/versioning.go
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// États du versionnage d'un bucket (vide : jamais activé)
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+)
+
+// NullVersionID est l'identifiant des versions écrites sans versionnage actif
+const NullVersionID = "null"
+
+// versioningConfig est la configuration de versionnage persistée d'un bucket
+type versioningConfig struct {
+	Status string `json:"status"`
+}
+
+// DeleteResult décrit l'effet d'une suppression d'objet
+type DeleteResult struct {
+	VersionID    string // version supprimée, ou version du marqueur de suppression créé
+	DeleteMarker bool   // un marqueur de suppression a été créé, ou c'est un marqueur qui a été supprimé
+}
+
+// ObjectVersion décrit une version (ou un marqueur de suppression) d'un objet
+type ObjectVersion struct {
+	Key      string
+	Metadata ObjectMetadata
+	IsLatest bool
+}
+
+// ListVersionsResult est une page de listing des versions d'objets
+type ListVersionsResult struct {
+	Versions            []ObjectVersion
+	CommonPrefixes      []string
+	IsTruncated         bool
+	NextKeyMarker       string
+	NextVersionIDMarker string
+}
+
+// PutBucketVersioning active ou suspend le versionnage d'un bucket
+func (s *Storage) PutBucketVersioning(bucketName, status string) error {
+	if status != VersioningEnabled && status != VersioningSuspended {
+		return ErrInvalidVersioningStatus
+	}
+	data, err := json.Marshal(versioningConfig{Status: status})
+	if err != nil {
+		return err
+	}
+	return s.putBucketConfig(bucketName, "versioning.json", data)
+}
+
+// GetBucketVersioning retourne l'état du versionnage d'un bucket (vide s'il n'a jamais été activé)
+func (s *Storage) GetBucketVersioning(bucketName string) (string, error) {
+	data, err := s.getBucketConfig(bucketName, "versioning.json")
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var config versioningConfig
+	err = json.Unmarshal(data, &config)
+	return config.Status, err
+}
+
+// versionsDir retourne le répertoire des versions archivées d'une clé.
+// La clé est hachée : les versions de "a" et de "a/b" ne peuvent pas se mélanger.
+func (s *Storage) versionsDir(bucketName, objectName string) string {
+	sum := sha256.Sum256([]byte(objectName))
+	return filepath.Join(s.bucketConfigDir(bucketName), "versions", hex.EncodeToString(sum[:]))
+}
+
+// versionDataPath retourne le chemin du contenu d'une version archivée
+func (s *Storage) versionDataPath(bucketName, objectName, versionID string) string {
+	return filepath.Join(s.versionsDir(bucketName, objectName), versionID)
+}
+
+// versionMetadataPath retourne le chemin des métadonnées d'une version archivée
+func (s *Storage) versionMetadataPath(bucketName, objectName, versionID string) string {
+	return s.versionDataPath(bucketName, objectName, versionID) + ".json"
+}
+
+// newVersionID génère un identifiant de version : horodatage puis aléa, pour un ordre chronologique
+func newVersionID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016x%s", time.Now().UnixNano(), hex.EncodeToString(buf)), nil
+}
+
+// isValidVersionID vérifie un identifiant de version fourni par un client avant de l'utiliser dans un chemin
+func isValidVersionID(versionID string) bool {
+	if versionID == NullVersionID {
+		return true
+	}
+	if len(versionID) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(versionID)
+	return err == nil
+}
+
+// versionIDOf retourne l'identifiant de version d'un objet ; les objets écrits avant le versionnage ont la version "null"
+func versionIDOf(metadata ObjectMetadata) string {
+	if metadata.VersionID == "" {
+		return NullVersionID
+	}
+	return metadata.VersionID
+}
+
+// sortVersions trie des versions de la plus récente à la plus ancienne
+func sortVersions(versions []ObjectMetadata) {
+	sort.Slice(versions, func(i, j int) bool {
+		if !versions[i].LastModified.Equal(versions[j].LastModified) {
+			return versions[i].LastModified.After(versions[j].LastModified)
+		}
+		return versions[i].VersionID > versions[j].VersionID
+	})
+}
+
+// archivedVersions retourne les versions archivées d'une clé, de la plus récente à la plus ancienne
+func (s *Storage) archivedVersions(bucketName, objectName string) ([]ObjectMetadata, error) {
+	return readArchivedVersions(s.versionsDir(bucketName, objectName))
+}
+
+// readArchivedVersions lit les métadonnées des versions rangées dans un répertoire de versions
+func readArchivedVersions(dir string) ([]ObjectMetadata, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []ObjectMetadata
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		var metadata ObjectMetadata
+		if err := readJSON(filepath.Join(dir, entry.Name()), &metadata); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		versions = append(versions, metadata)
+	}
+	sortVersions(versions)
+	return versions, nil
+}
+
+// removeCurrent supprime la version courante d'un objet et ses fichiers annexes. Un contenu déjà absent
+// (suppression interrompue) n'empêche pas celle des fichiers annexes : os.ErrNotExist est retourné ensuite.
+func (s *Storage) removeCurrent(bucketName, objectName string) error {
+	removeErr := os.Remove(s.ObjectPath(bucketName, objectName))
+	if removeErr != nil && !os.IsNotExist(removeErr) {
+		return removeErr
+	}
+	if err := s.removeSidecar(bucketName, objectName, "meta"); err != nil {
+		return err
+	}
+	if err := s.removeSidecar(bucketName, objectName, "acl"); err != nil {
+		return err
+	}
+	return removeErr
+}
+
+// linkCurrent archive la version courante d'un objet sans la retirer : son contenu est lié (lien physique)
+// parmi les versions archivées, et c'est le renommage de la nouvelle version (ou le retrait de la clé par un
+// marqueur de suppression) qui le remplace. La clé garde ainsi une version courante à chaque instant d'une
+// écriture ; un appel répété (reprise après une panne) archive de nouveau la même version.
+func (s *Storage) linkCurrent(bucketName, objectName string) error {
+	metadata, err := s.getObjectMetadata(bucketName, objectName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	versionID := versionIDOf(metadata)
+	dir := s.versionsDir(bucketName, objectName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// Le lien est créé sous un nom temporaire puis renommé : une version archivée existante est remplacée
+	link := filepath.Join(dir, tmpPrefix+versionID)
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(s.ObjectPath(bucketName, objectName), link); err != nil {
+		return err
+	}
+	if err := os.Rename(link, s.versionDataPath(bucketName, objectName, versionID)); err != nil {
+		return err
+	}
+	// Les métadonnées en dernier : une version n'est listée qu'une fois son contenu archivé
+	metadata.Key = objectName
+	metadata.VersionID = versionID
+	return writeJSON(s.versionMetadataPath(bucketName, objectName, versionID), metadata)
+}
+
+// removeArchivedVersion supprime une version archivée ; found vaut false si elle n'existe pas
+func (s *Storage) removeArchivedVersion(bucketName, objectName, versionID string) (metadata ObjectMetadata, found bool, err error) {
+	metadataPath := s.versionMetadataPath(bucketName, objectName, versionID)
+	if err := readJSON(metadataPath, &metadata); err != nil {
+		if os.IsNotExist(err) {
+			return metadata, false, nil
+		}
+		return metadata, false, err
+	}
+	// Un marqueur de suppression n'a pas de contenu
+	if err := os.Remove(s.versionDataPath(bucketName, objectName, versionID)); err != nil && !os.IsNotExist(err) {
+		return metadata, true, err
+	}
+	return metadata, true, os.Remove(metadataPath)
+}
+
+// writeDeleteMarker enregistre un marqueur de suppression, qui devient la dernière version de la clé
+func (s *Storage) writeDeleteMarker(bucketName, objectName string, marker ObjectMetadata) error {
+	if err := os.MkdirAll(s.versionsDir(bucketName, objectName), 0755); err != nil {
+		return err
+	}
+	return writeJSON(s.versionMetadataPath(bucketName, objectName, marker.VersionID), marker)
+}
+
+// putDeleteMarker crée un marqueur de suppression en passant par le journal des écritures, comme une écriture
+// d'objet : la version courante est archivée (ou remplacée, pour une version "null"), la clé retirée, puis le
+// marqueur enregistré. L'appelant détient le verrou de la clé.
+func (s *Storage) putDeleteMarker(bucketName, objectName, status, versionID string) error {
+	intent := writeIntent{
+		Bucket:     bucketName,
+		Key:        objectName,
+		Versioning: status,
+		Metadata: ObjectMetadata{
+			Key:          objectName,
+			VersionID:    versionID,
+			DeleteMarker: true,
+			LastModified: time.Now().UTC(),
+		},
+	}
+	intentPath, err := s.beginWrite(intent)
+	if err != nil {
+		return err
+	}
+	if err := s.completeWrite(intent); err != nil {
+		log.Printf("Suppression de %s/%s interrompue, reprise au prochain démarrage : %v", bucketName, objectName, err)
+		return err
+	}
+	if err := os.Remove(intentPath); err != nil {
+		log.Printf("Erreur lors de la suppression de l'entrée du journal %s: %v", intentPath, err)
+	}
+	return nil
+}
+
+// promoteLatest remet en place la version archivée la plus récente quand la clé n'a plus de version courante,
+// sauf si cette version est un marqueur de suppression
+func (s *Storage) promoteLatest(bucketName, objectName string) error {
+	if _, err := s.StatObject(bucketName, objectName); err == nil {
+		return nil
+	}
+	versions, err := s.archivedVersions(bucketName, objectName)
+	if err != nil || len(versions) == 0 || versions[0].DeleteMarker {
+		return err
+	}
+
+	latest := versions[0]
+	objectPath := s.ObjectPath(bucketName, objectName)
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return err
+	}
+	// Les métadonnées d'abord : le contenu n'est jamais en place sans elles. Après une panne avant le
+	// renommage, elles restent sans contenu et ne décrivent aucune version courante.
+	if err := s.putObjectMetadata(bucketName, objectName, latest); err != nil {
+		return err
+	}
+	if err := os.Rename(s.versionDataPath(bucketName, objectName, latest.VersionID), objectPath); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(objectPath)); err != nil {
+		return err
+	}
+	return os.Remove(s.versionMetadataPath(bucketName, objectName, latest.VersionID))
+}
+
+// DeleteObject supprime un objet. Sans versionID, la suppression dépend du versionnage du bucket :
+// suppression définitive (jamais activé) ou création d'un marqueur de suppression (activé ou suspendu).
+// Avec versionID, la version désignée est supprimée définitivement.
+func (s *Storage) DeleteObject(bucketName, objectName, versionID string) (DeleteResult, error) {
+	if !s.BucketExists(bucketName) {
+		return DeleteResult{}, ErrNoSuchBucket
+	}
+	unlock := s.locks.lock(bucketName, objectName)
+	defer unlock()
+	if versionID != "" {
+		return s.deleteVersion(bucketName, objectName, versionID)
+	}
+
+	status, err := s.GetBucketVersioning(bucketName)
+	if err != nil {
+		return DeleteResult{}, err
+	}
+	switch status {
+	case VersioningEnabled:
+		markerID, err := newVersionID()
+		if err != nil {
+			return DeleteResult{}, err
+		}
+		return DeleteResult{VersionID: markerID, DeleteMarker: true}, s.putDeleteMarker(bucketName, objectName, status, markerID)
+	case VersioningSuspended:
+		// Le marqueur "null" remplace l'éventuelle version "null", courante ou archivée
+		return DeleteResult{VersionID: NullVersionID, DeleteMarker: true}, s.putDeleteMarker(bucketName, objectName, status, NullVersionID)
+	default:
+		// Comme sur S3, supprimer une clé inexistante n'est pas une erreur
+		if err := s.removeCurrent(bucketName, objectName); err != nil && !os.IsNotExist(err) {
+			return DeleteResult{}, err
+		}
+		return DeleteResult{}, nil
+	}
+}
+
+// deleteVersion supprime définitivement une version (ou un marqueur de suppression) d'un objet
+func (s *Storage) deleteVersion(bucketName, objectName, versionID string) (DeleteResult, error) {
+	if !isValidVersionID(versionID) {
+		return DeleteResult{}, ErrNoSuchVersion
+	}
+	result := DeleteResult{VersionID: versionID}
+
+	current, err := s.getObjectMetadata(bucketName, objectName)
+	switch {
+	case err == nil && versionIDOf(current) == versionID:
+		if err := s.removeCurrent(bucketName, objectName); err != nil {
+			return result, err
+		}
+	case err != nil && !os.IsNotExist(err):
+		return result, err
+	default:
+		metadata, found, err := s.removeArchivedVersion(bucketName, objectName, versionID)
+		if err != nil || !found {
+			// Supprimer une version inexistante n'est pas une erreur
+			return result, err
+		}
+		result.DeleteMarker = metadata.DeleteMarker
+	}
+	return result, s.promoteLatest(bucketName, objectName)
+}
+
+// GetObjectVersionMetadata retourne les métadonnées d'une version d'un objet (la dernière si versionID est vide).
+// La dernière version peut être un marqueur de suppression (DeleteMarker) : l'objet est alors considéré supprimé.
+// Retourne os.ErrNotExist si la clé n'a aucune version, ErrNoSuchVersion si la version demandée n'existe pas.
+func (s *Storage) GetObjectVersionMetadata(bucketName, objectName, versionID string) (ObjectMetadata, error) {
+	unlock := s.locks.rlock(bucketName, objectName)
+	defer unlock()
+	metadata, _, err := s.findVersion(bucketName, objectName, versionID)
+	return metadata, err
+}
+
+// GetObjectVersion ouvre une version d'un objet (la dernière si versionID est vide) et retourne ses métadonnées.
+// Pour un marqueur de suppression, le fichier est nil et metadata.DeleteMarker vaut true.
+// Un objet SSE-C ne peut être lu qu'avec la clé du client (voir OpenObjectVersion).
+func (s *Storage) GetObjectVersion(bucketName, objectName, versionID string) (io.ReadSeekCloser, ObjectMetadata, error) {
+	return s.OpenObjectVersion(bucketName, objectName, versionID, nil)
+}
+
+// OpenObjectVersion est GetObjectVersion pour un objet éventuellement chiffré avec une clé client (SSE-C,
+// customerKey nil sinon) : le contenu retourné est en clair, positionnable comme le fichier.
+// Le fichier est ouvert sous le verrou de la clé : il reste lisible en entier même si l'objet est remplacé ensuite.
+func (s *Storage) OpenObjectVersion(bucketName, objectName, versionID string, customerKey []byte) (io.ReadSeekCloser, ObjectMetadata, error) {
+	unlock := s.locks.rlock(bucketName, objectName)
+	defer unlock()
+	metadata, isCurrent, err := s.findVersion(bucketName, objectName, versionID)
+	if err != nil || metadata.DeleteMarker {
+		return nil, metadata, err
+	}
+	dataKey, err := openDataKey(metadata, s.MasterKey, s.PreviousMasterKey, customerKey)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	path := s.ObjectPath(bucketName, objectName)
+	if !isCurrent {
+		path = s.versionDataPath(bucketName, objectName, versionID)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && versionID != "" {
+			return nil, metadata, ErrNoSuchVersion
+		}
+		return nil, metadata, err
+	}
+	reader, err := decryptObject(file, metadata, dataKey)
+	if err != nil {
+		file.Close()
+		return nil, metadata, err
+	}
+	return reader, metadata, nil
+}
+
+// findVersion retrouve les métadonnées d'une version et indique s'il s'agit de la version courante
+func (s *Storage) findVersion(bucketName, objectName, versionID string) (metadata ObjectMetadata, isCurrent bool, err error) {
+	current, err := s.getObjectMetadata(bucketName, objectName)
+	if err != nil && !os.IsNotExist(err) {
+		return ObjectMetadata{}, false, err
+	}
+	hasCurrent := err == nil
+
+	if versionID == "" {
+		if hasCurrent {
+			return current, true, nil
+		}
+		versions, err := s.archivedVersions(bucketName, objectName)
+		if err != nil {
+			return ObjectMetadata{}, false, err
+		}
+		if len(versions) > 0 && versions[0].DeleteMarker {
+			return versions[0], false, nil
+		}
+		return ObjectMetadata{}, false, os.ErrNotExist
+	}
+
+	if !isValidVersionID(versionID) {
+		return ObjectMetadata{}, false, ErrNoSuchVersion
+	}
+	if hasCurrent && versionIDOf(current) == versionID {
+		return current, true, nil
+	}
+	if err := readJSON(s.versionMetadataPath(bucketName, objectName, versionID), &metadata); err != nil {
+		if os.IsNotExist(err) {
+			return ObjectMetadata{}, false, ErrNoSuchVersion
+		}
+		return ObjectMetadata{}, false, err
+	}
+	return metadata, false, nil
+}
+
+// ListObjectVersions liste les versions et marqueurs de suppression des objets d'un bucket,
+// par clé puis de la version la plus récente à la plus ancienne. opts.StartAfter joue le rôle de key-marker.
+func (s *Storage) ListObjectVersions(bucketName string, opts ListObjectsOptions, versionIDMarker string) (ListVersionsResult, error) {
+	var result ListVersionsResult
+	if !s.BucketExists(bucketName) {
+		return result, ErrNoSuchBucket
+	}
+
+	keys, err := s.versionedKeys(bucketName, opts.Prefix)
+	if err != nil {
+		return result, err
+	}
+
+	// Seules les versions des clés de la page sont lues : à partir du key-marker, jusqu'à ce que la page
+	// déborde (une entrée de plus que MaxKeys suffit à la savoir tronquée)
+	byKey := make(map[string][]ObjectMetadata)
+	entries, lastPrefix := 0, ""
+	for _, key := range keys[sort.SearchStrings(keys, opts.StartAfter):] {
+		if entries > opts.MaxKeys {
+			break
+		}
+		if commonPrefix := commonPrefixOf(key, opts); commonPrefix != "" {
+			// Les clés regroupées sous un préfixe commun n'ont pas de versions à lire
+			byKey[key] = nil
+			if commonPrefix > opts.StartAfter && commonPrefix != lastPrefix {
+				entries++
+				lastPrefix = commonPrefix
+			}
+			continue
+		}
+		versions, err := s.keyVersions(bucketName, key)
+		if err != nil {
+			return result, err
+		}
+		byKey[key] = versions
+		if key != opts.StartAfter {
+			entries += len(versions)
+		}
+	}
+	return paginateVersions(byKey, opts, versionIDMarker), nil
+}
+
+// WalkObjectVersions appelle fn pour chaque clé d'un bucket commençant par prefix, par ordre des clés, avec ses
+// versions de la plus récente à la plus ancienne. Contrairement au listing paginé, les versions de chaque clé ne
+// sont lues qu'une fois : c'est le parcours des traitements qui examinent toutes les versions d'un bucket.
+func (s *Storage) WalkObjectVersions(bucketName, prefix string, fn func(versions []ObjectVersion) error) error {
+	if !s.BucketExists(bucketName) {
+		return ErrNoSuchBucket
+	}
+	keys, err := s.versionedKeys(bucketName, prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		versions, err := s.keyVersions(bucketName, key)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			continue
+		}
+		listed := make([]ObjectVersion, 0, len(versions))
+		for i, version := range versions {
+			listed = append(listed, ObjectVersion{Key: key, Metadata: version, IsLatest: i == 0})
+		}
+		if err := fn(listed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionedKeys retourne, triées, les clés d'un bucket commençant par prefix qui ont une version courante ou
+// archivée. Seule la première version archivée de chaque clé est lue, pour retrouver la clé.
+func (s *Storage) versionedKeys(bucketName, prefix string) ([]string, error) {
+	seen := make(map[string]bool)
+	err := s.walkObjects(bucketName, prefix, "", func(objectName string) error {
+		seen[objectName] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	archiveRoot := filepath.Join(s.bucketConfigDir(bucketName), "versions")
+	dirs, err := os.ReadDir(archiveRoot)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(archiveRoot, dir.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			var metadata ObjectMetadata
+			err := readJSON(filepath.Join(archiveRoot, dir.Name(), entry.Name()), &metadata)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(metadata.Key, prefix) {
+				seen[metadata.Key] = true
+			}
+			break
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// keyVersions retourne les versions d'une clé, de la plus récente à la plus ancienne : la version courante
+// (si elle existe), puis les versions archivées
+func (s *Storage) keyVersions(bucketName, objectName string) ([]ObjectMetadata, error) {
+	var versions []ObjectMetadata
+	current, err := s.GetObjectMetadata(bucketName, objectName)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		current.VersionID = versionIDOf(current)
+		versions = append(versions, current)
+	}
+	archived, err := s.archivedVersions(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	for _, version := range archived {
+		// Une version courante déjà archivée par une écriture pas encore terminée n'est listée qu'une fois
+		if len(versions) > 0 && versions[0].VersionID == version.VersionID {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) > 1 {
+		sortVersions(versions)
+	}
+	return versions, nil
+}
+
+// paginateVersions construit une page de listing des versions à partir des versions de chaque clé :
+// clés triées, key-marker (opts.StartAfter) et version-id-marker, regroupement par délimiteur puis MaxKeys
+func paginateVersions(byKey map[string][]ObjectMetadata, opts ListObjectsOptions, versionIDMarker string) ListVersionsResult {
+	var result ListVersionsResult
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	count := 0
+	for _, key := range keys {
+		if key < opts.StartAfter || (key == opts.StartAfter && versionIDMarker == "") {
+			continue
+		}
+
+		commonPrefix := commonPrefixOf(key, opts)
+		if commonPrefix != "" {
+			if commonPrefix <= opts.StartAfter ||
+				(len(result.CommonPrefixes) > 0 && result.CommonPrefixes[len(result.CommonPrefixes)-1] == commonPrefix) {
+				continue
+			}
+			if count >= opts.MaxKeys {
+				result.IsTruncated = count > 0
+				break
+			}
+			count++
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix)
+			result.NextKeyMarker, result.NextVersionIDMarker = commonPrefix, ""
+			continue
+		}
+
+		// La version courante (si elle existe) est en tête, suivie des versions archivées déjà triées
+		versions := byKey[key]
+		if len(versions) > 1 {
+			sortVersions(versions)
+		}
+		start := 0
+		if key == opts.StartAfter {
+			start = len(versions)
+			for i, version := range versions {
+				if version.VersionID == versionIDMarker {
+					start = i + 1
+					break
+				}
+			}
+		}
+		for i := start; i < len(versions); i++ {
+			if count >= opts.MaxKeys {
+				result.IsTruncated = count > 0
+				return result
+			}
+			count++
+			result.Versions = append(result.Versions, ObjectVersion{Key: key, Metadata: versions[i], IsLatest: i == 0})
+			result.NextKeyMarker, result.NextVersionIDMarker = key, versions[i].VersionID
+		}
+	}
+	return result
+}